@@ -4,29 +4,206 @@
 package start
 
 import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"go.uber.org/zap"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/tools/record"
+
 	commoncmd "github.com/triggermesh/scoby-hook-triggermesh/pkg/common/cmd"
+	"github.com/triggermesh/scoby-hook-triggermesh/pkg/config/observability"
 	"github.com/triggermesh/scoby-hook-triggermesh/pkg/handler"
 	"github.com/triggermesh/scoby-hook-triggermesh/pkg/handler/kuards"
+	"github.com/triggermesh/scoby-hook-triggermesh/pkg/metrics"
 	"github.com/triggermesh/scoby-hook-triggermesh/pkg/sources/client/s3"
 	"github.com/triggermesh/scoby-hook-triggermesh/pkg/sources/reconciler/awss3source"
+	"github.com/triggermesh/scoby-hook-triggermesh/pkg/tracing"
 
 	"github.com/triggermesh/scoby-hook-triggermesh/pkg/server"
 )
 
+const (
+	metricsBackendPrometheus = "prometheus"
+	defaultPrometheusPort    = 9090
+
+	tracingServiceName   = "scoby-hook-triggermesh"
+	eventSourceComponent = "scoby-hook-triggermesh"
+)
+
 type Cmd struct {
-	Address string `help:"Address to listen for incoming requests." env:"ADDRESS" default:":8080"`
-	Path    string `help:"Path where hook requests are served." env:"PATH" default:"v1"`
+	Address     string   `help:"Address to listen for incoming requests. When not informed the hook listens at all interfaces on the configured port." env:"ADDRESS"`
+	Path        string   `help:"Base path where hook requests are served. Each API version is served under this path." env:"HOOK_PATH" default:"/"`
+	APIVersions []string `help:"Hook API versions to serve." env:"API_VERSIONS" default:"v1"`
+
+	HealthPort int `help:"Port to serve health probes over plain HTTP, regardless of the TLS and authentication settings of the hook. When not informed health probes are only served along with hook requests." env:"HEALTH_PORT"`
+
+	HandlerTimeout time.Duration `help:"Maximum duration of a handler operation on an object." env:"HANDLER_TIMEOUT" default:"30s"`
+
+	// Reconciliation parameters
+	ObserveAWSResources bool `help:"Report differences between the current and desired state of the AWS resources of every source instead of applying them, regardless of the reconcile mode of sources." env:"OBSERVE_AWS_RESOURCES"`
+
+	// TLS parameters
+	TLSCertFile     string `help:"PEM encoded certificate file used to serve the hook over TLS." env:"TLS_CERT_FILE" type:"path"`
+	TLSKeyFile      string `help:"PEM encoded private key file for the TLS certificate." env:"TLS_KEY_FILE" type:"path"`
+	TLSClientCAFile string `help:"PEM encoded CA bundle used to verify client certificates. When informed callers must present a certificate signed by one of these CAs." env:"TLS_CLIENT_CA_FILE" type:"path"`
+
+	// Authentication parameters
+	AllowedServiceAccounts []string `help:"ServiceAccounts allowed to send hook requests, expressed as <namespace>/<name>. When informed callers must authenticate using a bearer token that is validated through the TokenReview API." env:"ALLOWED_SERVICE_ACCOUNTS"`
+	TokenAudiences         []string `help:"Audiences that bearer tokens must be issued for." env:"TOKEN_AUDIENCES"`
+}
+
+func (c *Cmd) Validate() error {
+	if (c.TLSCertFile == "") != (c.TLSKeyFile == "") {
+		return errors.New("TLS certificate and key files must be informed together")
+	}
+
+	if c.TLSClientCAFile != "" && c.TLSCertFile == "" {
+		return errors.New("client CA file can only be used when the hook is served over TLS")
+	}
+
+	if len(c.TokenAudiences) != 0 && len(c.AllowedServiceAccounts) == 0 {
+		return errors.New("token audiences can only be used along with allowed ServiceAccounts")
+	}
+
+	if c.HealthPort < 0 {
+		return errors.New("health port must be a positive number")
+	}
+
+	if c.HandlerTimeout <= 0 {
+		return errors.New("handler timeout must be a positive duration")
+	}
+
+	return nil
 }
 
 func (c *Cmd) Run(g *commoncmd.Globals) error {
 	g.Logger.Debug("Creating TriggerMesh hook server")
 
+	broadcaster := record.NewBroadcaster()
+	broadcaster.StartRecordingToSink(&typedcorev1.EventSinkImpl{Interface: g.KubeClient.CoreV1().Events("")})
+	defer broadcaster.Shutdown()
+
+	opts := []server.Option{
+		server.WithReadinessCheck("kubernetes", server.KubernetesReadinessCheck(g.KubeClient.Discovery())),
+		server.WithEventRecorder(broadcaster.NewRecorder(scheme.Scheme, corev1.EventSource{Component: eventSourceComponent})),
+	}
+	for _, v := range c.APIVersions {
+		dec, err := server.RequestDecoderForVersion(v)
+		if err != nil {
+			return err
+		}
+		opts = append(opts, server.WithAPIVersion(v, dec))
+	}
+
+	if c.HealthPort != 0 {
+		opts = append(opts, server.WithHealthAddress(":"+strconv.Itoa(c.HealthPort)))
+	}
+
+	if c.TLSCertFile != "" {
+		certs, err := server.NewCertificateReloader(c.TLSCertFile, c.TLSKeyFile, c.TLSClientCAFile, g.Logger)
+		if err != nil {
+			return err
+		}
+		opts = append(opts, server.WithTLS(certs))
+	}
+
+	if len(c.AllowedServiceAccounts) != 0 {
+		auth, err := server.NewTokenReviewAuthenticator(g.KubeClient.AuthenticationV1().TokenReviews(),
+			c.AllowedServiceAccounts, c.TokenAudiences, g.Logger)
+		if err != nil {
+			return err
+		}
+		opts = append(opts, server.WithAuthenticator(auth))
+	}
+
+	preg := prometheus.NewRegistry()
+	preg.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+
+	rec, err := metrics.NewRecorder(preg)
+	if err != nil {
+		return err
+	}
+	opts = append(opts, server.WithMetrics(rec))
+
+	s3Cg := s3.NewClientGetter(g.KubeClient.CoreV1().Secrets, g.KubeClient.CoreV1().ServiceAccounts)
+
 	r := handler.NewRegistry([]handler.Handler{
 		// Kuards is a temporary playground
 		kuards.New(),
-		awss3source.New(s3.NewClientGetter(g.KubeClient.CoreV1().Secrets), g.Logger),
+		awss3source.New(s3Cg, g.KubeClient.CoreV1().ServiceAccounts, g.Logger,
+			awss3source.WithObserveOnly(c.ObserveAWSResources),
+		),
+	},
+		handler.Logging(g.Logger),
+		handler.Metrics(rec),
+		handler.Recover(g.Logger),
+		handler.InitConditions(),
+		handler.Deadline(c.HandlerTimeout),
+	)
+
+	// Metrics and tracing settings can be updated at runtime through the
+	// observability configuration.
+	endpoint := metrics.NewEndpoint(preg, g.Logger)
+	exporter := tracing.NewExporter(tracingServiceName)
+	defer exporter.Flush()
+
+	g.WatchMetricsConfig(func(mc *observability.MetricsConfig) {
+		applyMetricsConfig(g.Context, mc, endpoint, exporter, g.Logger)
 	})
 
-	s := server.New(c.Path, c.Address, r, g.DynClient, g.Logger)
+	address := c.Address
+	if address == "" {
+		address = ":" + strconv.Itoa(g.Port)
+	}
+
+	s := server.New(c.Path, address, r, g.DynClient, g.Logger, opts...)
 	return s.Start(g.Context)
 }
+
+// applyMetricsConfig configures the metrics endpoint and the tracing exporter
+// using the provided metrics configuration.
+func applyMetricsConfig(ctx context.Context, mc *observability.MetricsConfig, endpoint *metrics.Endpoint, exporter *tracing.Exporter, logger *zap.SugaredLogger) {
+	if mc == nil {
+		mc = &observability.MetricsConfig{}
+	}
+
+	if err := exporter.SetAddress(mc.OpenCensusAddress); err != nil {
+		logger.Errorw("Error configuring tracing exporter", zap.Error(err))
+	}
+
+	sampleRate := tracing.DefaultSampleRate
+	if mc.TracingSampleRate != nil {
+		sampleRate = *mc.TracingSampleRate
+	}
+	if err := tracing.SetSampleRate(sampleRate); err != nil {
+		logger.Errorw("Error configuring tracing sample rate", zap.Error(err))
+	}
+
+	switch mc.BackendDestination {
+	case "":
+		endpoint.SetPort(ctx, 0)
+
+	case metricsBackendPrometheus:
+		port := mc.PrometheusPort
+		if port == 0 {
+			port = defaultPrometheusPort
+		}
+		endpoint.SetPort(ctx, port)
+
+	default:
+		logger.Warnw("Unsupported metrics backend, metrics will not be exposed",
+			zap.String("backend", mc.BackendDestination))
+		endpoint.SetPort(ctx, 0)
+	}
+}
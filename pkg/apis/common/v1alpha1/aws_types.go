@@ -38,10 +38,56 @@ type AWSAuth struct {
 	Credentials *AWSSecurityCredentials `json:"credentials,omitempty"`
 
 	// (Amazon EKS only) The ARN of an IAM role which can be impersonated
-	// to obtain AWS permissions.
+	// to obtain AWS permissions. The role is assumed with the identity of
+	// a ServiceAccount dedicated to the source, named after the source with
+	// the "-awss3source" suffix, which the role's trust policy must allow.
+	// The role is used by the hook to manage AWS resources. The receive
+	// adapter obtains AWS permissions from the ServiceAccount of its own
+	// Pods, since Scoby doesn't allow running it as the source's
+	// ServiceAccount.
 	// See https://docs.aws.amazon.com/eks/latest/userguide/iam-roles-for-service-accounts.html
 	// +optional
 	EksIAMRole *apis.ARN `json:"iamRole,omitempty"`
+
+	// IAM roles which are assumed in sequence on top of the credentials
+	// provided by one of the other authentication methods. Each role is
+	// assumed using the credentials obtained from the previous one (role
+	// chaining), which allows accessing resources in other AWS accounts.
+	// See https://docs.aws.amazon.com/IAM/latest/UserGuide/id_roles_terms-and-concepts.html#iam-term-role-chaining
+	// The receive adapter supports a single role: it assumes the last role
+	// of the chain directly, with its own credentials and without the
+	// external ID and session settings.
+	// +optional
+	AssumeRoles []AWSAssumeRole `json:"assumeRoles,omitempty"`
+}
+
+// AWSAssumeRole represents an IAM role to assume using the AWS STS API.
+// See https://docs.aws.amazon.com/STS/latest/APIReference/API_AssumeRole.html
+//
+// +k8s:deepcopy-gen=true
+type AWSAssumeRole struct {
+	// ARN of the IAM role to assume.
+	RoleARN apis.ARN `json:"roleARN"`
+
+	// Unique identifier required by the trust policy of roles which
+	// delegate access to third parties.
+	// See https://docs.aws.amazon.com/IAM/latest/UserGuide/id_roles_create_for-user_externalid.html
+	// +optional
+	ExternalID string `json:"externalID,omitempty"`
+
+	// Name of the role session, which appears in AWS CloudTrail logs.
+	// +optional
+	SessionName string `json:"sessionName,omitempty"`
+
+	// Duration of the role session, in seconds. Sessions of chained roles
+	// are limited to one hour by AWS.
+	// +optional
+	DurationSeconds *int64 `json:"durationSeconds,omitempty"`
+
+	// Tags to pass to the role session.
+	// See https://docs.aws.amazon.com/IAM/latest/UserGuide/id_session-tags.html
+	// +optional
+	SessionTags map[string]string `json:"sessionTags,omitempty"`
 }
 
 // AWSSecurityCredentials represents a set of AWS security credentials.
@@ -63,12 +109,14 @@ type AWSEndpoint struct {
 	URL *pkgapis.URL `json:"url,omitempty"`
 }
 
-const annotationEksIAMRole = "eks.amazonaws.com/role-arn"
+// AnnotationEksIAMRole is the annotation which associates a ServiceAccount
+// with an IAM role on EKS.
+const AnnotationEksIAMRole = "eks.amazonaws.com/role-arn"
 
 // AwsIamRoleAnnotation returns a functional option that sets the EKS role-arn
 // annotation on a ServiceAccount.
 func AwsIamRoleAnnotation(iamRole apis.ARN) resource.ServiceAccountOption {
 	return func(sa *corev1.ServiceAccount) {
-		metav1.SetMetaDataAnnotation(&sa.ObjectMeta, annotationEksIAMRole, iamRole.String())
+		metav1.SetMetaDataAnnotation(&sa.ObjectMeta, AnnotationEksIAMRole, iamRole.String())
 	}
 }
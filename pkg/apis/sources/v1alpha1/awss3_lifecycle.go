@@ -0,0 +1,56 @@
+/*
+Copyright 2023 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"knative.dev/pkg/kmeta"
+)
+
+// AWSS3GenericEventType is the type of events emitted by the AWSS3Source.
+const AWSS3GenericEventType = "objectnotification"
+
+// AWSEventType returns an event type in a format suitable for usage as a
+// CloudEvent type attribute.
+func AWSEventType(awsService, eventType string) string {
+	return "com.amazon." + awsService + "." + eventType
+}
+
+// GetEventTypes returns the event types generated by the source.
+func (s *AWSS3Source) GetEventTypes() []string {
+	return []string{
+		AWSEventType(s.Spec.ARN.Service, AWSS3GenericEventType),
+	}
+}
+
+// AsEventSource returns a representation of the source suitable for usage as
+// a CloudEvent source attribute.
+func (s *AWSS3Source) AsEventSource() string {
+	return s.Spec.ARN.String()
+}
+
+// GetGroupVersionKind implements kmeta.OwnerRefable.
+func (s *AWSS3Source) GetGroupVersionKind() schema.GroupVersionKind {
+	return SchemeGroupVersion.WithKind("AWSS3Source")
+}
+
+// ServiceAccountName returns the name of the ServiceAccount whose identity
+// is exchanged for the credentials of the source's EKS IAM role.
+func (s *AWSS3Source) ServiceAccountName() string {
+	return kmeta.ChildName(s.Name, "-awss3source")
+}
@@ -58,6 +58,12 @@ type AWSS3SourceSpec struct {
 	// https://docs.aws.amazon.com/AmazonS3/latest/userguide/notification-how-to-event-types-and-destinations.html
 	EventTypes []string `json:"eventTypes"`
 
+	// Filter rules which restrict notifications to objects whose key
+	// matches the given prefix and/or suffix.
+	// https://docs.aws.amazon.com/AmazonS3/latest/userguide/notification-how-to-filtering.html
+	// +optional
+	Filter *AWSS3SourceFilter `json:"filter,omitempty"`
+
 	// The intermediate destination of notifications originating from the
 	// Amazon S3 bucket, before they are retrieved by this event source.
 	// If omitted, an Amazon SQS queue is automatically created and
@@ -65,20 +71,101 @@ type AWSS3SourceSpec struct {
 	// +optional
 	Destination *AWSS3SourceDestination `json:"destination,omitempty"`
 
+	// Attributes of the Amazon SQS queue which is automatically created for
+	// the event source. Ignored when the destination is a user-provided SQS
+	// queue.
+	// +optional
+	Queue *AWSS3SourceQueue `json:"queue,omitempty"`
+
+	// Whether the hook manages the AWS resources of the event source, or
+	// only observes them. In the Observe mode, differences between the
+	// current and desired configuration of the bucket notifications and of
+	// their intermediate destinations are reported in the Drifted condition,
+	// which is then False and makes the source not Ready, instead of being
+	// applied, and AWS resources are left untouched when the source is
+	// deleted. Defaults to Manage.
+	// +optional
+	ReconcileMode AWSS3SourceReconcileMode `json:"reconcileMode,omitempty"`
+
+	// What happens to the AWS resources of the event source when it is
+	// deleted. Defaults to Delete.
+	// +optional
+	DeletionPolicy AWSS3SourceDeletionPolicy `json:"deletionPolicy,omitempty"`
+
 	// Authentication method to interact with the Amazon S3 and SQS APIs.
 	Auth v1alpha1.AWSAuth `json:"auth"`
 
+	// Customizations of the AWS REST API endpoint, for example to target
+	// Localstack or MinIO instead of the public AWS cloud.
+	// +optional
+	Endpoint *v1alpha1.AWSEndpoint `json:"endpoint,omitempty"`
+
 	// Adapter spec overrides parameters.
 	// +optional
 	AdapterOverrides *v1alpha1.AdapterOverrides `json:"adapterOverrides,omitempty"`
 }
 
+// AWSS3SourceReconcileMode is the mode of reconciliation of the AWS resources
+// of an event source.
+type AWSS3SourceReconcileMode string
+
+// Reconcile modes of AWS resources.
+const (
+	// AWSS3SourceReconcileModeManage applies the desired configuration to
+	// AWS resources.
+	AWSS3SourceReconcileModeManage AWSS3SourceReconcileMode = "Manage"
+	// AWSS3SourceReconcileModeObserve reports differences between the
+	// current and desired configuration of AWS resources without applying
+	// them.
+	AWSS3SourceReconcileModeObserve AWSS3SourceReconcileMode = "Observe"
+)
+
+// AWSS3SourceDeletionPolicy determines what happens to the AWS resources of an
+// event source when it is deleted.
+type AWSS3SourceDeletionPolicy string
+
+// Deletion policies of AWS resources.
+const (
+	// AWSS3SourceDeletionPolicyDelete disables the bucket notifications of
+	// the source, and deletes the AWS resources created for the source.
+	AWSS3SourceDeletionPolicyDelete AWSS3SourceDeletionPolicy = "Delete"
+	// AWSS3SourceDeletionPolicyRetain leaves the bucket notifications and
+	// all AWS resources of the source untouched.
+	AWSS3SourceDeletionPolicyRetain AWSS3SourceDeletionPolicy = "Retain"
+	// AWSS3SourceDeletionPolicyRetainQueue disables the bucket notifications
+	// of the source and deletes its intermediate destinations, but retains
+	// its SQS queues along with the messages they contain.
+	AWSS3SourceDeletionPolicyRetainQueue AWSS3SourceDeletionPolicy = "RetainQueue"
+)
+
+// AWSS3SourceFilter contains rules for filtering bucket notifications by
+// object key.
+type AWSS3SourceFilter struct {
+	// Prefix of the keys of matching objects.
+	// +optional
+	Prefix string `json:"prefix,omitempty"`
+
+	// Suffix of the keys of matching objects.
+	// +optional
+	Suffix string `json:"suffix,omitempty"`
+}
+
 // AWSS3SourceDestination contains possible intermediate destinations for
 // bucket notifications.
 type AWSS3SourceDestination struct {
 	// Amazon SQS destination.
 	// +optional
 	SQS *AWSS3SourceDestinationSQS `json:"sqs,omitempty"`
+
+	// Amazon SNS destination. Notifications are published to a SNS topic
+	// which the event source's SQS queue subscribes to.
+	// +optional
+	SNS *AWSS3SourceDestinationSNS `json:"sns,omitempty"`
+
+	// Amazon EventBridge destination. Notifications are sent to the default
+	// event bus, and routed to the event source's SQS queue by a rule.
+	// +optional
+	EventBridge *AWSS3SourceDestinationEventBridge `json:"eventBridge,omitempty"`
 }
 
 // AWSS3SourceDestinationSQS contains properties of an Amazon SQS queue to use
@@ -89,6 +176,66 @@ type AWSS3SourceDestinationSQS struct {
 	QueueARN apis.ARN `json:"queueARN"`
 }
 
+// AWSS3SourceDestinationSNS contains properties of an Amazon SNS topic to use
+// as destination for bucket notifications.
+type AWSS3SourceDestinationSNS struct {
+	// SNS Topic ARN
+	// https://docs.aws.amazon.com/service-authorization/latest/reference/list_amazonsns.html#amazonsns-resources-for-iam-policies
+	//
+	// If omitted, a SNS topic is automatically created and associated with
+	// the bucket. Otherwise, the topic's access policy must allow the bucket
+	// to publish notifications.
+	// +optional
+	TopicARN *apis.ARN `json:"topicARN,omitempty"`
+}
+
+// AWSS3SourceDestinationEventBridge contains properties of the Amazon
+// EventBridge destination for bucket notifications.
+type AWSS3SourceDestinationEventBridge struct{}
+
+// AWSS3SourceQueue contains attributes of the SQS queue created for the event
+// source. Attributes which are omitted are reset to the SQS defaults.
+// https://docs.aws.amazon.com/AWSSimpleQueueService/latest/APIReference/API_SetQueueAttributes.html
+type AWSS3SourceQueue struct {
+	// Server-side encryption of messages. If omitted, messages are
+	// encrypted using SQS-owned encryption keys (SSE-SQS).
+	// +optional
+	Encryption *AWSS3SourceQueueEncryption `json:"encryption,omitempty"`
+
+	// Number of seconds SQS retains a message. Defaults to 345600 (4 days).
+	// +optional
+	MessageRetentionPeriod *int64 `json:"messageRetentionPeriod,omitempty"`
+
+	// Number of seconds a received message remains hidden from subsequent
+	// receive requests. Defaults to 30.
+	// +optional
+	VisibilityTimeout *int64 `json:"visibilityTimeout,omitempty"`
+
+	// Dead-letter queue which receives messages that could not be processed.
+	// If set, a dead-letter queue is automatically created for the event
+	// source.
+	// +optional
+	DeadLetterQueue *AWSS3SourceDeadLetterQueue `json:"deadLetterQueue,omitempty"`
+}
+
+// AWSS3SourceQueueEncryption contains the server-side encryption settings of
+// the SQS queue created for the event source.
+// https://docs.aws.amazon.com/AWSSimpleQueueService/latest/SQSDeveloperGuide/sqs-server-side-encryption.html
+type AWSS3SourceQueueEncryption struct {
+	// ID, alias or ARN of the AWS KMS key used to encrypt messages (SSE-KMS).
+	// The key policy must allow the S3 service principal to use the key.
+	KMSKeyID string `json:"kmsKeyID"`
+}
+
+// AWSS3SourceDeadLetterQueue contains the redrive policy of the SQS queue
+// created for the event source.
+// https://docs.aws.amazon.com/AWSSimpleQueueService/latest/SQSDeveloperGuide/sqs-dead-letter-queues.html
+type AWSS3SourceDeadLetterQueue struct {
+	// Number of times a message is received before being moved to the
+	// dead-letter queue.
+	MaxReceiveCount int64 `json:"maxReceiveCount"`
+}
+
 // AWSS3SourceStatus defines the observed state of the event source.
 type AWSS3SourceStatus struct {
 	v1alpha1.Status `json:",inline"`
@@ -0,0 +1,329 @@
+//go:build !ignore_autogenerated
+// +build !ignore_autogenerated
+
+/*
+Copyright 2023 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Code generated by deepcopy-gen. DO NOT EDIT.
+
+package v1alpha1
+
+import (
+	apis "github.com/triggermesh/scoby-hook-triggermesh/pkg/apis"
+	commonv1alpha1 "github.com/triggermesh/scoby-hook-triggermesh/pkg/apis/common/v1alpha1"
+	runtime "k8s.io/apimachinery/pkg/runtime"
+)
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AWSS3Source) DeepCopyInto(out *AWSS3Source) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AWSS3Source.
+func (in *AWSS3Source) DeepCopy() *AWSS3Source {
+	if in == nil {
+		return nil
+	}
+	out := new(AWSS3Source)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *AWSS3Source) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AWSS3SourceDeadLetterQueue) DeepCopyInto(out *AWSS3SourceDeadLetterQueue) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AWSS3SourceDeadLetterQueue.
+func (in *AWSS3SourceDeadLetterQueue) DeepCopy() *AWSS3SourceDeadLetterQueue {
+	if in == nil {
+		return nil
+	}
+	out := new(AWSS3SourceDeadLetterQueue)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AWSS3SourceDestination) DeepCopyInto(out *AWSS3SourceDestination) {
+	*out = *in
+	if in.SQS != nil {
+		in, out := &in.SQS, &out.SQS
+		*out = new(AWSS3SourceDestinationSQS)
+		**out = **in
+	}
+	if in.SNS != nil {
+		in, out := &in.SNS, &out.SNS
+		*out = new(AWSS3SourceDestinationSNS)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.EventBridge != nil {
+		in, out := &in.EventBridge, &out.EventBridge
+		*out = new(AWSS3SourceDestinationEventBridge)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AWSS3SourceDestination.
+func (in *AWSS3SourceDestination) DeepCopy() *AWSS3SourceDestination {
+	if in == nil {
+		return nil
+	}
+	out := new(AWSS3SourceDestination)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AWSS3SourceDestinationEventBridge) DeepCopyInto(out *AWSS3SourceDestinationEventBridge) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AWSS3SourceDestinationEventBridge.
+func (in *AWSS3SourceDestinationEventBridge) DeepCopy() *AWSS3SourceDestinationEventBridge {
+	if in == nil {
+		return nil
+	}
+	out := new(AWSS3SourceDestinationEventBridge)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AWSS3SourceDestinationSNS) DeepCopyInto(out *AWSS3SourceDestinationSNS) {
+	*out = *in
+	if in.TopicARN != nil {
+		in, out := &in.TopicARN, &out.TopicARN
+		*out = new(apis.ARN)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AWSS3SourceDestinationSNS.
+func (in *AWSS3SourceDestinationSNS) DeepCopy() *AWSS3SourceDestinationSNS {
+	if in == nil {
+		return nil
+	}
+	out := new(AWSS3SourceDestinationSNS)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AWSS3SourceDestinationSQS) DeepCopyInto(out *AWSS3SourceDestinationSQS) {
+	*out = *in
+	out.QueueARN = in.QueueARN
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AWSS3SourceDestinationSQS.
+func (in *AWSS3SourceDestinationSQS) DeepCopy() *AWSS3SourceDestinationSQS {
+	if in == nil {
+		return nil
+	}
+	out := new(AWSS3SourceDestinationSQS)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AWSS3SourceFilter) DeepCopyInto(out *AWSS3SourceFilter) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AWSS3SourceFilter.
+func (in *AWSS3SourceFilter) DeepCopy() *AWSS3SourceFilter {
+	if in == nil {
+		return nil
+	}
+	out := new(AWSS3SourceFilter)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AWSS3SourceList) DeepCopyInto(out *AWSS3SourceList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]AWSS3Source, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AWSS3SourceList.
+func (in *AWSS3SourceList) DeepCopy() *AWSS3SourceList {
+	if in == nil {
+		return nil
+	}
+	out := new(AWSS3SourceList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *AWSS3SourceList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AWSS3SourceQueue) DeepCopyInto(out *AWSS3SourceQueue) {
+	*out = *in
+	if in.Encryption != nil {
+		in, out := &in.Encryption, &out.Encryption
+		*out = new(AWSS3SourceQueueEncryption)
+		**out = **in
+	}
+	if in.MessageRetentionPeriod != nil {
+		in, out := &in.MessageRetentionPeriod, &out.MessageRetentionPeriod
+		*out = new(int64)
+		**out = **in
+	}
+	if in.VisibilityTimeout != nil {
+		in, out := &in.VisibilityTimeout, &out.VisibilityTimeout
+		*out = new(int64)
+		**out = **in
+	}
+	if in.DeadLetterQueue != nil {
+		in, out := &in.DeadLetterQueue, &out.DeadLetterQueue
+		*out = new(AWSS3SourceDeadLetterQueue)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AWSS3SourceQueue.
+func (in *AWSS3SourceQueue) DeepCopy() *AWSS3SourceQueue {
+	if in == nil {
+		return nil
+	}
+	out := new(AWSS3SourceQueue)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AWSS3SourceQueueEncryption) DeepCopyInto(out *AWSS3SourceQueueEncryption) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AWSS3SourceQueueEncryption.
+func (in *AWSS3SourceQueueEncryption) DeepCopy() *AWSS3SourceQueueEncryption {
+	if in == nil {
+		return nil
+	}
+	out := new(AWSS3SourceQueueEncryption)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AWSS3SourceSpec) DeepCopyInto(out *AWSS3SourceSpec) {
+	*out = *in
+	in.SourceSpec.DeepCopyInto(&out.SourceSpec)
+	out.ARN = in.ARN
+	if in.EventTypes != nil {
+		in, out := &in.EventTypes, &out.EventTypes
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Filter != nil {
+		in, out := &in.Filter, &out.Filter
+		*out = new(AWSS3SourceFilter)
+		**out = **in
+	}
+	if in.Destination != nil {
+		in, out := &in.Destination, &out.Destination
+		*out = new(AWSS3SourceDestination)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Queue != nil {
+		in, out := &in.Queue, &out.Queue
+		*out = new(AWSS3SourceQueue)
+		(*in).DeepCopyInto(*out)
+	}
+	in.Auth.DeepCopyInto(&out.Auth)
+	if in.Endpoint != nil {
+		in, out := &in.Endpoint, &out.Endpoint
+		*out = new(commonv1alpha1.AWSEndpoint)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.AdapterOverrides != nil {
+		in, out := &in.AdapterOverrides, &out.AdapterOverrides
+		*out = new(commonv1alpha1.AdapterOverrides)
+		(*in).DeepCopyInto(*out)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AWSS3SourceSpec.
+func (in *AWSS3SourceSpec) DeepCopy() *AWSS3SourceSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(AWSS3SourceSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AWSS3SourceStatus) DeepCopyInto(out *AWSS3SourceStatus) {
+	*out = *in
+	in.Status.DeepCopyInto(&out.Status)
+	if in.QueueARN != nil {
+		in, out := &in.QueueARN, &out.QueueARN
+		*out = new(apis.ARN)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AWSS3SourceStatus.
+func (in *AWSS3SourceStatus) DeepCopy() *AWSS3SourceStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(AWSS3SourceStatus)
+	in.DeepCopyInto(out)
+	return out
+}
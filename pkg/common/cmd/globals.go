@@ -8,7 +8,9 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
+	"reflect"
 	"strings"
+	"sync"
 	"time"
 
 	"go.uber.org/automaxprocs/maxprocs"
@@ -44,12 +46,20 @@ type Globals struct {
 	KubernetesNamespace                  string `help:"Namespace where the hook is running." env:"KUBERNETES_NAMESPACE"`
 	KubernetesObservabilityConfigMapName string `help:"ConfigMap object name that contains the observability configuration." env:"KUBERNETES_OBSERVABILITY_CONFIGMAP_NAME"`
 
-	Context      context.Context    `kong:"-"`
-	Logger       *zap.SugaredLogger `kong:"-"`
-	LogLevel     zap.AtomicLevel    `kong:"-"`
-	KubeClient   kclient.Interface  `kong:"-"`
-	DynClient    kdclient.Interface `kong:"-"`
-	ConfigMethod ConfigMethod       `kong:"-"`
+	Context       context.Context              `kong:"-"`
+	Logger        *zap.SugaredLogger           `kong:"-"`
+	LogLevel      zap.AtomicLevel              `kong:"-"`
+	MetricsConfig *observability.MetricsConfig `kong:"-"`
+	KubeClient    kclient.Interface            `kong:"-"`
+	DynClient     kdclient.Interface           `kong:"-"`
+	ConfigMethod  ConfigMethod                 `kong:"-"`
+
+	// logCore is the logger core, which is replaced when the logging
+	// configuration changes.
+	logCore *observability.ReloadableCore
+
+	m               sync.Mutex
+	metricsHandlers []func(*observability.MetricsConfig)
 }
 
 func (g *Globals) Validate() error {
@@ -145,8 +155,10 @@ func (g *Globals) Initialize() error {
 		cfg = observability.DefaultConfig()
 	}
 
+	g.logCore = observability.NewReloadableCore()
+
 	// Call build to perform validation of zap configuration.
-	l, err = cfg.LoggerCfg.Build()
+	l, err = cfg.LoggerCfg.Build(g.logCore.WrapCore())
 	for {
 		if err == nil {
 			break
@@ -157,13 +169,81 @@ func (g *Globals) Initialize() error {
 
 		defaultConfigApplied = true
 		cfg = observability.DefaultConfig()
-		l, err = cfg.LoggerCfg.Build()
+		l, err = cfg.LoggerCfg.Build(g.logCore.WrapCore())
 	}
 
-	g.LogLevel = cfg.LoggerCfg.Level
-
 	g.Logger = l.Sugar()
 	g.LogLevel = cfg.LoggerCfg.Level
+	g.MetricsConfig = cfg.MetricsConfig
+
+	if g.KubernetesObservabilityConfigMapName != "" {
+		w := observability.NewWatcher(g.KubeClient, g.KubernetesNamespace, g.KubernetesObservabilityConfigMapName, g.Logger)
+		w.OnChange(g.applyConfig)
+		go w.Start(g.Context)
+	}
+
+	return nil
+}
+
+// WatchMetricsConfig calls the provided function with the current metrics
+// configuration, and then each time it changes.
+func (g *Globals) WatchMetricsConfig(f func(*observability.MetricsConfig)) {
+	g.m.Lock()
+	defer g.m.Unlock()
+
+	g.metricsHandlers = append(g.metricsHandlers, f)
+	f(g.MetricsConfig)
+}
+
+// applyConfig applies an updated observability configuration to the running
+// process.
+func (g *Globals) applyConfig(cfg *observability.Config) {
+	if err := g.applyLoggerConfig(cfg.LoggerCfg); err != nil {
+		g.Logger.Errorw("Error applying logger configuration, keeping current configuration", zap.Error(err))
+	}
+
+	g.m.Lock()
+	defer g.m.Unlock()
+
+	// a nil configuration disables metrics, which handlers must be
+	// notified about as well
+	mc := cfg.MetricsConfig
+	if reflect.DeepEqual(mc, g.MetricsConfig) {
+		return
+	}
+
+	if mc == nil {
+		g.Logger.Info("Metrics configuration removed, disabling metrics")
+	} else {
+		g.Logger.Infow("Applying updated metrics configuration", zap.Any("metrics", mc))
+	}
+	g.MetricsConfig = mc
+	for _, f := range g.metricsHandlers {
+		f(mc)
+	}
+}
+
+// applyLoggerConfig replaces the logger core with one built from the provided
+// configuration. The log level is set on the existing atomic level so that
+// every logger derived from the initial one observes the change.
+func (g *Globals) applyLoggerConfig(cfg *zap.Config) error {
+	if cfg == nil {
+		return nil
+	}
+
+	lvl := cfg.Level.Level()
+	cfg.Level = g.LogLevel
+
+	l, err := cfg.Build()
+	if err != nil {
+		return fmt.Errorf("building logger: %w", err)
+	}
+
+	if g.LogLevel.Level() != lvl {
+		g.Logger.Infow("Changing log level", zap.Stringer("from", g.LogLevel.Level()), zap.Stringer("to", lvl))
+		g.LogLevel.SetLevel(lvl)
+	}
+	g.logCore.Swap(l.Core())
 
 	return nil
 }
@@ -0,0 +1,41 @@
+// Copyright 2023 TriggerMesh Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+
+	"github.com/triggermesh/scoby-hook-triggermesh/pkg/config/observability"
+)
+
+func TestApplyMetricsConfig(t *testing.T) {
+	g := &Globals{Logger: zap.NewNop().Sugar()}
+
+	var applied []*observability.MetricsConfig
+	g.WatchMetricsConfig(func(mc *observability.MetricsConfig) {
+		applied = append(applied, mc)
+	})
+
+	newConfig := func(sampleRate float64) *observability.Config {
+		return &observability.Config{MetricsConfig: &observability.MetricsConfig{
+			BackendDestination: "prometheus",
+			TracingSampleRate:  &sampleRate,
+		}}
+	}
+
+	g.applyConfig(newConfig(0.5))
+	g.applyConfig(newConfig(0.5))
+	assert.Len(t, applied, 2, "Identical configurations should only be applied once")
+
+	g.applyConfig(newConfig(1))
+	assert.Len(t, applied, 3, "Updated configuration should be applied")
+
+	g.applyConfig(&observability.Config{})
+	assert.Len(t, applied, 4, "Removed configuration should be applied")
+	assert.Nil(t, applied[3])
+	assert.Nil(t, g.MetricsConfig)
+}
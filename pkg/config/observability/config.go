@@ -19,6 +19,7 @@ const (
 	reportingPeriodSecondsLabel = "metrics.reporting-period-seconds"
 	prometheusPortLabel         = "metrics.prometheus-port"
 	openCensusAddressLabel      = "metrics.opencensus-address"
+	tracingSampleRateLabel      = "tracing.sample-rate"
 )
 
 type Config struct {
@@ -50,7 +51,7 @@ func (c *Config) ToMap() (map[string]string, error) {
 		}
 		if f, ok := v.(float64); ok {
 			if f != 0 {
-				m[k] = strconv.Itoa(int(f))
+				m[k] = strconv.FormatFloat(f, 'f', -1, 64)
 			}
 			continue
 		}
@@ -65,6 +66,9 @@ type MetricsConfig struct {
 	ReportingPeriodSeconds int    `json:"metrics.reporting-period-seconds"`
 	PrometheusPort         int    `json:"metrics.prometheus-port"`
 	OpenCensusAddress      string `json:"metrics.opencensus-address"`
+	// TracingSampleRate is the fraction of traces sampled, in the range
+	// [0, 1]. The default rate applies when it is not set.
+	TracingSampleRate *float64 `json:"tracing.sample-rate,omitempty"`
 }
 
 func ReadFromFile(file string) (*Config, error) {
@@ -147,5 +151,16 @@ func ParseFromMap(content map[string]string) (*Config, error) {
 		cfg.OpenCensusAddress = c
 	}
 
+	if c, ok := content[tracingSampleRateLabel]; ok {
+		r, err := strconv.ParseFloat(c, 64)
+		if err != nil {
+			return nil, fmt.Errorf("tracing sample rate must be a number: %w", err)
+		}
+		if r < 0 || r > 1 {
+			return nil, fmt.Errorf("tracing sample rate %v is not in the range [0, 1]", r)
+		}
+		cfg.TracingSampleRate = &r
+	}
+
 	return cfg, nil
 }
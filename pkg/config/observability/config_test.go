@@ -106,6 +106,7 @@ metrics.prometheus-port: 9092
 
 func TestConfigToMap(t *testing.T) {
 	info := zap.NewAtomicLevelAt(zapcore.InfoLevel)
+	sampleRate := 0.25
 	cases := map[string]struct {
 		config      Config
 		expectedMap map[string]string
@@ -128,6 +129,18 @@ func TestConfigToMap(t *testing.T) {
 				"metrics.reporting-period-seconds": "5",
 			},
 		},
+		"tracing": {
+			config: Config{
+				MetricsConfig: &MetricsConfig{
+					OpenCensusAddress: "otel-collector:55678",
+					TracingSampleRate: &sampleRate,
+				},
+			},
+			expectedMap: map[string]string{
+				"metrics.opencensus-address": "otel-collector:55678",
+				"tracing.sample-rate":        "0.25",
+			},
+		},
 	}
 
 	for name, tc := range cases {
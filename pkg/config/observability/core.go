@@ -0,0 +1,109 @@
+// Copyright 2023 TriggerMesh Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package observability
+
+import (
+	"sync/atomic"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// ReloadableCore is a zapcore.Core that forwards entries to a core that can be
+// replaced at runtime. Loggers derived from it, including those created using
+// With, keep writing to the latest core.
+type ReloadableCore struct {
+	root   *atomic.Pointer[coreRef]
+	fields []zapcore.Field
+
+	// derived caches the result of adding fields to the current root core.
+	derived atomic.Pointer[derivedCore]
+}
+
+// coreRef wraps a core so it can be stored in an atomic pointer.
+type coreRef struct {
+	zapcore.Core
+}
+
+// derivedCore is a core with fields added, built from a root core.
+type derivedCore struct {
+	root *coreRef
+	core zapcore.Core
+}
+
+var _ zapcore.Core = (*ReloadableCore)(nil)
+
+// NewReloadableCore returns a ReloadableCore that discards entries until a
+// core is swapped in.
+func NewReloadableCore() *ReloadableCore {
+	c := &ReloadableCore{
+		root: &atomic.Pointer[coreRef]{},
+	}
+	c.root.Store(&coreRef{Core: zapcore.NewNopCore()})
+	return c
+}
+
+// Swap replaces the core entries are written to. The replaced core is synced.
+func (c *ReloadableCore) Swap(core zapcore.Core) {
+	if old := c.root.Swap(&coreRef{Core: core}); old != nil {
+		_ = old.Sync()
+	}
+}
+
+// WrapCore returns a zap option that swaps in the logger core and replaces it
+// with this ReloadableCore.
+func (c *ReloadableCore) WrapCore() zap.Option {
+	return zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+		c.Swap(core)
+		return c
+	})
+}
+
+// current returns the core entries must be written to.
+func (c *ReloadableCore) current() zapcore.Core {
+	root := c.root.Load()
+	if len(c.fields) == 0 {
+		return root.Core
+	}
+
+	if d := c.derived.Load(); d != nil && d.root == root {
+		return d.core
+	}
+
+	d := &derivedCore{root: root, core: root.With(c.fields)}
+	c.derived.Store(d)
+	return d.core
+}
+
+// Enabled implements zapcore.LevelEnabler.
+func (c *ReloadableCore) Enabled(l zapcore.Level) bool {
+	return c.current().Enabled(l)
+}
+
+// With implements zapcore.Core.
+func (c *ReloadableCore) With(fields []zapcore.Field) zapcore.Core {
+	f := make([]zapcore.Field, 0, len(c.fields)+len(fields))
+	f = append(f, c.fields...)
+	f = append(f, fields...)
+
+	return &ReloadableCore{
+		root:   c.root,
+		fields: f,
+	}
+}
+
+// Check implements zapcore.Core.
+func (c *ReloadableCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	return c.current().Check(ent, ce)
+}
+
+// Write implements zapcore.Core.
+func (c *ReloadableCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	return c.current().Write(ent, fields)
+}
+
+// Sync implements zapcore.Core.
+func (c *ReloadableCore) Sync() error {
+	return c.root.Load().Sync()
+}
@@ -0,0 +1,41 @@
+// Copyright 2023 TriggerMesh Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package observability
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestReloadableCore(t *testing.T) {
+	c := NewReloadableCore()
+
+	first, firstLogs := observer.New(zapcore.InfoLevel)
+	l := zap.New(first, c.WrapCore())
+	derived := l.With(zap.String("component", "test"))
+
+	l.Info("first")
+	l.Debug("discarded")
+	require.Equal(t, 1, firstLogs.Len())
+
+	second, secondLogs := observer.New(zapcore.DebugLevel)
+	c.Swap(second)
+
+	l.Debug("second")
+	derived.Info("derived")
+
+	assert.Equal(t, 1, firstLogs.Len(), "Expected no entries written to the replaced core")
+	require.Equal(t, 2, secondLogs.Len())
+
+	entries := secondLogs.All()
+	assert.Equal(t, "second", entries[0].Message)
+	assert.Equal(t, "derived", entries[1].Message)
+	assert.Equal(t, map[string]interface{}{"component": "test"}, entries[1].ContextMap(),
+		"Expected fields added to derived loggers to be kept")
+}
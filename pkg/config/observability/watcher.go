@@ -0,0 +1,107 @@
+// Copyright 2023 TriggerMesh Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package observability
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"go.uber.org/zap"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	kclient "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+)
+
+// Watcher keeps an observability ConfigMap under watch and notifies
+// listeners when its configuration changes.
+type Watcher struct {
+	lw cache.ListerWatcher
+
+	m         sync.Mutex
+	listeners []func(*Config)
+
+	logger *zap.SugaredLogger
+}
+
+// NewWatcher creates a Watcher for the ConfigMap with the provided namespace
+// and name.
+func NewWatcher(cli kclient.Interface, namespace, name string, logger *zap.SugaredLogger) *Watcher {
+	sel := fields.OneTermEqualSelector("metadata.name", name).String()
+	cms := cli.CoreV1().ConfigMaps(namespace)
+
+	return &Watcher{
+		lw: &cache.ListWatch{
+			ListFunc: func(opts metav1.ListOptions) (runtime.Object, error) {
+				opts.FieldSelector = sel
+				return cms.List(context.Background(), opts)
+			},
+			WatchFunc: func(opts metav1.ListOptions) (watch.Interface, error) {
+				opts.FieldSelector = sel
+				return cms.Watch(context.Background(), opts)
+			},
+		},
+		logger: logger,
+	}
+}
+
+// OnChange registers a function that is called with the new configuration
+// each time the ConfigMap changes. When the ConfigMap is deleted the default
+// configuration is notified.
+func (w *Watcher) OnChange(f func(*Config)) {
+	w.m.Lock()
+	defer w.m.Unlock()
+
+	w.listeners = append(w.listeners, f)
+}
+
+// Start watches the ConfigMap until the context is done.
+func (w *Watcher) Start(ctx context.Context) {
+	_, ctrl := cache.NewInformer(w.lw, &corev1.ConfigMap{}, 0, cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			w.update(obj)
+		},
+		UpdateFunc: func(_, obj interface{}) {
+			w.update(obj)
+		},
+		DeleteFunc: func(interface{}) {
+			w.logger.Warn("Observability ConfigMap was deleted, applying default configuration")
+			w.notify(DefaultConfig())
+		},
+	})
+
+	ctrl.Run(ctx.Done())
+}
+
+// update parses the ConfigMap and notifies listeners of the configuration
+// it contains.
+func (w *Watcher) update(obj interface{}) {
+	cm, ok := obj.(*corev1.ConfigMap)
+	if !ok {
+		return
+	}
+
+	cfg, err := ParseFromMap(cm.Data)
+	if err != nil {
+		msg := err.Error()
+		w.logger.Error("Error parsing observability ConfigMap, keeping current configuration", zap.Error(errors.New(msg)))
+		return
+	}
+
+	w.notify(cfg)
+}
+
+func (w *Watcher) notify(cfg *Config) {
+	w.m.Lock()
+	defer w.m.Unlock()
+
+	for _, f := range w.listeners {
+		f(cfg)
+	}
+}
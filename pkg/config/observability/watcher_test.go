@@ -0,0 +1,83 @@
+// Copyright 2023 TriggerMesh Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package observability
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestWatcher(t *testing.T) {
+	const (
+		ns   = "triggermesh"
+		name = "config-observability"
+	)
+
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Namespace: ns, Name: name},
+		Data: map[string]string{
+			zapLoggerConfigLabel: `{"level": "info"}`,
+		},
+	}
+	cli := fake.NewSimpleClientset(cm)
+
+	cfgs := make(chan *Config, 10)
+	w := NewWatcher(cli, ns, name, zap.NewNop().Sugar())
+	w.OnChange(func(cfg *Config) { cfgs <- cfg })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go w.Start(ctx)
+
+	cfg := receiveConfig(t, cfgs)
+	assert.Equal(t, zapcore.InfoLevel, cfg.LoggerCfg.Level.Level())
+
+	cm = cm.DeepCopy()
+	cm.Data = map[string]string{
+		zapLoggerConfigLabel:    `{"level": "debug"}`,
+		backendDestinationLabel: "prometheus",
+	}
+	_, err := cli.CoreV1().ConfigMaps(ns).Update(ctx, cm, metav1.UpdateOptions{})
+	require.NoError(t, err)
+
+	cfg = receiveConfig(t, cfgs)
+	assert.Equal(t, zapcore.DebugLevel, cfg.LoggerCfg.Level.Level())
+	assert.Equal(t, "prometheus", cfg.BackendDestination)
+
+	cm = cm.DeepCopy()
+	cm.Data = map[string]string{
+		prometheusPortLabel: "not a number",
+	}
+	_, err = cli.CoreV1().ConfigMaps(ns).Update(ctx, cm, metav1.UpdateOptions{})
+	require.NoError(t, err)
+
+	err = cli.CoreV1().ConfigMaps(ns).Delete(ctx, name, metav1.DeleteOptions{})
+	require.NoError(t, err)
+
+	cfg = receiveConfig(t, cfgs)
+	assert.Equal(t, DefaultConfig().MetricsConfig, cfg.MetricsConfig,
+		"Expected invalid configuration to be skipped and defaults applied on deletion")
+}
+
+func receiveConfig(t *testing.T, cfgs <-chan *Config) *Config {
+	t.Helper()
+
+	select {
+	case cfg := <-cfgs:
+		return cfg
+	case <-time.After(5 * time.Second):
+		require.FailNow(t, "Timed out waiting for configuration")
+		return nil
+	}
+}
@@ -0,0 +1,62 @@
+// Copyright 2023 TriggerMesh Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package handler
+
+import (
+	"fmt"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// Decoder converts objects retrieved through the dynamic client into the
+// Go types declared by the registered handlers.
+type Decoder struct {
+	scheme *runtime.Scheme
+}
+
+// NewDecoder returns a Decoder that knows the Go type of every handler at the
+// registry. Handlers that declare an unstructured type are not registered and
+// will receive the object as retrieved from the cluster.
+func NewDecoder(reg Registry) *Decoder {
+	scheme := runtime.NewScheme()
+
+	for gvk, h := range reg {
+		obj := h.NewObject()
+		if _, ok := obj.(runtime.Unstructured); ok {
+			continue
+		}
+		scheme.AddKnownTypeWithName(gvk, obj)
+	}
+
+	return &Decoder{
+		scheme: scheme,
+	}
+}
+
+// Decode converts the unstructured object into the Go type registered for
+// its GroupVersionKind.
+func (d *Decoder) Decode(u *unstructured.Unstructured) (metav1.Object, error) {
+	gvk := u.GroupVersionKind()
+	if !d.scheme.Recognizes(gvk) {
+		return u, nil
+	}
+
+	obj, err := d.scheme.New(gvk)
+	if err != nil {
+		return nil, fmt.Errorf("creating object for %q: %w", gvk.String(), err)
+	}
+
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(u.UnstructuredContent(), obj); err != nil {
+		return nil, fmt.Errorf("converting object into %T: %w", obj, err)
+	}
+
+	mobj, ok := obj.(metav1.Object)
+	if !ok {
+		return nil, fmt.Errorf("type %T registered for %q does not expose object metadata", obj, gvk.String())
+	}
+
+	return mobj, nil
+}
@@ -0,0 +1,117 @@
+// Copyright 2023 TriggerMesh Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package handler
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	hookv1 "github.com/triggermesh/scoby/pkg/hook/v1"
+
+	"github.com/triggermesh/scoby-hook-triggermesh/pkg/apis/sources/v1alpha1"
+)
+
+type fakeHandler struct {
+	gvr  schema.GroupVersionResource
+	kind string
+	obj  runtime.Object
+}
+
+func (h *fakeHandler) GroupVersionResource() *schema.GroupVersionResource { return &h.gvr }
+func (h *fakeHandler) Kind() string                                       { return h.kind }
+func (h *fakeHandler) NewObject() runtime.Object                          { return h.obj.DeepCopyObject() }
+func (h *fakeHandler) Reconcile(context.Context, metav1.Object) *hookv1.HookResponse {
+	return nil
+}
+
+func TestDecode(t *testing.T) {
+	reg := NewRegistry([]Handler{
+		&fakeHandler{
+			gvr:  v1alpha1.SchemeGroupVersion.WithResource("awss3sources"),
+			kind: "AWSS3Source",
+			obj:  &v1alpha1.AWSS3Source{},
+		},
+		&fakeHandler{
+			gvr:  schema.GroupVersionResource{Group: "extensions.triggermesh.io", Version: "v1", Resource: "kuards"},
+			kind: "Kuard",
+			obj:  &unstructured.Unstructured{},
+		},
+	})
+
+	dec := NewDecoder(reg)
+
+	testCases := map[string]struct {
+		object      map[string]interface{}
+		expectType  interface{}
+		expectError bool
+	}{
+		"typed object": {
+			object: map[string]interface{}{
+				"apiVersion": "sources.triggermesh.io/v1alpha1",
+				"kind":       "AWSS3Source",
+				"metadata": map[string]interface{}{
+					"namespace": "ns",
+					"name":      "name",
+				},
+				"spec": map[string]interface{}{
+					"arn":        "arn:aws:s3:::bucket",
+					"eventTypes": []interface{}{"s3:ObjectCreated:*"},
+				},
+			},
+			expectType: &v1alpha1.AWSS3Source{},
+		},
+		"unstructured object": {
+			object: map[string]interface{}{
+				"apiVersion": "extensions.triggermesh.io/v1",
+				"kind":       "Kuard",
+				"metadata": map[string]interface{}{
+					"namespace": "ns",
+					"name":      "name",
+				},
+			},
+			expectType: &unstructured.Unstructured{},
+		},
+		"malformed typed object": {
+			object: map[string]interface{}{
+				"apiVersion": "sources.triggermesh.io/v1alpha1",
+				"kind":       "AWSS3Source",
+				"metadata": map[string]interface{}{
+					"namespace": "ns",
+					"name":      "name",
+				},
+				"spec": map[string]interface{}{
+					"arn": "not an ARN",
+				},
+			},
+			expectError: true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			obj, err := dec.Decode(&unstructured.Unstructured{Object: tc.object})
+			if tc.expectError {
+				assert.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.IsType(t, tc.expectType, obj)
+			assert.Equal(t, "ns", obj.GetNamespace())
+			assert.Equal(t, "name", obj.GetName())
+		})
+	}
+
+	src, err := dec.Decode(&unstructured.Unstructured{Object: testCases["typed object"].object})
+	require.NoError(t, err)
+	assert.Equal(t, "bucket", src.(*v1alpha1.AWSS3Source).Spec.ARN.Resource)
+}
@@ -0,0 +1,51 @@
+// Copyright 2023 TriggerMesh Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package handler
+
+import (
+	"context"
+	"sync"
+)
+
+// errorsKey is the context key for the errors reported by handlers.
+type errorsKey struct{}
+
+// reportedErrors collects the errors reported while handling an object.
+type reportedErrors struct {
+	mu   sync.Mutex
+	errs []error
+}
+
+// WithErrorReporting returns a copy of the parent context in which handlers
+// can report the errors that caused an operation to fail.
+func WithErrorReporting(ctx context.Context) context.Context {
+	return context.WithValue(ctx, errorsKey{}, &reportedErrors{})
+}
+
+// ReportError reports an error that caused the operation on the handled
+// object to fail, so that the caller can hint at whether and when the
+// operation should be retried.
+// The error is discarded when the context does not support error reporting.
+func ReportError(ctx context.Context, err error) {
+	r, ok := ctx.Value(errorsKey{}).(*reportedErrors)
+	if !ok || err == nil {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.errs = append(r.errs, err)
+}
+
+// ReportedErrors returns the errors reported in the context.
+func ReportedErrors(ctx context.Context) []error {
+	r, ok := ctx.Value(errorsKey{}).(*reportedErrors)
+	if !ok {
+		return nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]error(nil), r.errs...)
+}
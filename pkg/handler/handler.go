@@ -7,8 +7,10 @@ import (
 	"context"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 
+	commonv1alpha1 "github.com/triggermesh/scoby/pkg/apis/common/v1alpha1"
 	hookv1 "github.com/triggermesh/scoby/pkg/hook/v1"
 )
 
@@ -23,6 +25,10 @@ type Handler interface {
 	GroupVersionResource() *schema.GroupVersionResource
 	// Kind for the managed object
 	Kind() string
+	// NewObject returns an empty instance of the Go type the handler expects
+	// to receive at Reconcile and Finalize. Handlers that work on generic
+	// objects return an *unstructured.Unstructured.
+	NewObject() runtime.Object
 
 	Reconcile(ctx context.Context, obj metav1.Object) *hookv1.HookResponse
 }
@@ -31,3 +37,17 @@ type Handler interface {
 type HandlerFinalizable interface {
 	Finalize(ctx context.Context, obj metav1.Object) *hookv1.HookResponse
 }
+
+// HandlerReadiness exposes whether a handler is initialized and ready to serve
+// requests. Handlers that do not implement it are ready once registered.
+type HandlerReadiness interface {
+	Ready(ctx context.Context) error
+}
+
+// HandlerConditions exposes the conditions a handler reports for each
+// operation, along with their initial state. Middlewares use them to
+// initialize responses and to report failures that prevent the handler from
+// producing a response.
+type HandlerConditions interface {
+	InitialConditions(op hookv1.Operation) commonv1alpha1.Conditions
+}
@@ -7,6 +7,8 @@ import (
 	"context"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 
 	commonv1alpha1 "github.com/triggermesh/scoby/pkg/apis/common/v1alpha1"
@@ -43,6 +45,11 @@ func (h *KuardHandler) Kind() string {
 	return h.kind
 }
 
+// NewObject returns an unstructured object, kuards do not have a Go type.
+func (h *KuardHandler) NewObject() runtime.Object {
+	return &unstructured.Unstructured{}
+}
+
 func (h *KuardHandler) Reconcile(ctx context.Context, obj metav1.Object) *hookv1.HookResponse {
 	return &hookv1.HookResponse{
 		Status: &hookv1.HookStatus{
@@ -0,0 +1,105 @@
+// Copyright 2023 TriggerMesh Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package handler
+
+import (
+	"context"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	hookv1 "github.com/triggermesh/scoby/pkg/hook/v1"
+)
+
+// OperationFunc handles a hook operation on an object.
+type OperationFunc func(ctx context.Context, obj metav1.Object) *hookv1.HookResponse
+
+// OperationInfo describes the handler operation wrapped by a middleware.
+type OperationInfo struct {
+	GVK       schema.GroupVersionKind
+	Operation hookv1.Operation
+	// Handler is the handler being wrapped, which can be used to
+	// discover optional interfaces it implements.
+	Handler Handler
+}
+
+// Middleware wraps a handler operation to add behavior before and after it.
+type Middleware func(info OperationInfo, next OperationFunc) OperationFunc
+
+// Chain wraps the Reconcile and, if implemented, Finalize operations of the
+// handler with the provided middlewares. The first middleware is the
+// outermost one.
+func Chain(h Handler, mws ...Middleware) Handler {
+	if len(mws) == 0 {
+		return h
+	}
+
+	gvr := h.GroupVersionResource()
+	gvk := gvr.GroupVersion().WithKind(h.Kind())
+
+	wrap := func(op hookv1.Operation, f OperationFunc) OperationFunc {
+		info := OperationInfo{GVK: gvk, Operation: op, Handler: h}
+		for i := len(mws) - 1; i >= 0; i-- {
+			f = mws[i](info, f)
+		}
+		return f
+	}
+
+	ch := &chainedHandler{
+		Handler:   h,
+		reconcile: wrap(hookv1.OperationReconcile, h.Reconcile),
+	}
+
+	if fh, ok := h.(HandlerFinalizable); ok {
+		return &chainedFinalizableHandler{
+			chainedHandler: ch,
+			finalize:       wrap(hookv1.OperationFinalize, fh.Finalize),
+		}
+	}
+
+	return ch
+}
+
+// chainedHandler is a Handler whose operations are wrapped by middlewares.
+type chainedHandler struct {
+	Handler
+	reconcile OperationFunc
+}
+
+var (
+	_ Handler          = (*chainedHandler)(nil)
+	_ HandlerReadiness = (*chainedHandler)(nil)
+)
+
+// Reconcile implements Handler.
+func (h *chainedHandler) Reconcile(ctx context.Context, obj metav1.Object) *hookv1.HookResponse {
+	return h.reconcile(ctx, obj)
+}
+
+// Ready implements HandlerReadiness.
+func (h *chainedHandler) Ready(ctx context.Context) error {
+	if rh, ok := h.Handler.(HandlerReadiness); ok {
+		return rh.Ready(ctx)
+	}
+	return nil
+}
+
+// Unwrap returns the handler wrapped by middlewares.
+func (h *chainedHandler) Unwrap() Handler {
+	return h.Handler
+}
+
+// chainedFinalizableHandler is a HandlerFinalizable whose operations are
+// wrapped by middlewares.
+type chainedFinalizableHandler struct {
+	*chainedHandler
+	finalize OperationFunc
+}
+
+var _ HandlerFinalizable = (*chainedFinalizableHandler)(nil)
+
+// Finalize implements HandlerFinalizable.
+func (h *chainedFinalizableHandler) Finalize(ctx context.Context, obj metav1.Object) *hookv1.HookResponse {
+	return h.finalize(ctx, obj)
+}
@@ -0,0 +1,167 @@
+// Copyright 2023 TriggerMesh Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package handler
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	commonv1alpha1 "github.com/triggermesh/scoby/pkg/apis/common/v1alpha1"
+	hookv1 "github.com/triggermesh/scoby/pkg/hook/v1"
+)
+
+const tCondition = "Subscribed"
+
+// operationHandler is a finalizable handler that reports a single condition,
+// and whose operations are provided by the test.
+type operationHandler struct {
+	fakeHandler
+	reconcile OperationFunc
+	finalize  OperationFunc
+}
+
+func (h *operationHandler) Reconcile(ctx context.Context, obj metav1.Object) *hookv1.HookResponse {
+	return h.reconcile(ctx, obj)
+}
+
+func (h *operationHandler) Finalize(ctx context.Context, obj metav1.Object) *hookv1.HookResponse {
+	return h.finalize(ctx, obj)
+}
+
+func (h *operationHandler) InitialConditions(hookv1.Operation) commonv1alpha1.Conditions {
+	return commonv1alpha1.Conditions{{Type: tCondition, Status: metav1.ConditionUnknown}}
+}
+
+func newOperationHandler(f OperationFunc) *operationHandler {
+	return &operationHandler{
+		fakeHandler: fakeHandler{
+			gvr:  schema.GroupVersionResource{Group: "test.triggermesh.io", Version: "v1", Resource: "tests"},
+			kind: "Test",
+			obj:  &unstructured.Unstructured{},
+		},
+		reconcile: f,
+		finalize:  f,
+	}
+}
+
+func newTestObject() metav1.Object {
+	obj := &unstructured.Unstructured{}
+	obj.SetNamespace("test-ns")
+	obj.SetName("test-name")
+	return obj
+}
+
+func TestChain(t *testing.T) {
+	var calls []string
+	trace := func(name string) Middleware {
+		return func(info OperationInfo, next OperationFunc) OperationFunc {
+			return func(ctx context.Context, obj metav1.Object) *hookv1.HookResponse {
+				calls = append(calls, name+":"+string(info.Operation))
+				return next(ctx, obj)
+			}
+		}
+	}
+
+	h := newOperationHandler(func(context.Context, metav1.Object) *hookv1.HookResponse {
+		calls = append(calls, "handler")
+		return &hookv1.HookResponse{}
+	})
+
+	ch := Chain(h, trace("outer"), trace("inner"))
+	assert.Equal(t, h.GroupVersionResource(), ch.GroupVersionResource())
+	assert.Equal(t, h.Kind(), ch.Kind())
+
+	ch.Reconcile(context.Background(), newTestObject())
+	assert.Equal(t, []string{"outer:" + string(hookv1.OperationReconcile), "inner:" + string(hookv1.OperationReconcile), "handler"}, calls)
+
+	calls = nil
+	fh, ok := ch.(HandlerFinalizable)
+	require.True(t, ok, "Expected finalizable handlers to remain finalizable")
+	fh.Finalize(context.Background(), newTestObject())
+	assert.Equal(t, []string{"outer:" + string(hookv1.OperationFinalize), "inner:" + string(hookv1.OperationFinalize), "handler"}, calls)
+
+	_, ok = Chain(&fakeHandler{obj: &unstructured.Unstructured{}}, trace("outer")).(HandlerFinalizable)
+	assert.False(t, ok, "Expected non finalizable handlers to remain non finalizable")
+}
+
+func TestMiddlewares(t *testing.T) {
+	testCases := map[string]struct {
+		middleware Middleware
+		operation  OperationFunc
+
+		expectStatus metav1.ConditionStatus
+		expectReason string
+	}{
+		"recover from panic": {
+			middleware: Recover(zap.NewNop().Sugar()),
+			operation: func(context.Context, metav1.Object) *hookv1.HookResponse {
+				panic("boom")
+			},
+			expectStatus: metav1.ConditionFalse,
+			expectReason: ReasonHandlerPanic,
+		},
+		"deadline exceeded": {
+			middleware: Deadline(time.Millisecond),
+			operation: func(ctx context.Context, _ metav1.Object) *hookv1.HookResponse {
+				<-ctx.Done()
+				return nil
+			},
+			expectStatus: metav1.ConditionFalse,
+			expectReason: ReasonDeadlineExceeded,
+		},
+		"deadline not exceeded": {
+			middleware: Deadline(time.Minute),
+			operation: func(context.Context, metav1.Object) *hookv1.HookResponse {
+				return &hookv1.HookResponse{Status: &hookv1.HookStatus{
+					Conditions: commonv1alpha1.Conditions{{Type: tCondition, Status: metav1.ConditionTrue}},
+				}}
+			},
+			expectStatus: metav1.ConditionTrue,
+		},
+		"initialize missing conditions": {
+			middleware: InitConditions(),
+			operation: func(context.Context, metav1.Object) *hookv1.HookResponse {
+				return nil
+			},
+			expectStatus: metav1.ConditionUnknown,
+		},
+		"logger with object identity": {
+			middleware: Logging(zap.NewNop().Sugar()),
+			operation: func(ctx context.Context, _ metav1.Object) *hookv1.HookResponse {
+				status := metav1.ConditionFalse
+				if LoggerFromContext(ctx, nil) != nil {
+					status = metav1.ConditionTrue
+				}
+				return &hookv1.HookResponse{Status: &hookv1.HookStatus{
+					Conditions: commonv1alpha1.Conditions{{Type: tCondition, Status: status}},
+				}}
+			},
+			expectStatus: metav1.ConditionTrue,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			h := Chain(newOperationHandler(tc.operation), tc.middleware)
+
+			res := h.Reconcile(context.Background(), newTestObject())
+			require.NotNil(t, res)
+			require.NotNil(t, res.Status)
+
+			c := res.Status.Conditions.GetByType(tCondition)
+			require.NotNil(t, c, "Expected the handler condition to be reported")
+			assert.Equal(t, tc.expectStatus, c.Status)
+			assert.Equal(t, tc.expectReason, c.Reason)
+		})
+	}
+}
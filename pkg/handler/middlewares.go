@@ -0,0 +1,172 @@
+// Copyright 2023 TriggerMesh Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package handler
+
+import (
+	"context"
+	"errors"
+	"runtime/debug"
+	"time"
+
+	"go.uber.org/zap"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	commonv1alpha1 "github.com/triggermesh/scoby/pkg/apis/common/v1alpha1"
+	hookv1 "github.com/triggermesh/scoby/pkg/hook/v1"
+
+	"github.com/triggermesh/scoby-hook-triggermesh/pkg/metrics"
+)
+
+// Reasons for conditions reported by middlewares.
+const (
+	ReasonHandlerPanic     = "HandlerPanic"
+	ReasonDeadlineExceeded = "DeadlineExceeded"
+)
+
+// Recover turns panics raised while handling an object into a response where
+// every condition reported by the handler is False.
+func Recover(logger *zap.SugaredLogger) Middleware {
+	return func(info OperationInfo, next OperationFunc) OperationFunc {
+		return func(ctx context.Context, obj metav1.Object) (res *hookv1.HookResponse) {
+			defer func() {
+				if r := recover(); r != nil {
+					LoggerFromContext(ctx, logger).Errorw("Handler panicked", zap.Any("panic", r),
+						zap.ByteString("stack", debug.Stack()))
+					res = failedResponse(info, ReasonHandlerPanic, "The hook handler failed unexpectedly")
+				}
+			}()
+
+			return next(ctx, obj)
+		}
+	}
+}
+
+// Deadline limits the time a handler can spend handling an object. Handlers
+// are expected to honor the context deadline. When the handler does not
+// produce a response before the deadline, every condition it reports is set
+// to False.
+func Deadline(d time.Duration) Middleware {
+	return func(info OperationInfo, next OperationFunc) OperationFunc {
+		return func(ctx context.Context, obj metav1.Object) *hookv1.HookResponse {
+			ctx, cancel := context.WithTimeout(ctx, d)
+			defer cancel()
+
+			res := next(ctx, obj)
+			if res == nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				res = failedResponse(info, ReasonDeadlineExceeded, "The hook handler did not complete in "+d.String())
+			}
+
+			return res
+		}
+	}
+}
+
+// InitConditions adds the initial conditions declared by handlers that
+// implement HandlerConditions to responses that do not contain them.
+func InitConditions() Middleware {
+	return func(info OperationInfo, next OperationFunc) OperationFunc {
+		hc, ok := info.Handler.(HandlerConditions)
+		if !ok {
+			return next
+		}
+
+		return func(ctx context.Context, obj metav1.Object) *hookv1.HookResponse {
+			res := next(ctx, obj)
+			if res == nil {
+				res = &hookv1.HookResponse{}
+			}
+			if res.Status == nil {
+				res.Status = &hookv1.HookStatus{}
+			}
+
+			for _, c := range hc.InitialConditions(info.Operation) {
+				if res.Status.Conditions.GetByType(c.Type) == nil {
+					res.Status.Conditions = append(res.Status.Conditions, c)
+				}
+			}
+
+			return res
+		}
+	}
+}
+
+// Logging provides handlers with a logger that contains the identity of the
+// handled object, and logs the outcome of each operation.
+func Logging(logger *zap.SugaredLogger) Middleware {
+	return func(info OperationInfo, next OperationFunc) OperationFunc {
+		return func(ctx context.Context, obj metav1.Object) *hookv1.HookResponse {
+			l := logger.With(
+				zap.String("group", info.GVK.Group),
+				zap.String("version", info.GVK.Version),
+				zap.String("kind", info.GVK.Kind),
+				zap.String("namespace", obj.GetNamespace()),
+				zap.String("name", obj.GetName()),
+				zap.String("operation", string(info.Operation)),
+			)
+
+			l.Debug("Handling object")
+			start := time.Now()
+
+			res := next(WithLogger(ctx, l), obj)
+
+			l = l.With(zap.Duration("duration", time.Since(start)))
+			if res != nil && res.Status != nil {
+				l = l.With(zap.Any("conditions", res.Status.Conditions))
+			}
+			l.Debug("Handled object")
+
+			return res
+		}
+	}
+}
+
+// Metrics records the conditions reported by handlers.
+func Metrics(rec *metrics.Recorder) Middleware {
+	return func(info OperationInfo, next OperationFunc) OperationFunc {
+		return func(ctx context.Context, obj metav1.Object) *hookv1.HookResponse {
+			res := next(ctx, obj)
+			rec.ObserveResponse(info.GVK, info.Operation, res)
+			return res
+		}
+	}
+}
+
+// failedResponse returns a response where every condition reported by the
+// handler is False.
+func failedResponse(info OperationInfo, reason, msg string) *hookv1.HookResponse {
+	var conds commonv1alpha1.Conditions
+	if hc, ok := info.Handler.(HandlerConditions); ok {
+		conds = hc.InitialConditions(info.Operation)
+	}
+
+	for i := range conds {
+		conds[i].Status = metav1.ConditionFalse
+		conds[i].Reason = reason
+		conds[i].Message = msg
+	}
+
+	return &hookv1.HookResponse{
+		Status: &hookv1.HookStatus{
+			Conditions: conds,
+		},
+	}
+}
+
+// loggerKey is the context key for the logger.
+type loggerKey struct{}
+
+// WithLogger returns a copy of the parent context that contains the logger.
+func WithLogger(ctx context.Context, logger *zap.SugaredLogger) context.Context {
+	return context.WithValue(ctx, loggerKey{}, logger)
+}
+
+// LoggerFromContext returns the logger contained in the context, or the
+// provided default logger if there is none.
+func LoggerFromContext(ctx context.Context, def *zap.SugaredLogger) *zap.SugaredLogger {
+	if l, ok := ctx.Value(loggerKey{}).(*zap.SugaredLogger); ok {
+		return l
+	}
+	return def
+}
@@ -4,12 +4,17 @@
 package handler
 
 import (
+	"sort"
+
 	"k8s.io/apimachinery/pkg/runtime/schema"
 )
 
 type Registry map[schema.GroupVersionKind]Handler
 
-func NewRegistry(h []Handler) Registry {
+// NewRegistry creates a registry for the handlers. Operations of every handler
+// are wrapped by the provided middlewares, the first middleware being the
+// outermost one.
+func NewRegistry(h []Handler, mws ...Middleware) Registry {
 	r := make(map[schema.GroupVersionKind]Handler, len(h))
 
 	for i := range h {
@@ -18,8 +23,22 @@ func NewRegistry(h []Handler) Registry {
 			Group:   gvr.Group,
 			Version: gvr.Version,
 			Kind:    h[i].Kind(),
-		}] = h[i]
+		}] = Chain(h[i], mws...)
 	}
 
 	return r
 }
+
+// GroupVersionKinds returns the sorted list of GVKs served by the registry.
+func (r Registry) GroupVersionKinds() []schema.GroupVersionKind {
+	gvks := make([]schema.GroupVersionKind, 0, len(r))
+	for gvk := range r {
+		gvks = append(gvks, gvk)
+	}
+
+	sort.Slice(gvks, func(i, j int) bool {
+		return gvks[i].String() < gvks[j].String()
+	})
+
+	return gvks
+}
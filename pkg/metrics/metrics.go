@@ -0,0 +1,179 @@
+// Copyright 2023 TriggerMesh Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package metrics records and exposes hook metrics.
+package metrics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.uber.org/zap"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	hookv1 "github.com/triggermesh/scoby/pkg/hook/v1"
+)
+
+const (
+	namespace = "scoby_hook"
+
+	labelGroup     = "group"
+	labelVersion   = "version"
+	labelKind      = "kind"
+	labelOperation = "operation"
+	labelCode      = "code"
+	labelCondition = "condition"
+	labelStatus    = "status"
+	labelReason    = "reason"
+)
+
+// Recorder records metrics for hook requests and handler outcomes.
+type Recorder struct {
+	requests   *prometheus.CounterVec
+	latency    *prometheus.HistogramVec
+	conditions *prometheus.CounterVec
+}
+
+// NewRecorder creates a Recorder and registers its metrics.
+func NewRecorder(reg prometheus.Registerer) (*Recorder, error) {
+	r := &Recorder{
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "requests_total",
+			Help:      "Number of hook requests served, by object kind, operation and HTTP response code.",
+		}, []string{labelGroup, labelVersion, labelKind, labelOperation, labelCode}),
+
+		latency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "request_duration_seconds",
+			Help:      "Latency of hook requests, by object kind and operation.",
+			Buckets:   []float64{.01, .05, .1, .25, .5, 1, 2.5, 5, 10, 30},
+		}, []string{labelGroup, labelVersion, labelKind, labelOperation}),
+
+		conditions: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "condition_results_total",
+			Help:      "Number of conditions reported by handlers, by object kind, operation, condition type, status and reason.",
+		}, []string{labelGroup, labelVersion, labelKind, labelOperation, labelCondition, labelStatus, labelReason}),
+	}
+
+	for _, c := range []prometheus.Collector{r.requests, r.latency, r.conditions} {
+		if err := reg.Register(c); err != nil {
+			return nil, fmt.Errorf("registering metrics: %w", err)
+		}
+	}
+
+	return r, nil
+}
+
+// ObserveRequest records a served hook request.
+func (r *Recorder) ObserveRequest(gvk schema.GroupVersionKind, op hookv1.Operation, code int, d time.Duration) {
+	r.requests.WithLabelValues(gvk.Group, gvk.Version, gvk.Kind, string(op), strconv.Itoa(code)).Inc()
+	r.latency.WithLabelValues(gvk.Group, gvk.Version, gvk.Kind, string(op)).Observe(d.Seconds())
+}
+
+// ObserveResponse records the conditions reported in a handler's response.
+func (r *Recorder) ObserveResponse(gvk schema.GroupVersionKind, op hookv1.Operation, res *hookv1.HookResponse) {
+	if res == nil || res.Status == nil {
+		return
+	}
+
+	for _, c := range res.Status.Conditions {
+		r.conditions.WithLabelValues(gvk.Group, gvk.Version, gvk.Kind, string(op),
+			c.Type, string(c.Status), c.Reason).Inc()
+	}
+}
+
+// Serve exposes the metrics gathered by the gatherer at the /metrics path of
+// the informed port, until the context is done.
+func Serve(ctx context.Context, port int, g prometheus.Gatherer, logger *zap.SugaredLogger) error {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.HandlerFor(g, promhttp.HandlerOpts{}))
+
+	srv := &http.Server{
+		Addr:              ":" + strconv.Itoa(port),
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	errCh := make(chan error)
+
+	go func() {
+		logger.Infow("Starting Prometheus metrics server", zap.Int("port", port))
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			errCh <- err
+		}
+		close(errCh)
+	}()
+
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+		defer cancel()
+
+		return srv.Shutdown(ctx)
+	}
+}
+
+// Endpoint serves the metrics gathered by a gatherer at a port that can be
+// changed at runtime.
+type Endpoint struct {
+	g      prometheus.Gatherer
+	logger *zap.SugaredLogger
+
+	m      sync.Mutex
+	port   int
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// NewEndpoint creates an Endpoint that is not serving metrics until a port is
+// set.
+func NewEndpoint(g prometheus.Gatherer, logger *zap.SugaredLogger) *Endpoint {
+	return &Endpoint{
+		g:      g,
+		logger: logger,
+	}
+}
+
+// SetPort serves metrics at the informed port, stopping the server at the
+// previous port if any. Setting port 0 stops serving metrics.
+func (e *Endpoint) SetPort(ctx context.Context, port int) {
+	e.m.Lock()
+	defer e.m.Unlock()
+
+	if port == e.port {
+		return
+	}
+
+	if e.cancel != nil {
+		e.cancel()
+		<-e.done
+		e.cancel, e.done = nil, nil
+	}
+
+	e.port = port
+	if port == 0 {
+		return
+	}
+
+	ctx, e.cancel = context.WithCancel(ctx)
+	e.done = make(chan struct{})
+
+	go func(done chan struct{}) {
+		defer close(done)
+		if err := Serve(ctx, port, e.g, e.logger); err != nil {
+			e.logger.Errorw("Prometheus metrics server failed", zap.Error(err))
+		}
+	}(e.done)
+}
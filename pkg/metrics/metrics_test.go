@@ -0,0 +1,59 @@
+// Copyright 2023 TriggerMesh Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package metrics
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	commonv1alpha1 "github.com/triggermesh/scoby/pkg/apis/common/v1alpha1"
+	hookv1 "github.com/triggermesh/scoby/pkg/hook/v1"
+)
+
+var tGVK = schema.GroupVersionKind{Group: "sources.triggermesh.io", Version: "v1alpha1", Kind: "AWSS3Source"}
+
+func TestRecorder(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	rec, err := NewRecorder(reg)
+	require.NoError(t, err)
+
+	rec.ObserveRequest(tGVK, hookv1.OperationReconcile, 200, 150*time.Millisecond)
+	rec.ObserveRequest(tGVK, hookv1.OperationReconcile, 200, 50*time.Millisecond)
+	rec.ObserveRequest(tGVK, hookv1.OperationFinalize, 400, 10*time.Millisecond)
+
+	rec.ObserveResponse(tGVK, hookv1.OperationReconcile, &hookv1.HookResponse{
+		Status: &hookv1.HookStatus{
+			Conditions: commonv1alpha1.Conditions{{
+				Type:   "Subscribed",
+				Status: metav1.ConditionFalse,
+				Reason: "ReconcileQueue",
+			}},
+		},
+	})
+	// responses without status are ignored
+	rec.ObserveResponse(tGVK, hookv1.OperationReconcile, nil)
+
+	expect := `
+# HELP scoby_hook_requests_total Number of hook requests served, by object kind, operation and HTTP response code.
+# TYPE scoby_hook_requests_total counter
+scoby_hook_requests_total{code="200",group="sources.triggermesh.io",kind="AWSS3Source",operation="reconcile",version="v1alpha1"} 2
+scoby_hook_requests_total{code="400",group="sources.triggermesh.io",kind="AWSS3Source",operation="finalize",version="v1alpha1"} 1
+# HELP scoby_hook_condition_results_total Number of conditions reported by handlers, by object kind, operation, condition type, status and reason.
+# TYPE scoby_hook_condition_results_total counter
+scoby_hook_condition_results_total{condition="Subscribed",group="sources.triggermesh.io",kind="AWSS3Source",operation="reconcile",reason="ReconcileQueue",status="False",version="v1alpha1"} 1
+`
+	assert.NoError(t, testutil.GatherAndCompare(reg, strings.NewReader(expect),
+		"scoby_hook_requests_total", "scoby_hook_condition_results_total"))
+
+	assert.Equal(t, 2, testutil.CollectAndCount(rec.latency))
+}
@@ -0,0 +1,63 @@
+/*
+Copyright 2023 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package event contains functions for recording Kubernetes events about the
+// object a hook request is being handled for.
+package event
+
+import (
+	"context"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+)
+
+// recorderKey is the context key for the event recorder.
+type recorderKey struct{}
+
+// recorder records events about a single object.
+type recorder struct {
+	record.EventRecorder
+	obj runtime.Object
+}
+
+// WithRecorder returns a copy of the parent context in which events are
+// recorded about the given object using the provided recorder.
+func WithRecorder(ctx context.Context, rec record.EventRecorder, obj runtime.Object) context.Context {
+	return context.WithValue(ctx, recorderKey{}, &recorder{EventRecorder: rec, obj: obj})
+}
+
+// Normal records a normal event about the object stored in the context.
+// The event is discarded when the context does not contain a recorder.
+func Normal(ctx context.Context, reason, msgFmt string, args ...interface{}) {
+	recordEvent(ctx, corev1.EventTypeNormal, reason, msgFmt, args...)
+}
+
+// Warn records a warning event about the object stored in the context.
+// The event is discarded when the context does not contain a recorder.
+func Warn(ctx context.Context, reason, msgFmt string, args ...interface{}) {
+	recordEvent(ctx, corev1.EventTypeWarning, reason, msgFmt, args...)
+}
+
+func recordEvent(ctx context.Context, eventType, reason, msgFmt string, args ...interface{}) {
+	r, ok := ctx.Value(recorderKey{}).(*recorder)
+	if !ok {
+		return
+	}
+
+	r.Eventf(r.obj, eventType, reason, msgFmt, args...)
+}
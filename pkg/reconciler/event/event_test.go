@@ -0,0 +1,69 @@
+/*
+Copyright 2023 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package event
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/tools/record"
+)
+
+func TestRecordEvent(t *testing.T) {
+	obj := &unstructured.Unstructured{}
+
+	testCases := map[string]struct {
+		record   func(ctx context.Context)
+		expected []string
+	}{
+		"normal": {
+			record: func(ctx context.Context) {
+				Normal(ctx, "QueueCreated", "Created SQS queue %q", "my-queue")
+			},
+			expected: []string{`Normal QueueCreated Created SQS queue "my-queue"`},
+		},
+		"warning": {
+			record: func(ctx context.Context) {
+				Warn(ctx, "FailedUnsubscribe", "Authorization error")
+			},
+			expected: []string{"Warning FailedUnsubscribe Authorization error"},
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			rec := record.NewFakeRecorder(10)
+			tc.record(WithRecorder(context.Background(), rec, obj))
+			close(rec.Events)
+
+			var events []string
+			for e := range rec.Events {
+				events = append(events, e)
+			}
+			assert.Equal(t, tc.expected, events)
+		})
+	}
+
+	t.Run("no recorder", func(t *testing.T) {
+		assert.NotPanics(t, func() {
+			Warn(context.Background(), "FailedUnsubscribe", "Authorization error")
+		})
+	})
+}
@@ -0,0 +1,115 @@
+// Copyright 2023 TriggerMesh Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"go.uber.org/zap"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	authenticationclientv1 "k8s.io/client-go/kubernetes/typed/authentication/v1"
+)
+
+// serviceAccountUsernamePrefix is the prefix of the username Kubernetes
+// assigns to ServiceAccount identities.
+const serviceAccountUsernamePrefix = "system:serviceaccount:"
+
+// TokenReviewAuthenticator authenticates hook callers by validating their
+// bearer token with the Kubernetes TokenReview API, then authorizes the
+// resulting identity against a list of allowed ServiceAccounts.
+type TokenReviewAuthenticator struct {
+	cli       authenticationclientv1.TokenReviewInterface
+	audiences []string
+
+	// usernames of the allowed ServiceAccounts.
+	allowed map[string]struct{}
+
+	logger *zap.SugaredLogger
+}
+
+// NewTokenReviewAuthenticator returns an authenticator that allows requests
+// from the informed ServiceAccounts, each of them expressed as
+// <namespace>/<name>. When audiences are informed, tokens must be issued for
+// at least one of them.
+func NewTokenReviewAuthenticator(cli authenticationclientv1.TokenReviewInterface, serviceAccounts, audiences []string, logger *zap.SugaredLogger) (*TokenReviewAuthenticator, error) {
+	if len(serviceAccounts) == 0 {
+		return nil, errors.New("at least one allowed ServiceAccount must be informed")
+	}
+
+	allowed := make(map[string]struct{}, len(serviceAccounts))
+	for _, sa := range serviceAccounts {
+		ns, name, ok := strings.Cut(sa, "/")
+		if !ok || ns == "" || name == "" {
+			return nil, fmt.Errorf("ServiceAccount %q must be expressed as <namespace>/<name>", sa)
+		}
+		allowed[serviceAccountUsernamePrefix+ns+":"+name] = struct{}{}
+	}
+
+	return &TokenReviewAuthenticator{
+		cli:       cli,
+		audiences: audiences,
+		allowed:   allowed,
+		logger:    logger,
+	}, nil
+}
+
+// Handler returns an HTTP handler that only lets authorized requests through
+// to the next handler.
+func (a *TokenReviewAuthenticator) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := bearerToken(r)
+		if !ok {
+			msg := "request does not contain a bearer token"
+			a.logger.Error("Error authenticating request", zap.Error(errors.New(msg)))
+			writeHookError(w, http.StatusUnauthorized, msg)
+			return
+		}
+
+		tr, err := a.cli.Create(r.Context(), &authenticationv1.TokenReview{
+			Spec: authenticationv1.TokenReviewSpec{
+				Token:     token,
+				Audiences: a.audiences,
+			},
+		}, metav1.CreateOptions{})
+		if err != nil {
+			msg := "cannot review request token: " + err.Error()
+			a.logger.Error("Error authenticating request", zap.Error(errors.New(msg)))
+			writeHookError(w, http.StatusInternalServerError, msg)
+			return
+		}
+
+		if !tr.Status.Authenticated {
+			msg := "request token is not valid"
+			a.logger.Error("Error authenticating request", zap.Error(errors.New(msg)),
+				zap.String("reason", tr.Status.Error))
+			writeHookError(w, http.StatusUnauthorized, msg)
+			return
+		}
+
+		if _, ok := a.allowed[tr.Status.User.Username]; !ok {
+			msg := fmt.Sprintf("user %q is not allowed to use the hook", tr.Status.User.Username)
+			a.logger.Error("Error authorizing request", zap.Error(errors.New(msg)))
+			writeHookError(w, http.StatusForbidden, msg)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// bearerToken returns the bearer token at the request's Authorization header.
+func bearerToken(r *http.Request) (string, bool) {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+
+	token = strings.TrimSpace(token)
+	return token, token != ""
+}
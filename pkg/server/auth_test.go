@@ -0,0 +1,94 @@
+// Copyright 2023 TriggerMesh Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func TestTokenReviewAuthenticator(t *testing.T) {
+	// tokens known to the fake TokenReview API, and the user they belong to.
+	users := map[string]string{
+		"scoby-token": "system:serviceaccount:triggermesh:scoby-controller",
+		"other-token": "system:serviceaccount:default:other",
+	}
+
+	cli := fake.NewSimpleClientset()
+	cli.PrependReactor("create", "tokenreviews", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		tr := action.(k8stesting.CreateAction).GetObject().(*authenticationv1.TokenReview)
+		if user, ok := users[tr.Spec.Token]; ok {
+			tr.Status.Authenticated = true
+			tr.Status.User.Username = user
+		}
+		return true, tr, nil
+	})
+
+	auth, err := NewTokenReviewAuthenticator(cli.AuthenticationV1().TokenReviews(),
+		[]string{"triggermesh/scoby-controller"}, nil, zap.NewNop().Sugar())
+	require.NoError(t, err)
+
+	h := auth.Handler(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	testCases := map[string]struct {
+		authorization string
+		expectCode    int
+	}{
+		"allowed ServiceAccount": {
+			authorization: "Bearer scoby-token",
+			expectCode:    http.StatusOK,
+		},
+		"not allowed ServiceAccount": {
+			authorization: "Bearer other-token",
+			expectCode:    http.StatusForbidden,
+		},
+		"invalid token": {
+			authorization: "Bearer unknown-token",
+			expectCode:    http.StatusUnauthorized,
+		},
+		"missing token": {
+			expectCode: http.StatusUnauthorized,
+		},
+		"not a bearer token": {
+			authorization: "Basic c2NvYnk6c2NvYnk=",
+			expectCode:    http.StatusUnauthorized,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/v1", nil)
+			if tc.authorization != "" {
+				req.Header.Set("Authorization", tc.authorization)
+			}
+
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+
+			assert.Equal(t, tc.expectCode, rec.Code, rec.Body.String())
+		})
+	}
+}
+
+func TestNewTokenReviewAuthenticatorValidation(t *testing.T) {
+	cli := fake.NewSimpleClientset().AuthenticationV1().TokenReviews()
+
+	_, err := NewTokenReviewAuthenticator(cli, nil, nil, zap.NewNop().Sugar())
+	assert.Error(t, err, "No ServiceAccounts")
+
+	_, err = NewTokenReviewAuthenticator(cli, []string{"scoby-controller"}, nil, zap.NewNop().Sugar())
+	assert.Error(t, err, "ServiceAccount without namespace")
+}
@@ -0,0 +1,29 @@
+// Copyright 2023 TriggerMesh Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// HookError is the structured reply sent back to Scoby when a hook request
+// cannot be served.
+type HookError struct {
+	// Code is the HTTP status code for the reply.
+	Code int `json:"code"`
+	// Message describing the error.
+	Message string `json:"message"`
+}
+
+// writeHookError replies to the request with a JSON encoded HookError.
+func writeHookError(w http.ResponseWriter, code int, msg string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(&HookError{
+		Code:    code,
+		Message: msg,
+	})
+}
@@ -0,0 +1,125 @@
+// Copyright 2023 TriggerMesh Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"k8s.io/client-go/discovery"
+
+	"github.com/triggermesh/scoby-hook-triggermesh/pkg/handler"
+)
+
+const (
+	healthzPath  = "/healthz"
+	readyzPath   = "/readyz"
+	handlersPath = "/handlers"
+
+	// readinessTimeout bounds the time spent running readiness checks.
+	readinessTimeout = 5 * time.Second
+)
+
+// ReadinessCheck reports an error when a dependency of the hook is not ready.
+type ReadinessCheck func(ctx context.Context) error
+
+// namedReadinessCheck is a readiness check identified by name.
+type namedReadinessCheck struct {
+	name  string
+	check ReadinessCheck
+}
+
+// KubernetesReadinessCheck returns a readiness check that verifies
+// connectivity with the Kubernetes API server. The version of the server is
+// requested directly through the REST client of the discovery client, because
+// ServerVersion() doesn't accept a context and would not honour the deadline
+// of the check.
+func KubernetesReadinessCheck(cli discovery.DiscoveryInterface) ReadinessCheck {
+	return func(ctx context.Context) error {
+		if err := cli.RESTClient().Get().AbsPath("/version").Do(ctx).Error(); err != nil {
+			return fmt.Errorf("connecting to the Kubernetes API: %w", err)
+		}
+		return nil
+	}
+}
+
+// HandlerInfo describes a handler served by the hook.
+type HandlerInfo struct {
+	Group       string `json:"group"`
+	Version     string `json:"version"`
+	Kind        string `json:"kind"`
+	Resource    string `json:"resource"`
+	Finalizable bool   `json:"finalizable"`
+}
+
+// serveHealthz reports the hook process as alive.
+func (s *Server) serveHealthz(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	_, _ = w.Write([]byte("ok"))
+}
+
+// serveReadyz reports the hook as ready when it is listening for requests,
+// all registered handlers are initialized and all readiness checks pass.
+func (s *Server) serveReadyz(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), readinessTimeout)
+	defer cancel()
+
+	buf := &bytes.Buffer{}
+	ready := true
+
+	report := func(name string, err error) {
+		if err != nil {
+			ready = false
+			fmt.Fprintf(buf, "[-]%s failed: %s\n", name, err)
+			return
+		}
+		fmt.Fprintf(buf, "[+]%s ok\n", name)
+	}
+
+	if !s.listening.Load() {
+		report("server", fmt.Errorf("not listening for requests"))
+	} else {
+		report("server", nil)
+	}
+
+	for _, c := range s.readinessChecks {
+		report(c.name, c.check(ctx))
+	}
+
+	for _, gvk := range s.reg.GroupVersionKinds() {
+		if rh, ok := s.reg[gvk].(handler.HandlerReadiness); ok {
+			report("handler "+gvk.String(), rh.Ready(ctx))
+		}
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	if !ready {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	_, _ = w.Write(buf.Bytes())
+}
+
+// serveHandlers lists the handlers registered at the hook.
+func (s *Server) serveHandlers(w http.ResponseWriter, _ *http.Request) {
+	infos := make([]HandlerInfo, 0, len(s.reg))
+	for _, gvk := range s.reg.GroupVersionKinds() {
+		h := s.reg[gvk]
+		_, finalizable := h.(handler.HandlerFinalizable)
+
+		infos = append(infos, HandlerInfo{
+			Group:       gvk.Group,
+			Version:     gvk.Version,
+			Kind:        gvk.Kind,
+			Resource:    h.GroupVersionResource().Resource,
+			Finalizable: finalizable,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(infos)
+}
@@ -0,0 +1,193 @@
+// Copyright 2023 TriggerMesh Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/rest"
+
+	hookv1 "github.com/triggermesh/scoby/pkg/hook/v1"
+)
+
+type finalizableTestHandler struct {
+	testHandler
+	readyErr error
+}
+
+func (h *finalizableTestHandler) Finalize(ctx context.Context, obj metav1.Object) *hookv1.HookResponse {
+	return h.Reconcile(ctx, obj)
+}
+
+func (h *finalizableTestHandler) Ready(context.Context) error {
+	return h.readyErr
+}
+
+func TestServeHealthz(t *testing.T) {
+	s := newTestServer(t, &testHandler{}, "/")
+
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, healthzPath, nil))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestServeReadyz(t *testing.T) {
+	testCases := map[string]struct {
+		listening  bool
+		checkErr   error
+		handlerErr error
+		expectCode int
+	}{
+		"ready": {
+			listening:  true,
+			expectCode: http.StatusOK,
+		},
+		"not listening": {
+			listening:  false,
+			expectCode: http.StatusServiceUnavailable,
+		},
+		"readiness check fails": {
+			listening:  true,
+			checkErr:   errors.New("no connectivity"),
+			expectCode: http.StatusServiceUnavailable,
+		},
+		"handler not initialized": {
+			listening:  true,
+			handlerErr: errors.New("not initialized"),
+			expectCode: http.StatusServiceUnavailable,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			s := newTestServer(t, &finalizableTestHandler{readyErr: tc.handlerErr}, "/",
+				WithReadinessCheck("test", func(context.Context) error { return tc.checkErr }))
+			s.listening.Store(tc.listening)
+
+			rec := httptest.NewRecorder()
+			s.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, readyzPath, nil))
+
+			assert.Equal(t, tc.expectCode, rec.Code, rec.Body.String())
+		})
+	}
+}
+
+func TestServeHandlers(t *testing.T) {
+	s := newTestServer(t, &finalizableTestHandler{}, "/")
+
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, handlersPath, nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var infos []HandlerInfo
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&infos))
+
+	assert.Equal(t, []HandlerInfo{{
+		Group:       tGVR.Group,
+		Version:     tGVR.Version,
+		Kind:        "Test",
+		Resource:    tGVR.Resource,
+		Finalizable: true,
+	}}, infos)
+}
+
+func TestKubernetesReadinessCheckDeadline(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	defer srv.Close()
+
+	check := KubernetesReadinessCheck(discovery.NewDiscoveryClientForConfigOrDie(&rest.Config{Host: srv.URL}))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	assert.Error(t, check(ctx))
+	assert.Less(t, time.Since(start), 5*time.Second, "The check should not outlive its context")
+}
+
+func TestStartHealthAddress(t *testing.T) {
+	ca := newTestCert(t, "scoby-ca", true, nil)
+	serverCert := newTestCert(t, "hook", false, ca)
+
+	dir := t.TempDir()
+	certFile, keyFile, caFile := filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key"), filepath.Join(dir, "ca.crt")
+	writeFile(t, certFile, serverCert.certPEM)
+	writeFile(t, keyFile, serverCert.keyPEM)
+	writeFile(t, caFile, ca.certPEM)
+
+	certs, err := NewCertificateReloader(certFile, keyFile, caFile, zap.NewNop().Sugar())
+	require.NoError(t, err)
+
+	address, healthAddress := freeAddress(t), freeAddress(t)
+
+	s := newTestServer(t, &testHandler{}, "/", WithTLS(certs), WithHealthAddress(healthAddress))
+	s.address = address
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	errCh := make(chan error)
+	go func() { errCh <- s.Start(ctx) }()
+
+	get := func(url string) (int, error) {
+		res, err := http.Get(url)
+		if err != nil {
+			return 0, err
+		}
+		return res.StatusCode, res.Body.Close()
+	}
+
+	assert.Eventually(t, func() bool {
+		code, err := get("http://" + healthAddress + readyzPath)
+		return err == nil && code == http.StatusOK
+	}, 5*time.Second, 50*time.Millisecond, "Readiness probe over plain HTTP")
+
+	code, err := get("http://" + healthAddress + healthzPath)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, code, "Liveness probe over plain HTTP")
+
+	code, err = get("http://" + healthAddress + handlersPath)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusNotFound, code, "Only health probes are served at the health address")
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+	cli := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: roots}}}
+	_, err = cli.Get("https://" + address + healthzPath)
+	assert.Error(t, err, "Hook address requires a client certificate")
+
+	cancel()
+	assert.NoError(t, <-errCh)
+}
+
+// freeAddress returns a local address with a port that is free at the time of
+// the call.
+func freeAddress(t *testing.T) string {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer ln.Close()
+
+	return ln.Addr().String()
+}
@@ -0,0 +1,91 @@
+// Copyright 2023 TriggerMesh Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	hookv1 "github.com/triggermesh/scoby/pkg/hook/v1"
+
+	"github.com/triggermesh/scoby-hook-triggermesh/pkg/sources/errclass"
+)
+
+// Delays after which an operation which failed with an error of the given
+// class should be retried. Errors of classes which aren't listed are either
+// terminal or left to the default backoff of Scoby.
+//
+// Missing permissions are retried, since they may be granted outside of the
+// cluster (e.g. in IAM) without any update to the object.
+var requeueAfter = map[errclass.Class]time.Duration{
+	errclass.Transient: 10 * time.Second,
+	errclass.Throttled: 30 * time.Second,
+	errclass.NotFound:  time.Minute,
+	errclass.Invalid:   5 * time.Minute,
+	errclass.Denied:    10 * time.Minute,
+}
+
+// HookResponse is the reply sent back to Scoby when a hook request was
+// served. It extends hookv1.HookResponse with a hint about retrying the
+// operation.
+type HookResponse struct {
+	*hookv1.HookResponse
+	// Retry is set when the handler reported errors that could be
+	// classified.
+	Retry *RetryHint `json:"retry,omitempty"`
+}
+
+// RetryHint hints at whether and when a failed operation should be retried.
+type RetryHint struct {
+	// Reason is the class of the error that caused the operation to fail.
+	Reason errclass.Class `json:"reason"`
+	// Terminal indicates that the operation won't succeed unless the
+	// object or its dependencies (e.g. credentials) are updated.
+	Terminal bool `json:"terminal,omitempty"`
+	// RequeueAfter is the delay after which the operation should be
+	// retried.
+	RequeueAfter *metav1.Duration `json:"requeueAfter,omitempty"`
+}
+
+// retryHintFor returns a hint about retrying an operation which failed with
+// the given errors. Terminal errors, which are caused by invalid or expired
+// credentials, take precedence, otherwise the longest delay wins. A nil hint is returned if none of the errors can be classified.
+func retryHintFor(errs []error) *RetryHint {
+	var hint *RetryHint
+
+	for _, err := range errs {
+		class := errclass.Classify(err)
+
+		if class == errclass.Unauthenticated {
+			return &RetryHint{Reason: class, Terminal: true}
+		}
+
+		after, ok := requeueAfter[class]
+		if !ok {
+			continue
+		}
+		if hint == nil || after > hint.RequeueAfter.Duration {
+			hint = &RetryHint{Reason: class, RequeueAfter: &metav1.Duration{Duration: after}}
+		}
+	}
+
+	return hint
+}
+
+// writeHookResponse replies to the request with a JSON encoded HookResponse.
+// Delays are also conveyed through the standard Retry-After header.
+func writeHookResponse(w http.ResponseWriter, res *hookv1.HookResponse, hint *RetryHint) {
+	w.Header().Set("Content-Type", "application/json")
+	if hint != nil && hint.RequeueAfter != nil {
+		w.Header().Set("Retry-After", strconv.Itoa(int(hint.RequeueAfter.Seconds())))
+	}
+	_ = json.NewEncoder(w).Encode(&HookResponse{
+		HookResponse: res,
+		Retry:        hint,
+	})
+}
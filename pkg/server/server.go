@@ -5,19 +5,28 @@ package server
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
 	"fmt"
+	"net"
 	"net/http"
+	"path"
+	"sync/atomic"
 	"time"
 
+	"go.opencensus.io/trace"
 	"go.uber.org/zap"
 
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	kdclient "k8s.io/client-go/dynamic"
+	"k8s.io/client-go/tools/record"
 
 	"github.com/triggermesh/scoby-hook-triggermesh/pkg/handler"
+	"github.com/triggermesh/scoby-hook-triggermesh/pkg/metrics"
+	"github.com/triggermesh/scoby-hook-triggermesh/pkg/reconciler/event"
+	"github.com/triggermesh/scoby-hook-triggermesh/pkg/tracing"
 	hookv1 "github.com/triggermesh/scoby/pkg/hook/v1"
 )
 
@@ -25,43 +34,211 @@ type Server struct {
 	path    string
 	address string
 	reg     handler.Registry
+	dec     *handler.Decoder
+
+	// versions of the hook API served, indexed by the
+	// path element they are served at.
+	versions map[string]RequestDecoder
+	mux      *http.ServeMux
+
+	// healthAddress is set when health probes are also served over
+	// plain HTTP at a dedicated address, so that they don't require
+	// the TLS client certificates or the tokens expected from hook
+	// callers.
+	healthAddress string
+	healthMux     *http.ServeMux
+
+	// certs is set when the hook is served over TLS.
+	certs *CertificateReloader
+
+	// auth is set when hook callers must be authenticated.
+	auth *TokenReviewAuthenticator
+
+	// metrics is set when hook metrics are recorded.
+	metrics *metrics.Recorder
+
+	// recorder is set when events are recorded for handled objects.
+	recorder record.EventRecorder
+
+	readinessChecks []namedReadinessCheck
+	// listening is set once the server is accepting connections.
+	listening atomic.Bool
 
 	dyn kdclient.Interface
 
 	logger *zap.SugaredLogger `kong:"-"`
 }
 
-func New(path, address string, reg handler.Registry, dyn kdclient.Interface, logger *zap.SugaredLogger) *Server {
-	return &Server{
-		path:    path,
-		address: address,
-		reg:     reg,
-		dyn:     dyn,
+// Option is a functional option for the Server.
+type Option func(*Server)
+
+// WithAPIVersion serves a version of the hook API using the provided request
+// decoder. The version is served at the configured path followed by the
+// version name.
+func WithAPIVersion(version string, dec RequestDecoder) Option {
+	return func(s *Server) {
+		s.versions[version] = dec
+	}
+}
+
+// WithTLS serves the hook over TLS using the certificates managed by
+// the reloader.
+func WithTLS(certs *CertificateReloader) Option {
+	return func(s *Server) {
+		s.certs = certs
+	}
+}
+
+// WithHealthAddress serves the health probe endpoints over plain HTTP at the
+// given address, in addition to the address of the hook.
+func WithHealthAddress(address string) Option {
+	return func(s *Server) {
+		s.healthAddress = address
+	}
+}
+
+// WithAuthenticator requires hook requests to be authorized by the
+// TokenReview authenticator before being dispatched to handlers.
+func WithAuthenticator(auth *TokenReviewAuthenticator) Option {
+	return func(s *Server) {
+		s.auth = auth
+	}
+}
+
+// WithReadinessCheck gates the hook readiness on the provided check.
+func WithReadinessCheck(name string, check ReadinessCheck) Option {
+	return func(s *Server) {
+		s.readinessChecks = append(s.readinessChecks, namedReadinessCheck{name: name, check: check})
+	}
+}
+
+// WithMetrics records metrics for every hook request.
+func WithMetrics(rec *metrics.Recorder) Option {
+	return func(s *Server) {
+		s.metrics = rec
+	}
+}
+
+// WithEventRecorder makes the event recorder available to handlers for
+// recording events about the objects they handle.
+func WithEventRecorder(rec record.EventRecorder) Option {
+	return func(s *Server) {
+		s.recorder = rec
+	}
+}
+
+// New creates a hook server. When no API version is configured through options
+// the default API version is served.
+func New(path, address string, reg handler.Registry, dyn kdclient.Interface, logger *zap.SugaredLogger, opts ...Option) *Server {
+	s := &Server{
+		path:     path,
+		address:  address,
+		reg:      reg,
+		dec:      handler.NewDecoder(reg),
+		versions: make(map[string]RequestDecoder),
+		dyn:      dyn,
 
 		logger: logger,
 	}
 
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	if len(s.versions) == 0 {
+		s.versions[DefaultAPIVersion] = requestDecoders[DefaultAPIVersion]
+	}
+
+	s.mux = http.NewServeMux()
+	for v, dec := range s.versions {
+		h := s.hookHandler(dec)
+		if s.auth != nil {
+			h = s.auth.Handler(h)
+		}
+		s.mux.Handle(s.versionPath(v), tracing.Handler(h))
+	}
+
+	s.mux.HandleFunc(healthzPath, s.serveHealthz)
+	s.mux.HandleFunc(readyzPath, s.serveReadyz)
+	s.mux.HandleFunc(handlersPath, s.serveHandlers)
+
+	s.healthMux = http.NewServeMux()
+	s.healthMux.HandleFunc(healthzPath, s.serveHealthz)
+	s.healthMux.HandleFunc(readyzPath, s.serveReadyz)
+
+	return s
 }
 
-func (s *Server) Start(ctx context.Context) error {
-	mux := http.NewServeMux()
-	mux.Handle("/v1", s)
+// versionPath returns the HTTP path where a version of the hook API is served.
+func (s *Server) versionPath(version string) string {
+	return path.Join("/", s.path, version)
+}
 
+func (s *Server) Start(ctx context.Context) error {
 	srv := http.Server{
 		Addr:    s.address,
-		Handler: mux,
+		Handler: s,
+	}
+
+	if s.certs != nil {
+		srv.TLSConfig = s.certs.TLSConfig()
+		go s.certs.Watch(ctx)
 	}
 
-	errCh := make(chan error)
+	ln, err := net.Listen("tcp", s.address)
+	if err != nil {
+		return fmt.Errorf("listening at %q: %w", s.address, err)
+	}
+
+	var healthSrv *http.Server
+	var healthLn net.Listener
+	if s.healthAddress != "" {
+		healthSrv = &http.Server{
+			Addr:    s.healthAddress,
+			Handler: s.healthMux,
+		}
+
+		if healthLn, err = net.Listen("tcp", s.healthAddress); err != nil {
+			_ = ln.Close()
+			return fmt.Errorf("listening at %q: %w", s.healthAddress, err)
+		}
+	}
+
+	errCh := make(chan error, 2)
 
 	go func() {
-		s.logger.Infow("Starting TriggerMesh Scoby webhook", zap.String("address", s.address))
-		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		paths := make([]string, 0, len(s.versions))
+		for v := range s.versions {
+			paths = append(paths, s.versionPath(v))
+		}
+		s.logger.Infow("Starting TriggerMesh Scoby webhook", zap.String("address", s.address),
+			zap.Strings("paths", paths), zap.Bool("tls", s.certs != nil))
+
+		s.listening.Store(true)
+		defer s.listening.Store(false)
+
+		var err error
+		if s.certs != nil {
+			// certificates are provided by the TLS configuration.
+			err = srv.ServeTLS(ln, "", "")
+		} else {
+			err = srv.Serve(ln)
+		}
+		if err != nil && err != http.ErrServerClosed {
 			errCh <- err
 		}
-		close(errCh)
 	}()
 
+	if healthSrv != nil {
+		go func() {
+			s.logger.Infow("Serving health probes", zap.String("address", s.healthAddress))
+
+			if err := healthSrv.Serve(healthLn); err != nil && err != http.ErrServerClosed {
+				errCh <- fmt.Errorf("serving health probes: %w", err)
+			}
+		}()
+	}
+
 	// pods, err := s.client.CoreV1().Pods("default").List(ctx, metav1.ListOptions{})
 	// if err != nil {
 	// 	return err
@@ -79,17 +256,64 @@ func (s *Server) Start(ctx context.Context) error {
 		defer cancel()
 
 		srv.Shutdown(ctx)
+		if healthSrv != nil {
+			healthSrv.Shutdown(ctx)
+		}
 	}
 
 	return nil
 }
 
+// ServeHTTP implements http.Handler.
 func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	hreq := &hookv1.HookRequest{}
-	if err := json.NewDecoder(r.Body).Decode(hreq); err != nil {
-		msg := "cannot decode request into HookRequest: " + err.Error()
+	s.mux.ServeHTTP(w, r)
+}
+
+// hookHandler returns an HTTP handler that serves hook requests decoded by
+// the provided request decoder.
+func (s *Server) hookHandler(dec RequestDecoder) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		info := &hookRequestInfo{}
+
+		if s.metrics == nil {
+			s.serveHook(w, r, dec, info)
+			return
+		}
+
+		start := time.Now()
+		sw := &statusWriter{ResponseWriter: w, code: http.StatusOK}
+		s.serveHook(sw, r, dec, info)
+
+		s.metrics.ObserveRequest(info.gvk, info.op, sw.code, time.Since(start))
+	})
+}
+
+// hookRequestInfo collects information about a hook request while it is
+// being served.
+type hookRequestInfo struct {
+	gvk schema.GroupVersionKind
+	op  hookv1.Operation
+}
+
+// statusWriter is a http.ResponseWriter that keeps track of the response
+// status code.
+type statusWriter struct {
+	http.ResponseWriter
+	code int
+}
+
+// WriteHeader implements http.ResponseWriter.
+func (w *statusWriter) WriteHeader(code int) {
+	w.code = code
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (s *Server) serveHook(w http.ResponseWriter, r *http.Request, dec RequestDecoder, info *hookRequestInfo) {
+	hreq, err := dec.Decode(r)
+	if err != nil {
+		msg := err.Error()
 		s.logger.Error("Error decoding incoming request", zap.Error(errors.New(msg)))
-		http.Error(w, msg, http.StatusBadRequest)
+		writeHookError(w, http.StatusBadRequest, msg)
 		return
 	}
 
@@ -99,7 +323,7 @@ func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	if err != nil {
 		msg := "cannot parse APIVersion from HookRequest: " + err.Error()
 		s.logger.Error("Error parsing HookRequest", zap.Error(errors.New(msg)))
-		http.Error(w, msg, http.StatusBadRequest)
+		writeHookError(w, http.StatusBadRequest, msg)
 		return
 	}
 
@@ -108,46 +332,88 @@ func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	if !ok {
 		msg := fmt.Sprintf("the hook does not contain a handler for %q", gvk.String())
 		s.logger.Error("Error serving HookRequest", zap.Error(errors.New(msg)))
-		http.Error(w, msg, http.StatusBadRequest)
+		writeHookError(w, http.StatusBadRequest, msg)
 		return
 	}
+	info.gvk = gvk
+
+	trace.FromContext(r.Context()).AddAttributes(
+		trace.StringAttribute("hook.group", gvk.Group),
+		trace.StringAttribute("hook.version", gvk.Version),
+		trace.StringAttribute("hook.kind", gvk.Kind),
+		trace.StringAttribute("hook.namespace", hreq.Object.Namespace),
+		trace.StringAttribute("hook.name", hreq.Object.Name),
+		trace.StringAttribute("hook.operation", string(hreq.Operation)),
+	)
 
-	obj, err := s.dyn.Resource(*h.GroupVersionResource()).
+	u, err := s.dyn.Resource(*h.GroupVersionResource()).
 		Namespace(hreq.Object.Namespace).
 		Get(r.Context(), hreq.Object.Name, metav1.GetOptions{})
-	if err != nil {
+	switch {
+	case apierrors.IsNotFound(err):
 		msg := "object at the HookRequest cannot be found: " + err.Error()
 		s.logger.Error("Error processing request", zap.Error(errors.New(msg)))
 
 		// Using no content, to make clear that the API and the handler
 		// for the registered object exists, but the object cannot be retrieved.
-		http.Error(w, msg, http.StatusNoContent)
+		// A 204 response can't have a body, so the error is only logged.
+		w.WriteHeader(http.StatusNoContent)
+		return
+
+	case err != nil:
+		msg := "object at the HookRequest cannot be retrieved: " + err.Error()
+		s.logger.Error("Error processing request", zap.Error(errors.New(msg)))
+		writeHookError(w, http.StatusInternalServerError, msg)
 		return
 	}
 
+	obj, err := s.dec.Decode(u)
+	if err != nil {
+		msg := "object at the HookRequest cannot be decoded: " + err.Error()
+		s.logger.Error("Error processing request", zap.Error(errors.New(msg)))
+		writeHookError(w, http.StatusUnprocessableEntity, msg)
+		return
+	}
+
+	ctx := handler.WithErrorReporting(r.Context())
+	if ro, ok := obj.(runtime.Object); ok && s.recorder != nil {
+		ctx = event.WithRecorder(ctx, s.recorder, ro)
+	}
+
 	var hres *hookv1.HookResponse
 	switch hreq.Operation {
 	case hookv1.OperationReconcile:
-		hres = h.Reconcile(r.Context(), obj)
+		info.op = hreq.Operation
+		ctx, span := trace.StartSpan(ctx, "handler.Reconcile")
+		hres = h.Reconcile(ctx, obj)
+		span.End()
 
 	case hookv1.OperationFinalize:
+		info.op = hreq.Operation
 		f, ok := h.(handler.HandlerFinalizable)
 		if !ok {
 			msg := "hook handler does not support Finalizers"
 			s.logger.Error("Error processing request", zap.Error(errors.New(msg)))
-			http.Error(w, msg, http.StatusBadRequest)
+			writeHookError(w, http.StatusBadRequest, msg)
 			return
 		}
-		hres = f.Finalize(r.Context(), obj)
+		ctx, span := trace.StartSpan(ctx, "handler.Finalize")
+		hres = f.Finalize(ctx, obj)
+		span.End()
 
 	default:
 		msg := "request must be either " + string(hookv1.OperationReconcile) +
 			" or " + string(hookv1.OperationFinalize)
 		s.logger.Error("Error parsing request", zap.Error(errors.New(msg)))
-		http.Error(w, msg, http.StatusBadRequest)
+		writeHookError(w, http.StatusBadRequest, msg)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(hres)
+	hint := retryHintFor(handler.ReportedErrors(ctx))
+	if hint != nil {
+		s.logger.Debug("Operation failed", zap.String("reason", string(hint.Reason)),
+			zap.Bool("terminal", hint.Terminal), zap.Any("requeueAfter", hint.RequeueAfter))
+	}
+
+	writeHookResponse(w, hres, hint)
 }
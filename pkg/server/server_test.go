@@ -0,0 +1,270 @@
+// Copyright 2023 TriggerMesh Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	fakedynamic "k8s.io/client-go/dynamic/fake"
+	k8stesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/record"
+
+	commonv1alpha1 "github.com/triggermesh/scoby/pkg/apis/common/v1alpha1"
+	hookv1 "github.com/triggermesh/scoby/pkg/hook/v1"
+
+	"github.com/triggermesh/scoby-hook-triggermesh/pkg/handler"
+	"github.com/triggermesh/scoby-hook-triggermesh/pkg/reconciler/event"
+	"github.com/triggermesh/scoby-hook-triggermesh/pkg/sources/auth"
+	"github.com/triggermesh/scoby-hook-triggermesh/pkg/sources/errclass"
+)
+
+const (
+	tNamespace = "test-ns"
+	tName      = "test-name"
+)
+
+var tGVR = schema.GroupVersionResource{Group: "test.triggermesh.io", Version: "v1", Resource: "tests"}
+
+type testHandler struct {
+	reconciled metav1.Object
+	// error reported by the handler while reconciling
+	err error
+}
+
+func (h *testHandler) GroupVersionResource() *schema.GroupVersionResource { return &tGVR }
+func (h *testHandler) Kind() string                                       { return "Test" }
+func (h *testHandler) NewObject() runtime.Object                          { return &unstructured.Unstructured{} }
+func (h *testHandler) Reconcile(ctx context.Context, obj metav1.Object) *hookv1.HookResponse {
+	h.reconciled = obj
+	event.Normal(ctx, "Reconciled", "Reconciled %s", obj.GetName())
+	handler.ReportError(ctx, h.err)
+	return &hookv1.HookResponse{Status: &hookv1.HookStatus{}}
+}
+
+func newTestServer(t *testing.T, h handler.Handler, path string, opts ...Option) *Server {
+	t.Helper()
+
+	obj := &unstructured.Unstructured{}
+	obj.SetAPIVersion(tGVR.GroupVersion().String())
+	obj.SetKind("Test")
+	obj.SetNamespace(tNamespace)
+	obj.SetName(tName)
+
+	dyn := fakedynamic.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{tGVR: "TestList"}, obj)
+
+	return New(path, "", handler.NewRegistry([]handler.Handler{h}), dyn, zap.NewNop().Sugar(), opts...)
+}
+
+func newHookRequestBody(t *testing.T) string {
+	t.Helper()
+
+	b, err := json.Marshal(&hookv1.HookRequest{
+		Object: commonv1alpha1.Reference{
+			APIVersion: tGVR.GroupVersion().String(),
+			Kind:       "Test",
+			Namespace:  tNamespace,
+			Name:       tName,
+		},
+		Operation: hookv1.OperationReconcile,
+	})
+	require.NoError(t, err)
+
+	return string(b)
+}
+
+func TestServeVersions(t *testing.T) {
+	v2 := RequestDecoderFunc(decodeV1Request)
+
+	testCases := map[string]struct {
+		path       string
+		opts       []Option
+		reqPath    string
+		expectCode int
+	}{
+		"default version at root": {
+			path:       "/",
+			reqPath:    "/v1",
+			expectCode: http.StatusOK,
+		},
+		"default version at base path": {
+			path:       "hooks",
+			reqPath:    "/hooks/v1",
+			expectCode: http.StatusOK,
+		},
+		"version outside base path": {
+			path:       "hooks",
+			reqPath:    "/v1",
+			expectCode: http.StatusNotFound,
+		},
+		"additional version": {
+			path:       "/",
+			opts:       []Option{WithAPIVersion("v1", requestDecoders["v1"]), WithAPIVersion("v2", v2)},
+			reqPath:    "/v2",
+			expectCode: http.StatusOK,
+		},
+		"version not served": {
+			path:       "/",
+			reqPath:    "/v2",
+			expectCode: http.StatusNotFound,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			h := &testHandler{}
+			s := newTestServer(t, h, tc.path, tc.opts...)
+
+			rec := httptest.NewRecorder()
+			s.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, tc.reqPath, strings.NewReader(newHookRequestBody(t))))
+
+			assert.Equal(t, tc.expectCode, rec.Code, rec.Body.String())
+			if tc.expectCode == http.StatusOK {
+				require.NotNil(t, h.reconciled)
+				assert.Equal(t, tName, h.reconciled.GetName())
+			}
+		})
+	}
+}
+
+func TestServeHookError(t *testing.T) {
+	s := newTestServer(t, &testHandler{}, "/")
+
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/v1", strings.NewReader("{")))
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+
+	herr := &HookError{}
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(herr))
+	assert.Equal(t, http.StatusBadRequest, herr.Code)
+	assert.NotEmpty(t, herr.Message)
+}
+
+func TestServeObjectRetrievalError(t *testing.T) {
+	testCases := map[string]struct {
+		getErr     error
+		expectCode int
+	}{
+		"object not found": {
+			getErr:     nil,
+			expectCode: http.StatusNoContent,
+		},
+		"object cannot be retrieved": {
+			getErr:     errors.New("connection refused"),
+			expectCode: http.StatusInternalServerError,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			dyn := fakedynamic.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+				map[schema.GroupVersionResource]string{tGVR: "TestList"})
+			if tc.getErr != nil {
+				dyn.PrependReactor("get", "*", func(k8stesting.Action) (bool, runtime.Object, error) {
+					return true, nil, tc.getErr
+				})
+			}
+
+			s := New("/", "", handler.NewRegistry([]handler.Handler{&testHandler{}}), dyn, zap.NewNop().Sugar())
+
+			rec := httptest.NewRecorder()
+			s.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/v1", strings.NewReader(newHookRequestBody(t))))
+
+			assert.Equal(t, tc.expectCode, rec.Code)
+			if tc.expectCode == http.StatusNoContent {
+				assert.Zero(t, rec.Body.Len(), "A 204 response must not have a body")
+				return
+			}
+
+			herr := &HookError{}
+			require.NoError(t, json.NewDecoder(rec.Body).Decode(herr))
+			assert.Equal(t, tc.expectCode, herr.Code)
+			assert.Contains(t, herr.Message, "connection refused")
+		})
+	}
+}
+
+func TestServeEvents(t *testing.T) {
+	events := record.NewFakeRecorder(1)
+	s := newTestServer(t, &testHandler{}, "/", WithEventRecorder(events))
+
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/v1", strings.NewReader(newHookRequestBody(t))))
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	require.Len(t, events.Events, 1)
+	assert.Equal(t, "Normal Reconciled Reconciled "+tName, <-events.Events)
+}
+
+func TestServeRetryHint(t *testing.T) {
+	testCases := map[string]struct {
+		err              error
+		expectHint       *RetryHint
+		expectRetryAfter string
+	}{
+		"no error": {},
+		"unclassified error": {
+			err: assert.AnError,
+		},
+		"permanent credentials error": {
+			err:        auth.NewPermanentCredentialsError(assert.AnError),
+			expectHint: &RetryHint{Reason: errclass.Unauthenticated, Terminal: true},
+		},
+		"missing permissions": {
+			err: fmt.Errorf("deleting queue: %w",
+				awserr.NewRequestFailure(awserr.New("AccessDenied", "", nil), http.StatusForbidden, "")),
+			expectHint: &RetryHint{
+				Reason:       errclass.Denied,
+				RequeueAfter: &metav1.Duration{Duration: 10 * time.Minute},
+			},
+			expectRetryAfter: "600",
+		},
+		"throttled": {
+			err: fmt.Errorf("reconciling queue: %w",
+				awserr.NewRequestFailure(awserr.New("Throttling", "", nil), http.StatusBadRequest, "")),
+			expectHint: &RetryHint{
+				Reason:       errclass.Throttled,
+				RequeueAfter: &metav1.Duration{Duration: 30 * time.Second},
+			},
+			expectRetryAfter: "30",
+		},
+	}
+
+	for name, tc := range testCases {
+		//nolint:scopelint
+		t.Run(name, func(t *testing.T) {
+			s := newTestServer(t, &testHandler{err: tc.err}, "/")
+
+			rec := httptest.NewRecorder()
+			s.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/v1", strings.NewReader(newHookRequestBody(t))))
+			require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+			assert.Equal(t, tc.expectRetryAfter, rec.Header().Get("Retry-After"))
+
+			res := &HookResponse{}
+			require.NoError(t, json.NewDecoder(rec.Body).Decode(res))
+			assert.NotNil(t, res.Status, "Expected the response of the handler to be preserved")
+			assert.Equal(t, tc.expectHint, res.Retry)
+		})
+	}
+}
@@ -0,0 +1,164 @@
+// Copyright 2023 TriggerMesh Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+// certReloadInterval is the period at which certificate files are checked
+// for changes.
+const certReloadInterval = 30 * time.Second
+
+// CertificateReloader keeps the serving certificate and the optional client CA
+// bundle in sync with the files they are read from, so that rotated
+// certificates are served without restarting the hook.
+type CertificateReloader struct {
+	certFile string
+	keyFile  string
+	caFile   string
+
+	mu sync.RWMutex
+	// raw contents of the files, used to detect changes.
+	certPEM, keyPEM, caPEM []byte
+	cert                   *tls.Certificate
+	clientCAs              *x509.CertPool
+
+	logger *zap.SugaredLogger
+}
+
+// NewCertificateReloader loads the certificate, key and optional client CA
+// bundle from disk. When the client CA file is informed, callers are required
+// to present a certificate signed by one of the CAs in the bundle.
+func NewCertificateReloader(certFile, keyFile, caFile string, logger *zap.SugaredLogger) (*CertificateReloader, error) {
+	if certFile == "" || keyFile == "" {
+		return nil, errors.New("both TLS certificate and key files must be informed")
+	}
+
+	r := &CertificateReloader{
+		certFile: certFile,
+		keyFile:  keyFile,
+		caFile:   caFile,
+		logger:   logger,
+	}
+
+	if _, err := r.reload(); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// TLSConfig returns a TLS configuration that always uses the latest loaded
+// certificates.
+func (r *CertificateReloader) TLSConfig() *tls.Config {
+	cfg := &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: r.getCertificate,
+	}
+
+	if r.caFile != "" {
+		cfg.GetConfigForClient = r.getConfigForClient
+	}
+
+	return cfg
+}
+
+// Watch checks the certificate files for changes until the context is done.
+func (r *CertificateReloader) Watch(ctx context.Context) {
+	wait.UntilWithContext(ctx, func(ctx context.Context) {
+		changed, err := r.reload()
+		switch {
+		case err != nil:
+			r.logger.Errorw("Error reloading TLS certificates, keeping previous ones", zap.Error(err))
+		case changed:
+			r.logger.Info("TLS certificates reloaded")
+		}
+	}, certReloadInterval)
+}
+
+func (r *CertificateReloader) getCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.cert, nil
+}
+
+func (r *CertificateReloader) getConfigForClient(*tls.ClientHelloInfo) (*tls.Config, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: r.getCertificate,
+		ClientAuth:     tls.RequireAndVerifyClientCert,
+		ClientCAs:      r.clientCAs,
+	}, nil
+}
+
+// reload reads the certificate files and replaces the loaded certificates if
+// any of them changed. The returned boolean indicates whether certificates
+// were replaced.
+func (r *CertificateReloader) reload() (bool, error) {
+	certPEM, err := os.ReadFile(r.certFile)
+	if err != nil {
+		return false, fmt.Errorf("reading TLS certificate file: %w", err)
+	}
+
+	keyPEM, err := os.ReadFile(r.keyFile)
+	if err != nil {
+		return false, fmt.Errorf("reading TLS key file: %w", err)
+	}
+
+	var caPEM []byte
+	if r.caFile != "" {
+		if caPEM, err = os.ReadFile(r.caFile); err != nil {
+			return false, fmt.Errorf("reading client CA file: %w", err)
+		}
+	}
+
+	r.mu.RLock()
+	unchanged := bytes.Equal(certPEM, r.certPEM) &&
+		bytes.Equal(keyPEM, r.keyPEM) &&
+		bytes.Equal(caPEM, r.caPEM)
+	r.mu.RUnlock()
+
+	if unchanged {
+		return false, nil
+	}
+
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		return false, fmt.Errorf("parsing TLS key pair: %w", err)
+	}
+
+	var clientCAs *x509.CertPool
+	if caPEM != nil {
+		clientCAs = x509.NewCertPool()
+		if !clientCAs.AppendCertsFromPEM(caPEM) {
+			return false, fmt.Errorf("no valid certificates found at client CA file %q", r.caFile)
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.certPEM, r.keyPEM, r.caPEM = certPEM, keyPEM, caPEM
+	r.cert = &cert
+	r.clientCAs = clientCAs
+
+	return true, nil
+}
@@ -0,0 +1,71 @@
+// Copyright 2023 TriggerMesh Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+
+	hookv1 "github.com/triggermesh/scoby/pkg/hook/v1"
+)
+
+// DefaultAPIVersion is the hook API version served when none is configured.
+const DefaultAPIVersion = "v1"
+
+// RequestDecoder reads incoming requests for a version of the hook API.
+// Each version decodes its own wire format into a HookRequest.
+type RequestDecoder interface {
+	Decode(r *http.Request) (*hookv1.HookRequest, error)
+}
+
+// RequestDecoderFunc allows the use of ordinary functions as RequestDecoder.
+type RequestDecoderFunc func(r *http.Request) (*hookv1.HookRequest, error)
+
+// RequestDecoderFunc implements RequestDecoder.
+var _ RequestDecoder = (RequestDecoderFunc)(nil)
+
+// Decode implements RequestDecoder.
+func (f RequestDecoderFunc) Decode(r *http.Request) (*hookv1.HookRequest, error) {
+	return f(r)
+}
+
+// decodeV1Request decodes requests for the v1 hook API.
+func decodeV1Request(r *http.Request) (*hookv1.HookRequest, error) {
+	hreq := &hookv1.HookRequest{}
+	if err := json.NewDecoder(r.Body).Decode(hreq); err != nil {
+		return nil, fmt.Errorf("cannot decode request into HookRequest: %w", err)
+	}
+
+	return hreq, nil
+}
+
+// requestDecoders contains the decoders for all hook API versions known to
+// this hook.
+var requestDecoders = map[string]RequestDecoder{
+	"v1": RequestDecoderFunc(decodeV1Request),
+}
+
+// RequestDecoderForVersion returns the request decoder for a hook API version.
+func RequestDecoderForVersion(version string) (RequestDecoder, error) {
+	dec, ok := requestDecoders[version]
+	if !ok {
+		return nil, fmt.Errorf("unknown hook API version %q, supported versions are %v", version, SupportedAPIVersions())
+	}
+
+	return dec, nil
+}
+
+// SupportedAPIVersions returns the sorted list of hook API versions known to
+// this hook.
+func SupportedAPIVersions() []string {
+	versions := make([]string, 0, len(requestDecoders))
+	for v := range requestDecoders {
+		versions = append(versions, v)
+	}
+	sort.Strings(versions)
+
+	return versions
+}
@@ -23,7 +23,7 @@ import (
 
 	"github.com/stretchr/testify/assert"
 
-	. "github.com/triggermesh/triggermesh/pkg/sources/auth"
+	. "github.com/triggermesh/scoby-hook-triggermesh/pkg/sources/auth"
 )
 
 func TestPermanentCredentialsError(t *testing.T) {
@@ -0,0 +1,101 @@
+/*
+Copyright 2023 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aws
+
+import (
+	"sort"
+	"time"
+
+	awscore "github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/credentials/stscreds"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/sts"
+
+	"github.com/triggermesh/scoby-hook-triggermesh/pkg/apis/common/v1alpha1"
+)
+
+// DefaultRoleSessionName is the name of role sessions which don't have an
+// explicit name.
+const DefaultRoleSessionName = "triggermesh"
+
+// stsGlobalRegion is the region used to sign requests to the global STS
+// endpoint when the session doesn't have a region.
+const stsGlobalRegion = "us-east-1"
+
+// AssumeRoleChain returns credentials obtained by assuming the given roles in
+// sequence, starting from the given base credentials. Each role is assumed
+// using the credentials of the previous one. Credentials are refreshed
+// automatically before they expire.
+//
+// The configuration of the given session, such as a custom endpoint, applies
+// to requests sent to the STS API.
+func AssumeRoleChain(sess *session.Session, base *credentials.Credentials, roles []v1alpha1.AWSAssumeRole) *credentials.Credentials {
+	creds := base
+	for _, r := range roles {
+		creds = stscreds.NewCredentialsWithClient(newSTSClient(sess, creds), r.RoleARN.String(), assumeRoleOptions(r))
+	}
+
+	return creds
+}
+
+// newSTSClient returns a client for the STS API which signs requests using
+// the given credentials.
+func newSTSClient(sess *session.Session, creds *credentials.Credentials) *sts.STS {
+	region := awscore.StringValue(sess.Config.Region)
+	if region == "" {
+		region = stsGlobalRegion
+	}
+
+	return sts.New(sess, awscore.NewConfig().
+		WithRegion(region).
+		WithCredentials(creds),
+	)
+}
+
+// assumeRoleOptions returns a function which applies the parameters of the
+// given role to an AssumeRoleProvider.
+func assumeRoleOptions(r v1alpha1.AWSAssumeRole) func(*stscreds.AssumeRoleProvider) {
+	return func(p *stscreds.AssumeRoleProvider) {
+		p.RoleSessionName = DefaultRoleSessionName
+		if r.SessionName != "" {
+			p.RoleSessionName = r.SessionName
+		}
+
+		if r.ExternalID != "" {
+			p.ExternalID = awscore.String(r.ExternalID)
+		}
+
+		if r.DurationSeconds != nil {
+			p.Duration = time.Duration(*r.DurationSeconds) * time.Second
+		}
+
+		// tags are sorted by key so that requests are deterministic
+		keys := make([]string, 0, len(r.SessionTags))
+		for k := range r.SessionTags {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		for _, k := range keys {
+			p.Tags = append(p.Tags, &sts.Tag{
+				Key:   awscore.String(k),
+				Value: awscore.String(r.SessionTags[k]),
+			})
+		}
+	}
+}
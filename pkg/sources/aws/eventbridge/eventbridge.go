@@ -0,0 +1,159 @@
+/*
+Copyright 2023 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package eventbridge contains helpers for Amazon EventBridge.
+package eventbridge
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/eventbridge"
+	"github.com/aws/aws-sdk-go/service/eventbridge/eventbridgeiface"
+)
+
+// Rule contains the attributes of a rule relevant to the reconciliation of
+// event sources.
+type Rule struct {
+	ARN          string
+	EventPattern string
+}
+
+// GetRule returns the rule with the given name from the default event bus.
+func GetRule(ctx context.Context, cli eventbridgeiface.EventBridgeAPI, name string) (*Rule, error) {
+	rule := &eventbridge.DescribeRuleInput{
+		Name: &name,
+	}
+
+	resp, err := cli.DescribeRuleWithContext(ctx, rule)
+	if err != nil {
+		return nil, fmt.Errorf("describing rule %q: %w", *rule.Name, err)
+	}
+
+	return &Rule{
+		ARN:          aws.StringValue(resp.Arn),
+		EventPattern: aws.StringValue(resp.EventPattern),
+	}, nil
+}
+
+// PutRule creates or updates a rule with the given name, event pattern and
+// optional tags on the default event bus.
+//
+// Naming restrictions are described at https://docs.aws.amazon.com/eventbridge/latest/APIReference/API_PutRule.html
+func PutRule(ctx context.Context, cli eventbridgeiface.EventBridgeAPI, name, pattern string, tags map[string]string) (string /*arn*/, error) {
+	rule := &eventbridge.PutRuleInput{
+		Name:         &name,
+		EventPattern: &pattern,
+	}
+	for k, v := range tags {
+		rule.Tags = append(rule.Tags, &eventbridge.Tag{Key: aws.String(k), Value: aws.String(v)})
+	}
+
+	resp, err := cli.PutRuleWithContext(ctx, rule)
+	if err != nil {
+		return "", fmt.Errorf("putting rule %q: %w", *rule.Name, err)
+	}
+
+	return *resp.RuleArn, nil
+}
+
+// DeleteRule deletes the rule with the given name, after removing the targets
+// with the given IDs.
+func DeleteRule(ctx context.Context, cli eventbridgeiface.EventBridgeAPI, name string, targetIDs ...string) error {
+	if len(targetIDs) != 0 {
+		targets := &eventbridge.RemoveTargetsInput{
+			Rule: &name,
+			Ids:  aws.StringSlice(targetIDs),
+		}
+		if _, err := cli.RemoveTargetsWithContext(ctx, targets); err != nil {
+			return fmt.Errorf("removing targets of rule %q: %w", *targets.Rule, err)
+		}
+	}
+
+	rule := &eventbridge.DeleteRuleInput{
+		Name: &name,
+	}
+
+	if _, err := cli.DeleteRuleWithContext(ctx, rule); err != nil {
+		return fmt.Errorf("deleting rule %q: %w", *rule.Name, err)
+	}
+
+	return nil
+}
+
+// RuleTargets returns the ARNs of the targets of the rule with the given
+// name, indexed by target ID.
+func RuleTargets(ctx context.Context, cli eventbridgeiface.EventBridgeAPI, name string) (map[string]string, error) {
+	rule := &eventbridge.ListTargetsByRuleInput{
+		Rule: &name,
+	}
+
+	resp, err := cli.ListTargetsByRuleWithContext(ctx, rule)
+	if err != nil {
+		return nil, fmt.Errorf("listing targets of rule %q: %w", *rule.Rule, err)
+	}
+
+	targets := make(map[string]string, len(resp.Targets))
+	for _, t := range resp.Targets {
+		targets[aws.StringValue(t.Id)] = aws.StringValue(t.Arn)
+	}
+
+	return targets, nil
+}
+
+// PutTarget creates or updates the target with the given ID and ARN of the
+// rule with the given name.
+func PutTarget(ctx context.Context, cli eventbridgeiface.EventBridgeAPI, rule, id, arn string) error {
+	targets := &eventbridge.PutTargetsInput{
+		Rule: &rule,
+		Targets: []*eventbridge.Target{{
+			Id:  &id,
+			Arn: &arn,
+		}},
+	}
+
+	resp, err := cli.PutTargetsWithContext(ctx, targets)
+	if err != nil {
+		return fmt.Errorf("putting targets of rule %q: %w", *targets.Rule, err)
+	}
+	if n := aws.Int64Value(resp.FailedEntryCount); n != 0 {
+		e := resp.FailedEntries[0]
+		return fmt.Errorf("putting target %q of rule %q: %s: %s", id, rule,
+			aws.StringValue(e.ErrorCode), aws.StringValue(e.ErrorMessage))
+	}
+
+	return nil
+}
+
+// RuleTags returns the tags of the rule with the given ARN.
+func RuleTags(ctx context.Context, cli eventbridgeiface.EventBridgeAPI, arn string) (map[string]string, error) {
+	rule := &eventbridge.ListTagsForResourceInput{
+		ResourceARN: &arn,
+	}
+
+	resp, err := cli.ListTagsForResourceWithContext(ctx, rule)
+	if err != nil {
+		return nil, fmt.Errorf("listing tags of rule %q: %w", *rule.ResourceARN, err)
+	}
+
+	tags := make(map[string]string, len(resp.Tags))
+	for _, t := range resp.Tags {
+		tags[aws.StringValue(t.Key)] = aws.StringValue(t.Value)
+	}
+
+	return tags, nil
+}
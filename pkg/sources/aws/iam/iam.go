@@ -17,7 +17,15 @@ limitations under the License.
 // Package iam contains helpers to interact with IAM objects.
 package iam
 
-import "github.com/google/uuid"
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/google/uuid"
+)
 
 const latestPolicyLanguageVersion = "2012-10-17"
 
@@ -25,19 +33,46 @@ const latestPolicyLanguageVersion = "2012-10-17"
 // unmarshaling to/from JSON.
 // See https://docs.aws.amazon.com/IAM/latest/UserGuide/access_policies.html#access_policies-json
 type Policy struct {
-	Version   string            `json:"Version"`
-	ID        string            `json:"Id,omitempty"`
-	Statement []PolicyStatement `json:"Statement,omitempty"`
+	Version   string           `json:"Version"`
+	ID        string           `json:"Id,omitempty"`
+	Statement PolicyStatements `json:"Statement,omitempty"`
+}
+
+// PolicyStatements is the Statement element of a Policy. It is serialized as
+// a list, but can be deserialized from either a list or a single object.
+type PolicyStatements []PolicyStatement
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (s *PolicyStatements) UnmarshalJSON(b []byte) error {
+	if b = bytes.TrimSpace(b); len(b) > 0 && b[0] == '{' {
+		var stmt PolicyStatement
+		if err := json.Unmarshal(b, &stmt); err != nil {
+			return err
+		}
+		*s = PolicyStatements{stmt}
+		return nil
+	}
+
+	var stmts []PolicyStatement
+	if err := json.Unmarshal(b, &stmts); err != nil {
+		return err
+	}
+	*s = stmts
+	return nil
 }
 
 // PolicyStatement is a Statement element in a Policy.
+// https://docs.aws.amazon.com/IAM/latest/UserGuide/reference_policies_elements.html
 type PolicyStatement struct {
-	Sid       string                   `json:"Sid,omitempty"`
-	Effect    PolicyStatementEffect    `json:"Effect"`
-	Principal PolicyStatementPrincipal `json:"Principal,omitempty"`
-	Action    []string                 `json:"Action"`
-	Resource  []string                 `json:"Resource"`
-	Condition PolicyStatementCondition `json:"Condition,omitempty"`
+	Sid          string                    `json:"Sid,omitempty"`
+	Effect       PolicyStatementEffect     `json:"Effect"`
+	Principal    *PolicyStatementPrincipal `json:"Principal,omitempty"`
+	NotPrincipal *PolicyStatementPrincipal `json:"NotPrincipal,omitempty"`
+	Action       StringList                `json:"Action,omitempty"`
+	NotAction    StringList                `json:"NotAction,omitempty"`
+	Resource     StringList                `json:"Resource,omitempty"`
+	NotResource  StringList                `json:"NotResource,omitempty"`
+	Condition    PolicyStatementCondition  `json:"Condition,omitempty"`
 }
 
 // PolicyStatementEffect represents the Effect element of a Statement.
@@ -46,19 +81,100 @@ type PolicyStatementEffect string
 // List of acceptable PolicyStatementEffect values.
 const (
 	EffectAllow PolicyStatementEffect = "Allow"
+	EffectDeny  PolicyStatementEffect = "Deny"
 )
 
-// PolicyStatementPrincipal is the Principal element of a Statement.
+// PolicyStatementPrincipal is the Principal or NotPrincipal element of a
+// Statement.
 // https://docs.aws.amazon.com/IAM/latest/UserGuide/reference_policies_elements_principal.html
 type PolicyStatementPrincipal struct {
-	Service []string `json:"Service"`
+	// Everyone is set when the principal is the "*" wildcard, which
+	// matches all principals including anonymous users.
+	Everyone bool `json:"-"`
+
+	AWS           StringList `json:"AWS,omitempty"`
+	Service       StringList `json:"Service,omitempty"`
+	Federated     StringList `json:"Federated,omitempty"`
+	CanonicalUser StringList `json:"CanonicalUser,omitempty"`
 }
 
-// PolicyStatementCondition is the Condition element of a Statement.
+// principalWildcard is the serialized form of a principal which matches
+// everyone.
+const principalWildcard = "*"
+
+// principalFields is used to (de)serialize the fields of a
+// PolicyStatementPrincipal without recursing into its JSON methods.
+type principalFields PolicyStatementPrincipal
+
+// MarshalJSON implements json.Marshaler.
+func (p PolicyStatementPrincipal) MarshalJSON() ([]byte, error) {
+	if p.Everyone {
+		return json.Marshal(principalWildcard)
+	}
+	return json.Marshal(principalFields(p))
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (p *PolicyStatementPrincipal) UnmarshalJSON(b []byte) error {
+	var wildcard string
+	if err := json.Unmarshal(b, &wildcard); err == nil {
+		if wildcard != principalWildcard {
+			return fmt.Errorf("invalid principal %q", wildcard)
+		}
+		*p = PolicyStatementPrincipal{Everyone: true}
+		return nil
+	}
+
+	var f principalFields
+	if err := json.Unmarshal(b, &f); err != nil {
+		return err
+	}
+	*p = PolicyStatementPrincipal(f)
+	return nil
+}
+
+// PolicyStatementCondition is the Condition element of a Statement. It maps
+// condition operators (e.g. "StringEquals", "ForAnyValue:StringLike") to
+// condition keys and their values.
 // See https://docs.aws.amazon.com/IAM/latest/UserGuide/reference_policies_elements_condition_operators.html
-type PolicyStatementCondition struct {
-	ArnEquals    map[string]string `json:"ArnEquals,omitempty"`
-	StringEquals map[string]string `json:"StringEquals,omitempty"`
+type PolicyStatementCondition map[string]map[string]StringList
+
+// StringList is a list of strings which can be deserialized from either a
+// single value or a list of values. Boolean and numeric values, which are
+// valid in conditions, are deserialized to their string representation.
+type StringList []string
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (l *StringList) UnmarshalJSON(b []byte) error {
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return err
+	}
+
+	vals, isList := v.([]interface{})
+	if !isList {
+		vals = []interface{}{v}
+	}
+
+	list := make(StringList, len(vals))
+	for i, val := range vals {
+		switch val := val.(type) {
+		case string:
+			list[i] = val
+		case json.Number:
+			list[i] = val.String()
+		case bool:
+			list[i] = strconv.FormatBool(val)
+		default:
+			return fmt.Errorf("unsupported value of type %T in list of strings", val)
+		}
+	}
+
+	*l = list
+	return nil
 }
 
 // NewPolicy returns a new Policy with the given Statements applied to it.
@@ -88,60 +204,204 @@ func NewPolicyStatement(effect PolicyStatementEffect, opts ...PolicyStatementOpt
 // PolicyStatementOpt is a functional option for a PolicyStatement.
 type PolicyStatementOpt func(*PolicyStatement)
 
+// Sid sets the Sid of the Statement, which identifies it within a Policy.
+func Sid(sid string) PolicyStatementOpt {
+	return func(s *PolicyStatement) {
+		s.Sid = sid
+	}
+}
+
+// PrincipalEveryone sets the Principal to the "*" wildcard.
+func PrincipalEveryone() PolicyStatementOpt {
+	return func(s *PolicyStatement) {
+		s.Principal = &PolicyStatementPrincipal{Everyone: true}
+	}
+}
+
 // PrincipalService adds a "Service" to the Principal.
 func PrincipalService(service string) PolicyStatementOpt {
 	return func(s *PolicyStatement) {
-		ps := &s.Principal.Service
-		if *ps == nil {
-			valPs := make([]string, 0, 1)
-			*ps = valPs
-		}
-		*ps = append(*ps, service)
+		p := principal(&s.Principal)
+		p.Service = append(p.Service, service)
+	}
+}
+
+// PrincipalAWS adds an "AWS" principal (account, user or role) to the
+// Principal.
+func PrincipalAWS(arn string) PolicyStatementOpt {
+	return func(s *PolicyStatement) {
+		p := principal(&s.Principal)
+		p.AWS = append(p.AWS, arn)
+	}
+}
+
+// NotPrincipalAWS adds an "AWS" principal (account, user or role) to the
+// NotPrincipal.
+func NotPrincipalAWS(arn string) PolicyStatementOpt {
+	return func(s *PolicyStatement) {
+		p := principal(&s.NotPrincipal)
+		p.AWS = append(p.AWS, arn)
+	}
+}
+
+// principal initializes the given principal if necessary, and returns it.
+func principal(p **PolicyStatementPrincipal) *PolicyStatementPrincipal {
+	if *p == nil {
+		*p = &PolicyStatementPrincipal{}
 	}
+	return *p
 }
 
 // Action adds an Action.
 func Action(action string) PolicyStatementOpt {
 	return func(s *PolicyStatement) {
-		a := &s.Action
-		if *a == nil {
-			*a = make([]string, 0, 1)
-		}
-		*a = append(*a, action)
+		s.Action = append(s.Action, action)
+	}
+}
+
+// NotAction adds a NotAction.
+func NotAction(action string) PolicyStatementOpt {
+	return func(s *PolicyStatement) {
+		s.NotAction = append(s.NotAction, action)
 	}
 }
 
 // Resource adds a Resource.
 func Resource(resource string) PolicyStatementOpt {
 	return func(s *PolicyStatement) {
-		r := &s.Resource
-		if *r == nil {
-			*r = make([]string, 0, 1)
-		}
-		*r = append(*r, resource)
+		s.Resource = append(s.Resource, resource)
 	}
 }
 
-// ConditionArnEquals sets a Condition of type "ArnEquals".
-func ConditionArnEquals(key, val string) PolicyStatementOpt {
+// NotResource adds a NotResource.
+func NotResource(resource string) PolicyStatementOpt {
+	return func(s *PolicyStatement) {
+		s.NotResource = append(s.NotResource, resource)
+	}
+}
+
+// Condition adds values to a Condition with the given operator and key.
+func Condition(operator, key string, vals ...string) PolicyStatementOpt {
 	return func(s *PolicyStatement) {
-		aec := &s.Condition.ArnEquals
-		if *aec == nil {
-			valAec := make(map[string]string, 1)
-			*aec = valAec
+		if s.Condition == nil {
+			s.Condition = make(PolicyStatementCondition, 1)
 		}
-		(*aec)[key] = val
+		if s.Condition[operator] == nil {
+			s.Condition[operator] = make(map[string]StringList, 1)
+		}
+		s.Condition[operator][key] = append(s.Condition[operator][key], vals...)
 	}
 }
 
+// ConditionArnEquals sets a Condition of type "ArnEquals".
+func ConditionArnEquals(key, val string) PolicyStatementOpt {
+	return Condition("ArnEquals", key, val)
+}
+
 // ConditionStringEquals sets a Condition of type "StringEquals".
 func ConditionStringEquals(key, val string) PolicyStatementOpt {
-	return func(s *PolicyStatement) {
-		sec := &s.Condition.StringEquals
-		if *sec == nil {
-			valSec := make(map[string]string, 1)
-			*sec = valSec
+	return Condition("StringEquals", key, val)
+}
+
+// EqualPolicies returns whether two policies are semantically equal, that is
+// whether they contain the same statements regardless of their order.
+func EqualPolicies(a, b Policy) bool {
+	if a.Version != b.Version || a.ID != b.ID || len(a.Statement) != len(b.Statement) {
+		return false
+	}
+
+	matched := make([]bool, len(b.Statement))
+	for _, as := range a.Statement {
+		found := false
+		for i, bs := range b.Statement {
+			if !matched[i] && EqualStatements(as, bs) {
+				matched[i], found = true, true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	return true
+}
+
+// EqualStatements returns whether two statements are semantically equal.
+// Lists of values are compared regardless of their order and of duplicates.
+func EqualStatements(a, b PolicyStatement) bool {
+	return a.Sid == b.Sid &&
+		a.Effect == b.Effect &&
+		equalPrincipals(a.Principal, b.Principal) &&
+		equalPrincipals(a.NotPrincipal, b.NotPrincipal) &&
+		equalStringLists(a.Action, b.Action) &&
+		equalStringLists(a.NotAction, b.NotAction) &&
+		equalStringLists(a.Resource, b.Resource) &&
+		equalStringLists(a.NotResource, b.NotResource) &&
+		equalConditions(a.Condition, b.Condition)
+}
+
+// equalPrincipals returns whether two principals are semantically equal.
+func equalPrincipals(a, b *PolicyStatementPrincipal) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+
+	return a.Everyone == b.Everyone &&
+		equalStringLists(a.AWS, b.AWS) &&
+		equalStringLists(a.Service, b.Service) &&
+		equalStringLists(a.Federated, b.Federated) &&
+		equalStringLists(a.CanonicalUser, b.CanonicalUser)
+}
+
+// equalConditions returns whether two conditions are semantically equal.
+func equalConditions(a, b PolicyStatementCondition) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for op, aKeys := range a {
+		bKeys, ok := b[op]
+		if !ok || len(aKeys) != len(bKeys) {
+			return false
+		}
+		for k, aVals := range aKeys {
+			bVals, ok := bKeys[k]
+			if !ok || !equalStringLists(aVals, bVals) {
+				return false
+			}
 		}
-		(*sec)[key] = val
 	}
+
+	return true
+}
+
+// equalStringLists returns whether two lists contain the same set of values.
+func equalStringLists(a, b StringList) bool {
+	as, bs := uniqueSorted(a), uniqueSorted(b)
+	if len(as) != len(bs) {
+		return false
+	}
+	for i := range as {
+		if as[i] != bs[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// uniqueSorted returns a sorted copy of the given list without duplicates.
+func uniqueSorted(l StringList) []string {
+	set := make(map[string]struct{}, len(l))
+	for _, v := range l {
+		set[v] = struct{}{}
+	}
+
+	out := make([]string, 0, len(set))
+	for v := range set {
+		out = append(out, v)
+	}
+	sort.Strings(out)
+
+	return out
 }
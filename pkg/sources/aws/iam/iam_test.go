@@ -0,0 +1,188 @@
+/*
+Copyright 2023 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package iam
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPolicyJSON(t *testing.T) {
+	const polJSON = `{
+	  "Version": "2012-10-17",
+	  "Id": "my-policy",
+	  "Statement": [
+	    {
+	      "Sid": "DenyInsecureTransport",
+	      "Effect": "Deny",
+	      "Principal": "*",
+	      "NotAction": "sqs:GetQueueUrl",
+	      "Resource": "arn:aws:sqs:eu-west-1:123456789012:my-queue",
+	      "Condition": {
+	        "Bool": {"aws:SecureTransport": false},
+	        "NumericLessThan": {"s3:TlsVersion": 1.2}
+	      }
+	    },
+	    {
+	      "Sid": "AllowAccounts",
+	      "Effect": "Allow",
+	      "Principal": {"AWS": ["arn:aws:iam::123456789012:root", "210987654321"]},
+	      "NotPrincipal": {"AWS": "arn:aws:iam::123456789012:user/bob"},
+	      "Action": ["sqs:SendMessage", "sqs:ReceiveMessage"],
+	      "NotResource": ["arn:aws:sqs:eu-west-1:123456789012:other-queue"],
+	      "Condition": {
+	        "StringLike": {"aws:SourceArn": ["arn:aws:s3:::bucket-*", "arn:aws:s3:::other-*"]},
+	        "ForAnyValue:StringEquals": {"aws:PrincipalTag/team": "events"}
+	      }
+	    }
+	  ]
+	}`
+
+	var pol Policy
+	require.NoError(t, json.Unmarshal([]byte(polJSON), &pol))
+
+	expect := Policy{
+		Version: "2012-10-17",
+		ID:      "my-policy",
+		Statement: PolicyStatements{
+			NewPolicyStatement(EffectDeny,
+				Sid("DenyInsecureTransport"),
+				PrincipalEveryone(),
+				NotAction("sqs:GetQueueUrl"),
+				Resource("arn:aws:sqs:eu-west-1:123456789012:my-queue"),
+				Condition("Bool", "aws:SecureTransport", "false"),
+				Condition("NumericLessThan", "s3:TlsVersion", "1.2"),
+			),
+			NewPolicyStatement(EffectAllow,
+				Sid("AllowAccounts"),
+				PrincipalAWS("arn:aws:iam::123456789012:root"),
+				PrincipalAWS("210987654321"),
+				NotPrincipalAWS("arn:aws:iam::123456789012:user/bob"),
+				Action("sqs:SendMessage"),
+				Action("sqs:ReceiveMessage"),
+				NotResource("arn:aws:sqs:eu-west-1:123456789012:other-queue"),
+				Condition("StringLike", "aws:SourceArn", "arn:aws:s3:::bucket-*", "arn:aws:s3:::other-*"),
+				Condition("ForAnyValue:StringEquals", "aws:PrincipalTag/team", "events"),
+			),
+		},
+	}
+	assert.Equal(t, expect, pol)
+
+	// serializing the policy does not lose any data
+	b, err := json.Marshal(pol)
+	require.NoError(t, err)
+
+	var roundTripped Policy
+	require.NoError(t, json.Unmarshal(b, &roundTripped))
+	assert.Equal(t, pol, roundTripped)
+
+	t.Run("single statement object", func(t *testing.T) {
+		var pol Policy
+		err := json.Unmarshal([]byte(`{"Version":"2012-10-17","Statement":{"Effect":"Allow","Action":"*","Resource":"*"}}`), &pol)
+		require.NoError(t, err)
+		assert.Equal(t, PolicyStatements{{Effect: EffectAllow, Action: StringList{"*"}, Resource: StringList{"*"}}}, pol.Statement)
+	})
+
+	t.Run("invalid principal", func(t *testing.T) {
+		var stmt PolicyStatement
+		assert.Error(t, json.Unmarshal([]byte(`{"Principal":"someone"}`), &stmt))
+	})
+}
+
+func TestEqualStatements(t *testing.T) {
+	base := func(opts ...PolicyStatementOpt) PolicyStatement {
+		return NewPolicyStatement(EffectAllow, append([]PolicyStatementOpt{
+			Sid("stmt"),
+			PrincipalService("s3.amazonaws.com"),
+			Action("sqs:SendMessage"),
+			Action("sqs:GetQueueUrl"),
+			Resource("arn:aws:sqs:eu-west-1:123456789012:my-queue"),
+			Condition("ArnLike", "aws:SourceArn", "arn:aws:s3:::a", "arn:aws:s3:::b"),
+		}, opts...)...)
+	}
+
+	testCases := map[string]struct {
+		a, b   PolicyStatement
+		expect bool
+	}{
+		"identical": {
+			a:      base(),
+			b:      base(),
+			expect: true,
+		},
+		"lists in different order": {
+			a: base(),
+			b: NewPolicyStatement(EffectAllow,
+				Sid("stmt"),
+				PrincipalService("s3.amazonaws.com"),
+				Action("sqs:GetQueueUrl"),
+				Action("sqs:SendMessage"),
+				Action("sqs:SendMessage"),
+				Resource("arn:aws:sqs:eu-west-1:123456789012:my-queue"),
+				Condition("ArnLike", "aws:SourceArn", "arn:aws:s3:::b", "arn:aws:s3:::a"),
+			),
+			expect: true,
+		},
+		"different effect": {
+			a: base(),
+			b: func() PolicyStatement { s := base(); s.Effect = EffectDeny; return s }(),
+		},
+		"additional principal": {
+			a: base(),
+			b: base(PrincipalAWS("123456789012")),
+		},
+		"wildcard principal": {
+			a: base(),
+			b: base(PrincipalEveryone()),
+		},
+		"additional condition value": {
+			a: base(),
+			b: base(Condition("ArnLike", "aws:SourceArn", "arn:aws:s3:::c")),
+		},
+		"additional condition operator": {
+			a: base(),
+			b: base(ConditionStringEquals("aws:SourceAccount", "123456789012")),
+		},
+		"action moved to NotAction": {
+			a: base(),
+			b: func() PolicyStatement { s := base(); s.NotAction, s.Action = s.Action, nil; return s }(),
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tc.expect, EqualStatements(tc.a, tc.b))
+			assert.Equal(t, tc.expect, EqualStatements(tc.b, tc.a))
+		})
+	}
+}
+
+func TestEqualPolicies(t *testing.T) {
+	s1 := NewPolicyStatement(EffectAllow, Sid("s1"), Action("sqs:SendMessage"), Resource("*"))
+	s2 := NewPolicyStatement(EffectDeny, Sid("s2"), NotAction("sqs:SendMessage"), Resource("*"))
+
+	a := Policy{Version: latestPolicyLanguageVersion, Statement: PolicyStatements{s1, s2}}
+	b := Policy{Version: latestPolicyLanguageVersion, Statement: PolicyStatements{s2, s1}}
+	c := Policy{Version: latestPolicyLanguageVersion, Statement: PolicyStatements{s1, s1}}
+
+	assert.True(t, EqualPolicies(a, b))
+	assert.False(t, EqualPolicies(a, c))
+	assert.False(t, EqualPolicies(c, a))
+}
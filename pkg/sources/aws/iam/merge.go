@@ -0,0 +1,203 @@
+/*
+Copyright 2023 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package iam
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+)
+
+// document is a serialized Policy whose statements are kept in their raw JSON
+// form, so that statements which are not managed by the caller are preserved
+// as they are, including elements which are not modelled by PolicyStatement.
+type document struct {
+	Version   string            `json:"Version"`
+	ID        string            `json:"Id,omitempty"`
+	Statement []json.RawMessage `json:"Statement"`
+}
+
+// parseDocument parses a serialized Policy. An empty string is parsed as a
+// Policy without statements.
+func parseDocument(policy string) (*document, error) {
+	if policy == "" {
+		return &document{Version: latestPolicyLanguageVersion}, nil
+	}
+
+	// the Statement element can be either a single object or a list of
+	// objects
+	var doc struct {
+		document
+		Statement json.RawMessage `json:"Statement"`
+	}
+	if err := json.Unmarshal([]byte(policy), &doc); err != nil {
+		return nil, fmt.Errorf("parsing policy: %w", err)
+	}
+
+	stmts := bytes.TrimSpace(doc.Statement)
+	switch {
+	case len(stmts) == 0:
+	case stmts[0] == '{':
+		doc.document.Statement = []json.RawMessage{stmts}
+	default:
+		if err := json.Unmarshal(stmts, &doc.document.Statement); err != nil {
+			return nil, fmt.Errorf("parsing policy statements: %w", err)
+		}
+	}
+
+	return &doc.document, nil
+}
+
+// index returns the index of the statement with the given Sid, or -1 if the
+// document doesn't contain such statement.
+func (d *document) index(sid string) int {
+	for i, raw := range d.Statement {
+		var stmt struct {
+			Sid string `json:"Sid"`
+		}
+		if err := json.Unmarshal(raw, &stmt); err != nil {
+			continue
+		}
+		if stmt.Sid == sid {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// MergeStatement adds the given statement to the serialized policy, or
+// replaces the statement which has the same Sid. Statements created before
+// Sids were stable, which only differ from the given statement by their random
+// UUID Sid, are superseded by it and removed. Other statements are left
+// untouched. The returned boolean value indicates whether the policy was
+// modified.
+func MergeStatement(policy string, stmt PolicyStatement) (string, bool, error) {
+	if stmt.Sid == "" {
+		return "", false, errors.New("statement has no Sid")
+	}
+
+	doc, err := parseDocument(policy)
+	if err != nil {
+		return "", false, err
+	}
+
+	stmtJSON, err := json.Marshal(stmt)
+	if err != nil {
+		return "", false, fmt.Errorf("serializing policy statement: %w", err)
+	}
+
+	changed := false
+
+	stmts := doc.Statement[:0]
+	for _, raw := range doc.Statement {
+		if supersedes(stmt, raw) {
+			changed = true
+			continue
+		}
+		stmts = append(stmts, raw)
+	}
+	doc.Statement = stmts
+
+	i := doc.index(stmt.Sid)
+	switch {
+	case i < 0:
+		doc.Statement = append(doc.Statement, stmtJSON)
+		changed = true
+	case !equalStatement(doc.Statement[i], stmt):
+		doc.Statement[i] = stmtJSON
+		changed = true
+	}
+
+	if !changed {
+		return policy, false, nil
+	}
+
+	polJSON, err := json.Marshal(doc)
+	if err != nil {
+		return "", false, fmt.Errorf("serializing policy: %w", err)
+	}
+
+	return string(polJSON), true, nil
+}
+
+// RemoveStatement removes the statement with the given Sid from the
+// serialized policy. Other statements are left untouched. The returned
+// boolean value indicates whether the policy was modified. If the policy
+// doesn't contain any statement after the removal, an empty string is
+// returned.
+func RemoveStatement(policy, sid string) (string, bool, error) {
+	doc, err := parseDocument(policy)
+	if err != nil {
+		return "", false, err
+	}
+
+	i := doc.index(sid)
+	if i < 0 {
+		return policy, false, nil
+	}
+
+	doc.Statement = append(doc.Statement[:i], doc.Statement[i+1:]...)
+	if len(doc.Statement) == 0 {
+		return "", true, nil
+	}
+
+	polJSON, err := json.Marshal(doc)
+	if err != nil {
+		return "", false, fmt.Errorf("serializing policy: %w", err)
+	}
+
+	return string(polJSON), true, nil
+}
+
+// equalStatement returns whether a serialized statement is semantically
+// equal to the given statement.
+func equalStatement(raw json.RawMessage, stmt PolicyStatement) bool {
+	var cur PolicyStatement
+	if err := json.Unmarshal(raw, &cur); err != nil {
+		return false
+	}
+
+	return EqualStatements(cur, stmt)
+}
+
+// supersedes returns whether the given statement supersedes a serialized
+// statement which was created before Sids were stable. Such statements have a
+// random UUID as Sid, and are otherwise identical to the given statement.
+func supersedes(stmt PolicyStatement, raw json.RawMessage) bool {
+	var cur PolicyStatement
+	if err := json.Unmarshal(raw, &cur); err != nil {
+		return false
+	}
+
+	if !isLegacySid(cur.Sid) || cur.Sid == stmt.Sid {
+		return false
+	}
+
+	cur.Sid = stmt.Sid
+	return EqualStatements(cur, stmt)
+}
+
+// isLegacySid returns whether the given Sid has the format of the random
+// UUIDs which were assigned to statements before Sids were stable.
+func isLegacySid(sid string) bool {
+	id, err := uuid.Parse(sid)
+	return err == nil && id.String() == sid
+}
@@ -0,0 +1,183 @@
+/*
+Copyright 2023 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package iam
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMergeStatement(t *testing.T) {
+	const (
+		otherStmt = `{"Sid":"other-team","Effect":"Allow","Principal":{"AWS":"arn:aws:iam::123456789012:root"},` +
+			`"Action":"sqs:*","Resource":"arn:aws:sqs:eu-west-1:123456789012:my-queue"}`
+		ownStmt = `{"Sid":"own","Effect":"Allow","Principal":{"Service":["s3.amazonaws.com"]},` +
+			`"Action":["sqs:SendMessage"],"Resource":["arn:aws:sqs:eu-west-1:123456789012:my-queue"],` +
+			`"Condition":{"ArnEquals":{"aws:SourceArn":["arn:aws:s3:::my-bucket"]}}}`
+		staleStmt = `{"Sid":"own","Effect":"Allow","Principal":{"Service":["s3.amazonaws.com"]},` +
+			`"Action":["sqs:SendMessage"],"Resource":["arn:aws:sqs:eu-west-1:123456789012:old-queue"],` +
+			`"Condition":{"ArnEquals":{"aws:SourceArn":["arn:aws:s3:::my-bucket"]}}}`
+		legacyStmt = `{"Sid":"0d9a0cf5-5ce5-4d3e-9b35-b3e4b1e1d6c2","Effect":"Allow","Principal":{"Service":"s3.amazonaws.com"},` +
+			`"Action":"sqs:SendMessage","Resource":"arn:aws:sqs:eu-west-1:123456789012:my-queue",` +
+			`"Condition":{"ArnEquals":{"aws:SourceArn":"arn:aws:s3:::my-bucket"}}}`
+		foreignSidStmt = `{"Sid":"other-source","Effect":"Allow","Principal":{"Service":"s3.amazonaws.com"},` +
+			`"Action":"sqs:SendMessage","Resource":"arn:aws:sqs:eu-west-1:123456789012:my-queue",` +
+			`"Condition":{"ArnEquals":{"aws:SourceArn":"arn:aws:s3:::my-bucket"}}}`
+		foreignResourceStmt = `{"Sid":"7b0e5a9c-1f4d-4c2e-8a6b-9d3f2e1c0b5a","Effect":"Allow","Principal":{"Service":"s3.amazonaws.com"},` +
+			`"Action":"sqs:SendMessage","Resource":"arn:aws:sqs:eu-west-1:123456789012:other-queue",` +
+			`"Condition":{"ArnEquals":{"aws:SourceArn":"arn:aws:s3:::my-bucket"}}}`
+		foreignConditionStmt = `{"Sid":"5e2d8c4b-9a1f-4e7d-b3c6-0f8a2d4e6c1b","Effect":"Allow","Principal":{"Service":"s3.amazonaws.com"},` +
+			`"Action":"sqs:SendMessage","Resource":"arn:aws:sqs:eu-west-1:123456789012:my-queue",` +
+			`"Condition":{"ArnEquals":{"aws:SourceArn":"arn:aws:s3:::my-bucket"},"StringEquals":{"aws:SourceAccount":"123456789012"}}}`
+		otherBucketStmt = `{"Sid":"3c1e8f0a-2b7d-4a55-8f6e-5a0c9d2e7b41","Effect":"Allow","Principal":{"Service":"s3.amazonaws.com"},` +
+			`"Action":"sqs:SendMessage","Resource":"arn:aws:sqs:eu-west-1:123456789012:my-queue",` +
+			`"Condition":{"ArnEquals":{"aws:SourceArn":"arn:aws:s3:::other-bucket"}}}`
+	)
+
+	stmt := NewPolicyStatement(EffectAllow,
+		Sid("own"),
+		PrincipalService("s3.amazonaws.com"),
+		Action("sqs:SendMessage"),
+		Resource("arn:aws:sqs:eu-west-1:123456789012:my-queue"),
+		ConditionArnEquals("aws:SourceArn", "arn:aws:s3:::my-bucket"),
+	)
+
+	testCases := map[string]struct {
+		policy        string
+		expectPolicy  string
+		expectChanged bool
+	}{
+		"empty policy": {
+			policy:        "",
+			expectPolicy:  `{"Version":"2012-10-17","Statement":[` + ownStmt + `]}`,
+			expectChanged: true,
+		},
+		"statement added": {
+			policy:        `{"Version":"2012-10-17","Id":"pol","Statement":[` + otherStmt + `]}`,
+			expectPolicy:  `{"Version":"2012-10-17","Id":"pol","Statement":[` + otherStmt + `,` + ownStmt + `]}`,
+			expectChanged: true,
+		},
+		"single statement object": {
+			policy:        `{"Version":"2012-10-17","Statement":` + otherStmt + `}`,
+			expectPolicy:  `{"Version":"2012-10-17","Statement":[` + otherStmt + `,` + ownStmt + `]}`,
+			expectChanged: true,
+		},
+		"statement replaced": {
+			policy:        `{"Version":"2012-10-17","Statement":[` + staleStmt + `,` + otherStmt + `]}`,
+			expectPolicy:  `{"Version":"2012-10-17","Statement":[` + ownStmt + `,` + otherStmt + `]}`,
+			expectChanged: true,
+		},
+		"statement up to date": {
+			policy:       `{"Version":"2012-10-17","Statement":[` + otherStmt + `,` + ownStmt + `]}`,
+			expectPolicy: `{"Version":"2012-10-17","Statement":[` + otherStmt + `,` + ownStmt + `]}`,
+		},
+		"legacy statement superseded": {
+			policy:        `{"Version":"2012-10-17","Statement":[` + legacyStmt + `,` + otherStmt + `]}`,
+			expectPolicy:  `{"Version":"2012-10-17","Statement":[` + otherStmt + `,` + ownStmt + `]}`,
+			expectChanged: true,
+		},
+		"legacy statement superseded while up to date": {
+			policy:        `{"Version":"2012-10-17","Statement":[` + ownStmt + `,` + legacyStmt + `]}`,
+			expectPolicy:  `{"Version":"2012-10-17","Statement":[` + ownStmt + `]}`,
+			expectChanged: true,
+		},
+		"statement with other Sid preserved": {
+			policy:       `{"Version":"2012-10-17","Statement":[` + foreignSidStmt + `,` + ownStmt + `]}`,
+			expectPolicy: `{"Version":"2012-10-17","Statement":[` + foreignSidStmt + `,` + ownStmt + `]}`,
+		},
+		"statement with other Resource preserved": {
+			policy:       `{"Version":"2012-10-17","Statement":[` + foreignResourceStmt + `,` + ownStmt + `]}`,
+			expectPolicy: `{"Version":"2012-10-17","Statement":[` + foreignResourceStmt + `,` + ownStmt + `]}`,
+		},
+		"statement with other Condition preserved": {
+			policy:       `{"Version":"2012-10-17","Statement":[` + foreignConditionStmt + `,` + ownStmt + `]}`,
+			expectPolicy: `{"Version":"2012-10-17","Statement":[` + foreignConditionStmt + `,` + ownStmt + `]}`,
+		},
+		"statement for another source preserved": {
+			policy:       `{"Version":"2012-10-17","Statement":[` + otherBucketStmt + `,` + ownStmt + `]}`,
+			expectPolicy: `{"Version":"2012-10-17","Statement":[` + otherBucketStmt + `,` + ownStmt + `]}`,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			pol, changed, err := MergeStatement(tc.policy, stmt)
+			require.NoError(t, err)
+
+			assert.Equal(t, tc.expectChanged, changed)
+			assert.JSONEq(t, tc.expectPolicy, pol)
+		})
+	}
+
+	t.Run("statement without Sid", func(t *testing.T) {
+		_, _, err := MergeStatement("", PolicyStatement{Effect: EffectAllow})
+		assert.Error(t, err)
+	})
+
+	t.Run("invalid policy", func(t *testing.T) {
+		_, _, err := MergeStatement("{", stmt)
+		assert.Error(t, err)
+	})
+}
+
+func TestRemoveStatement(t *testing.T) {
+	const (
+		otherStmt = `{"Sid":"other-team","Effect":"Allow","Principal":"*","Action":"sqs:*","Resource":"*"}`
+		ownStmt   = `{"Sid":"own","Effect":"Allow","Principal":{"Service":["s3.amazonaws.com"]},"Action":["sqs:SendMessage"],"Resource":["*"]}`
+	)
+
+	testCases := map[string]struct {
+		policy        string
+		expectPolicy  string
+		expectChanged bool
+	}{
+		"statement removed": {
+			policy:        `{"Version":"2012-10-17","Statement":[` + ownStmt + `,` + otherStmt + `]}`,
+			expectPolicy:  `{"Version":"2012-10-17","Statement":[` + otherStmt + `]}`,
+			expectChanged: true,
+		},
+		"last statement removed": {
+			policy:        `{"Version":"2012-10-17","Statement":[` + ownStmt + `]}`,
+			expectPolicy:  "",
+			expectChanged: true,
+		},
+		"statement not found": {
+			policy:       `{"Version":"2012-10-17","Statement":[` + otherStmt + `]}`,
+			expectPolicy: `{"Version":"2012-10-17","Statement":[` + otherStmt + `]}`,
+		},
+		"empty policy": {
+			policy:       "",
+			expectPolicy: "",
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			pol, changed, err := RemoveStatement(tc.policy, "own")
+			require.NoError(t, err)
+
+			assert.Equal(t, tc.expectChanged, changed)
+			if tc.expectPolicy == "" {
+				assert.Empty(t, pol)
+				return
+			}
+			assert.JSONEq(t, tc.expectPolicy, pol)
+		})
+	}
+}
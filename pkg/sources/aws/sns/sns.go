@@ -0,0 +1,185 @@
+/*
+Copyright 2023 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package sns contains helpers for AWS SNS.
+package sns
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/sns"
+	"github.com/aws/aws-sdk-go/service/sns/snsiface"
+)
+
+// CreateTopic creates a topic with the given name and optional tags. The
+// operation is idempotent, and returns the ARN of the existing topic when a
+// topic with the same name and tags already exists.
+//
+// Naming restrictions are described at https://docs.aws.amazon.com/sns/latest/api/API_CreateTopic.html
+func CreateTopic(ctx context.Context, cli snsiface.SNSAPI, name string, tags map[string]string) (string /*arn*/, error) {
+	topic := &sns.CreateTopicInput{
+		Name: &name,
+	}
+	for k, v := range tags {
+		topic.Tags = append(topic.Tags, &sns.Tag{Key: aws.String(k), Value: aws.String(v)})
+	}
+
+	resp, err := cli.CreateTopicWithContext(ctx, topic)
+	if err != nil {
+		return "", fmt.Errorf("creating topic %q: %w", *topic.Name, err)
+	}
+
+	return *resp.TopicArn, nil
+}
+
+// DeleteTopic deletes the topic with the given ARN, along with its
+// subscriptions.
+func DeleteTopic(ctx context.Context, cli snsiface.SNSAPI, arn string) error {
+	topic := &sns.DeleteTopicInput{
+		TopicArn: &arn,
+	}
+
+	if _, err := cli.DeleteTopicWithContext(ctx, topic); err != nil {
+		return fmt.Errorf("deleting topic %q: %w", *topic.TopicArn, err)
+	}
+
+	return nil
+}
+
+// TopicPolicy returns the policy of the topic with the given ARN.
+func TopicPolicy(ctx context.Context, cli snsiface.SNSAPI, arn string) (string /*policy*/, error) {
+	attribs := &sns.GetTopicAttributesInput{
+		TopicArn: &arn,
+	}
+
+	resp, err := cli.GetTopicAttributesWithContext(ctx, attribs)
+	if err != nil {
+		return "", fmt.Errorf("getting attributes of topic %q: %w", *attribs.TopicArn, err)
+	}
+
+	return aws.StringValue(resp.Attributes["Policy"]), nil
+}
+
+// SetTopicPolicy sets the Policy attribute of the topic with the given ARN.
+//
+// See also https://docs.aws.amazon.com/sns/latest/dg/sns-access-policy-use-cases.html
+func SetTopicPolicy(ctx context.Context, cli snsiface.SNSAPI, arn, policy string) error {
+	attrs := &sns.SetTopicAttributesInput{
+		TopicArn:       &arn,
+		AttributeName:  aws.String("Policy"),
+		AttributeValue: &policy,
+	}
+
+	if _, err := cli.SetTopicAttributesWithContext(ctx, attrs); err != nil {
+		return fmt.Errorf("setting attributes of topic %q: %w", *attrs.TopicArn, err)
+	}
+
+	return nil
+}
+
+// TopicTags returns the tags of the topic with the given ARN.
+func TopicTags(ctx context.Context, cli snsiface.SNSAPI, arn string) (map[string]string, error) {
+	topic := &sns.ListTagsForResourceInput{
+		ResourceArn: &arn,
+	}
+
+	resp, err := cli.ListTagsForResourceWithContext(ctx, topic)
+	if err != nil {
+		return nil, fmt.Errorf("listing tags of topic %q: %w", *topic.ResourceArn, err)
+	}
+
+	tags := make(map[string]string, len(resp.Tags))
+	for _, t := range resp.Tags {
+		tags[aws.StringValue(t.Key)] = aws.StringValue(t.Value)
+	}
+
+	return tags, nil
+}
+
+// SubscribeQueue subscribes the SQS queue with the given ARN to the topic with
+// the given ARN. Messages are delivered raw, without the SNS envelope. The
+// operation is idempotent.
+func SubscribeQueue(ctx context.Context, cli snsiface.SNSAPI, topicARN, queueARN string) (string /*arn*/, error) {
+	sub := &sns.SubscribeInput{
+		TopicArn:              &topicARN,
+		Protocol:              aws.String("sqs"),
+		Endpoint:              &queueARN,
+		ReturnSubscriptionArn: aws.Bool(true),
+	}
+
+	resp, err := cli.SubscribeWithContext(ctx, sub)
+	if err != nil {
+		return "", fmt.Errorf("subscribing queue %q to topic %q: %w", *sub.Endpoint, *sub.TopicArn, err)
+	}
+
+	attrs := &sns.SetSubscriptionAttributesInput{
+		SubscriptionArn: resp.SubscriptionArn,
+		AttributeName:   aws.String("RawMessageDelivery"),
+		AttributeValue:  aws.String("true"),
+	}
+
+	if _, err := cli.SetSubscriptionAttributesWithContext(ctx, attrs); err != nil {
+		return "", fmt.Errorf("setting attributes of subscription %q: %w", *attrs.SubscriptionArn, err)
+	}
+
+	return *resp.SubscriptionArn, nil
+}
+
+// QueueSubscriptions returns the ARNs of the subscriptions of the SQS queue
+// with the given ARN to the topic with the given ARN.
+func QueueSubscriptions(ctx context.Context, cli snsiface.SNSAPI, topicARN, queueARN string) ([]string /*arn*/, error) {
+	var subARNs []string
+
+	subs := &sns.ListSubscriptionsByTopicInput{
+		TopicArn: &topicARN,
+	}
+
+	err := cli.ListSubscriptionsByTopicPagesWithContext(ctx, subs, func(page *sns.ListSubscriptionsByTopicOutput, _ bool) bool {
+		for _, s := range page.Subscriptions {
+			if aws.StringValue(s.Protocol) == "sqs" && aws.StringValue(s.Endpoint) == queueARN {
+				subARNs = append(subARNs, *s.SubscriptionArn)
+			}
+		}
+		return true
+	})
+	if err != nil {
+		return nil, fmt.Errorf("listing subscriptions of topic %q: %w", *subs.TopicArn, err)
+	}
+
+	return subARNs, nil
+}
+
+// UnsubscribeQueue removes the subscriptions of the SQS queue with the given
+// ARN from the topic with the given ARN.
+func UnsubscribeQueue(ctx context.Context, cli snsiface.SNSAPI, topicARN, queueARN string) error {
+	subARNs, err := QueueSubscriptions(ctx, cli, topicARN, queueARN)
+	if err != nil {
+		return err
+	}
+
+	for _, arn := range subARNs {
+		unsub := &sns.UnsubscribeInput{
+			SubscriptionArn: aws.String(arn),
+		}
+		if _, err := cli.UnsubscribeWithContext(ctx, unsub); err != nil {
+			return fmt.Errorf("deleting subscription %q: %w", *unsub.SubscriptionArn, err)
+		}
+	}
+
+	return nil
+}
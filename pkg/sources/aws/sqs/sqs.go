@@ -18,26 +18,27 @@ limitations under the License.
 package sqs
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
+	"strconv"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/sqs"
 	"github.com/aws/aws-sdk-go/service/sqs/sqsiface"
-
-	"github.com/triggermesh/scoby-hook-triggermesh/pkg/sources/aws/iam"
 )
 
-// CreateQueue creates a queue with the given name and optional tags.
+// CreateQueue creates a queue with the given name and optional attributes and
+// tags.
 //
 // Naming restrictions are described at https://docs.aws.amazon.com/AWSSimpleQueueService/latest/APIReference/API_CreateQueue.html
-func CreateQueue(cli sqsiface.SQSAPI, name string, tags map[string]string) (string /*url*/, error) {
+func CreateQueue(ctx context.Context, cli sqsiface.SQSAPI, name string, attrs, tags map[string]string) (string /*url*/, error) {
 	queue := &sqs.CreateQueueInput{
-		QueueName: &name,
-		Tags:      aws.StringMap(tags),
+		QueueName:  &name,
+		Attributes: aws.StringMap(attrs),
+		Tags:       aws.StringMap(tags),
 	}
 
-	resp, err := cli.CreateQueue(queue)
+	resp, err := cli.CreateQueueWithContext(ctx, queue)
 	if err != nil {
 		return "", fmt.Errorf("creating queue %q: %w", *queue.QueueName, err)
 	}
@@ -46,35 +47,48 @@ func CreateQueue(cli sqsiface.SQSAPI, name string, tags map[string]string) (stri
 }
 
 // SetQueuePolicy sets the Policy attribute of the queue with the given URL.
+// An empty policy removes the existing one.
 //
 // See also https://docs.aws.amazon.com/AWSSimpleQueueService/latest/SQSDeveloperGuide/sqs-authentication-and-access-control.html
-func SetQueuePolicy(cli sqsiface.SQSAPI, url string, pol iam.Policy) error {
-	polJSON, err := json.Marshal(pol)
-	if err != nil {
-		return fmt.Errorf("serializing queue policy to JSON: %w", err)
-	}
-
+func SetQueuePolicy(ctx context.Context, cli sqsiface.SQSAPI, url, policy string) error {
 	attrs := &sqs.SetQueueAttributesInput{
 		QueueUrl: &url,
 		Attributes: aws.StringMap(map[string]string{
-			sqs.QueueAttributeNamePolicy: string(polJSON),
+			sqs.QueueAttributeNamePolicy: policy,
 		}),
 	}
 
-	if _, err := cli.SetQueueAttributes(attrs); err != nil {
+	if _, err := cli.SetQueueAttributesWithContext(ctx, attrs); err != nil {
 		return fmt.Errorf("setting attributes of queue %q: %w", *attrs.QueueUrl, err)
 	}
 
 	return nil
 }
 
+// SetQueueAttributes sets the given attributes of the queue with the given
+// URL.
+//
+// See also https://docs.aws.amazon.com/AWSSimpleQueueService/latest/APIReference/API_SetQueueAttributes.html
+func SetQueueAttributes(ctx context.Context, cli sqsiface.SQSAPI, url string, attrs map[string]string) error {
+	in := &sqs.SetQueueAttributesInput{
+		QueueUrl:   &url,
+		Attributes: aws.StringMap(attrs),
+	}
+
+	if _, err := cli.SetQueueAttributesWithContext(ctx, in); err != nil {
+		return fmt.Errorf("setting attributes of queue %q: %w", *in.QueueUrl, err)
+	}
+
+	return nil
+}
+
 // DeleteQueue deletes the queue with the given URL.
-func DeleteQueue(cli sqsiface.SQSAPI, url string) error {
+func DeleteQueue(ctx context.Context, cli sqsiface.SQSAPI, url string) error {
 	queue := &sqs.DeleteQueueInput{
 		QueueUrl: &url,
 	}
 
-	if _, err := cli.DeleteQueue(queue); err != nil {
+	if _, err := cli.DeleteQueueWithContext(ctx, queue); err != nil {
 		return fmt.Errorf("deleting queue %q: %w", *queue.QueueUrl, err)
 	}
 
@@ -82,7 +96,7 @@ func DeleteQueue(cli sqsiface.SQSAPI, url string) error {
 }
 
 // QueuePolicy returns the policy of the queue with the given URL.
-func QueuePolicy(cli sqsiface.SQSAPI, url string) (string /*policy*/, error) {
+func QueuePolicy(ctx context.Context, cli sqsiface.SQSAPI, url string) (string /*policy*/, error) {
 	attribs := &sqs.GetQueueAttributesInput{
 		QueueUrl: &url,
 		AttributeNames: aws.StringSlice([]string{
@@ -90,17 +104,17 @@ func QueuePolicy(cli sqsiface.SQSAPI, url string) (string /*policy*/, error) {
 		}),
 	}
 
-	resp, err := cli.GetQueueAttributes(attribs)
+	resp, err := cli.GetQueueAttributesWithContext(ctx, attribs)
 	if err != nil {
 		return "", fmt.Errorf("getting attributes of queue %q: %w", *attribs.QueueUrl, err)
 	}
 
-	return *resp.Attributes[sqs.QueueAttributeNamePolicy], nil
+	return aws.StringValue(resp.Attributes[sqs.QueueAttributeNamePolicy]), nil
 }
 
 // QueueARN returns the ARN of the queue with the given URL.
-func QueueARN(cli sqsiface.SQSAPI, url string) (string /*arn*/, error) {
-	attrs, err := QueueAttributes(cli, url, []string{sqs.QueueAttributeNameQueueArn})
+func QueueARN(ctx context.Context, cli sqsiface.SQSAPI, url string) (string /*arn*/, error) {
+	attrs, err := QueueAttributes(ctx, cli, url, []string{sqs.QueueAttributeNameQueueArn})
 	if err != nil {
 		return "", fmt.Errorf("getting ARN attribute: %w", err)
 	}
@@ -109,13 +123,13 @@ func QueueARN(cli sqsiface.SQSAPI, url string) (string /*arn*/, error) {
 }
 
 // QueueAttributes returns selected attributes of the queue with the given URL.
-func QueueAttributes(cli sqsiface.SQSAPI, url string, attrs []string) (map[string]string, error) {
+func QueueAttributes(ctx context.Context, cli sqsiface.SQSAPI, url string, attrs []string) (map[string]string, error) {
 	attribs := &sqs.GetQueueAttributesInput{
 		QueueUrl:       &url,
 		AttributeNames: aws.StringSlice(attrs),
 	}
 
-	resp, err := cli.GetQueueAttributes(attribs)
+	resp, err := cli.GetQueueAttributesWithContext(ctx, attribs)
 	if err != nil {
 		return nil, fmt.Errorf("getting attributes of queue %q: %w", *attribs.QueueUrl, err)
 	}
@@ -124,12 +138,12 @@ func QueueAttributes(cli sqsiface.SQSAPI, url string, attrs []string) (map[strin
 }
 
 // QueueURL returns the URL of the queue identified by name.
-func QueueURL(cli sqsiface.SQSAPI, name string) (string /*url*/, error) {
+func QueueURL(ctx context.Context, cli sqsiface.SQSAPI, name string) (string /*url*/, error) {
 	queue := &sqs.GetQueueUrlInput{
 		QueueName: &name,
 	}
 
-	resp, err := cli.GetQueueUrl(queue)
+	resp, err := cli.GetQueueUrlWithContext(ctx, queue)
 	if err != nil {
 		return "", fmt.Errorf("getting URL of queue %q: %w", *queue.QueueName, err)
 	}
@@ -138,15 +152,95 @@ func QueueURL(cli sqsiface.SQSAPI, name string) (string /*url*/, error) {
 }
 
 // QueueTags returns the tags of the queue with the given URL.
-func QueueTags(cli sqsiface.SQSAPI, url string) (map[string]string, error) {
+func QueueTags(ctx context.Context, cli sqsiface.SQSAPI, url string) (map[string]string, error) {
 	queue := &sqs.ListQueueTagsInput{
 		QueueUrl: &url,
 	}
 
-	resp, err := cli.ListQueueTags(queue)
+	resp, err := cli.ListQueueTagsWithContext(ctx, queue)
 	if err != nil {
 		return nil, fmt.Errorf("listing tags of queue %q: %w", *queue.QueueUrl, err)
 	}
 
 	return aws.StringValueMap(resp.Tags), nil
 }
+
+// ReceiveMessages receives up to 10 messages from the queue with the given
+// URL. Long polling is used with a short wait time, so that all the servers
+// of the queue are queried and an empty result means that the queue contains
+// no visible message.
+//
+// See also https://docs.aws.amazon.com/AWSSimpleQueueService/latest/SQSDeveloperGuide/sqs-short-and-long-polling.html
+func ReceiveMessages(ctx context.Context, cli sqsiface.SQSAPI, url string) ([]*sqs.Message, error) {
+	queue := &sqs.ReceiveMessageInput{
+		QueueUrl:              &url,
+		MaxNumberOfMessages:   aws.Int64(10),
+		WaitTimeSeconds:       aws.Int64(1),
+		AttributeNames:        aws.StringSlice([]string{sqs.QueueAttributeNameAll}),
+		MessageAttributeNames: aws.StringSlice([]string{sqs.QueueAttributeNameAll}),
+	}
+
+	resp, err := cli.ReceiveMessageWithContext(ctx, queue)
+	if err != nil {
+		return nil, fmt.Errorf("receiving messages from queue %q: %w", *queue.QueueUrl, err)
+	}
+
+	return resp.Messages, nil
+}
+
+// SendMessages sends a batch of up to 10 messages to the queue with the given
+// URL. The body and attributes of the messages are preserved.
+func SendMessages(ctx context.Context, cli sqsiface.SQSAPI, url string, msgs []*sqs.Message) error {
+	batch := &sqs.SendMessageBatchInput{
+		QueueUrl: &url,
+		Entries:  make([]*sqs.SendMessageBatchRequestEntry, len(msgs)),
+	}
+
+	for i, msg := range msgs {
+		batch.Entries[i] = &sqs.SendMessageBatchRequestEntry{
+			Id:                aws.String(strconv.Itoa(i)),
+			MessageBody:       msg.Body,
+			MessageAttributes: msg.MessageAttributes,
+		}
+	}
+
+	resp, err := cli.SendMessageBatchWithContext(ctx, batch)
+	if err != nil {
+		return fmt.Errorf("sending messages to queue %q: %w", *batch.QueueUrl, err)
+	}
+	if len(resp.Failed) > 0 {
+		f := resp.Failed[0]
+		return fmt.Errorf("sending %d message(s) to queue %q: %s: %s",
+			len(resp.Failed), *batch.QueueUrl, aws.StringValue(f.Code), aws.StringValue(f.Message))
+	}
+
+	return nil
+}
+
+// DeleteMessages deletes a batch of up to 10 received messages from the queue
+// with the given URL.
+func DeleteMessages(ctx context.Context, cli sqsiface.SQSAPI, url string, msgs []*sqs.Message) error {
+	batch := &sqs.DeleteMessageBatchInput{
+		QueueUrl: &url,
+		Entries:  make([]*sqs.DeleteMessageBatchRequestEntry, len(msgs)),
+	}
+
+	for i, msg := range msgs {
+		batch.Entries[i] = &sqs.DeleteMessageBatchRequestEntry{
+			Id:            aws.String(strconv.Itoa(i)),
+			ReceiptHandle: msg.ReceiptHandle,
+		}
+	}
+
+	resp, err := cli.DeleteMessageBatchWithContext(ctx, batch)
+	if err != nil {
+		return fmt.Errorf("deleting messages from queue %q: %w", *batch.QueueUrl, err)
+	}
+	if len(resp.Failed) > 0 {
+		f := resp.Failed[0]
+		return fmt.Errorf("deleting %d message(s) from queue %q: %s: %s",
+			len(resp.Failed), *batch.QueueUrl, aws.StringValue(f.Code), aws.StringValue(f.Message))
+	}
+
+	return nil
+}
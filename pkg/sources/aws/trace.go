@@ -0,0 +1,93 @@
+/*
+Copyright 2023 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aws
+
+import (
+	"context"
+
+	awscore "github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"go.opencensus.io/trace"
+)
+
+const (
+	startSpanHandlerName = "triggermesh.tracing.StartSpan"
+	endSpanHandlerName   = "triggermesh.tracing.EndSpan"
+)
+
+// spanKey is the context key for the span of an AWS API request.
+type spanKey struct{}
+
+// InstrumentSession adds request handlers to the given session so that each
+// call to the AWS API is recorded as a trace span. The span is a child of the
+// span contained in the request's context, which is populated by the
+// *WithContext variants of AWS API methods.
+//
+// The span covers every attempt made for a single call, including retries.
+func InstrumentSession(sess *session.Session) *session.Session {
+	sess.Handlers.Build.PushFrontNamed(request.NamedHandler{
+		Name: startSpanHandlerName,
+		Fn:   startSpan,
+	})
+	sess.Handlers.Complete.PushBackNamed(request.NamedHandler{
+		Name: endSpanHandlerName,
+		Fn:   endSpan,
+	})
+
+	return sess
+}
+
+// startSpan starts a span for the given AWS API request.
+func startSpan(r *request.Request) {
+	ctx, span := trace.StartSpan(r.Context(), r.ClientInfo.ServiceID+"."+r.Operation.Name,
+		trace.WithSpanKind(trace.SpanKindClient))
+
+	span.AddAttributes(
+		trace.StringAttribute("aws.service", r.ClientInfo.ServiceID),
+		trace.StringAttribute("aws.operation", r.Operation.Name),
+		trace.StringAttribute("aws.region", awscore.StringValue(r.Config.Region)),
+	)
+
+	r.SetContext(context.WithValue(ctx, spanKey{}, span))
+}
+
+// endSpan ends the span started for the given AWS API request, if any.
+func endSpan(r *request.Request) {
+	span, ok := r.Context().Value(spanKey{}).(*trace.Span)
+	if !ok {
+		return
+	}
+	defer span.End()
+
+	if r.RequestID != "" {
+		span.AddAttributes(trace.StringAttribute("aws.request_id", r.RequestID))
+	}
+	if r.HTTPResponse != nil {
+		span.AddAttributes(trace.Int64Attribute("http.status_code", int64(r.HTTPResponse.StatusCode)))
+	}
+	span.AddAttributes(trace.Int64Attribute("aws.retry_count", int64(r.RetryCount)))
+
+	if r.Error != nil {
+		msg := r.Error.Error()
+		if awsErr, ok := r.Error.(awserr.Error); ok {
+			msg = awsErr.Code() + ": " + awsErr.Message()
+		}
+		span.SetStatus(trace.Status{Code: trace.StatusCodeUnknown, Message: msg})
+	}
+}
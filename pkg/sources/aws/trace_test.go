@@ -0,0 +1,89 @@
+/*
+Copyright 2023 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aws
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	awscore "github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/sts"
+	"go.opencensus.io/trace"
+)
+
+type spanRecorder struct {
+	mu    sync.Mutex
+	spans []*trace.SpanData
+}
+
+func (r *spanRecorder) ExportSpan(s *trace.SpanData) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.spans = append(r.spans, s)
+}
+
+func TestInstrumentSession(t *testing.T) {
+	const callerIdentityResponse = `<GetCallerIdentityResponse xmlns="https://sts.amazonaws.com/doc/2011-06-15/">
+  <GetCallerIdentityResult>
+    <Arn>arn:aws:iam::123456789012:user/fake</Arn>
+    <UserId>AIDAFAKE</UserId>
+    <Account>123456789012</Account>
+  </GetCallerIdentityResult>
+  <ResponseMetadata>
+    <RequestId>fake-request-id</RequestId>
+  </ResponseMetadata>
+</GetCallerIdentityResponse>`
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Amzn-Requestid", "fake-request-id")
+		_, _ = w.Write([]byte(callerIdentityResponse))
+	}))
+	defer srv.Close()
+
+	rec := &spanRecorder{}
+	trace.RegisterExporter(rec)
+	defer trace.UnregisterExporter(rec)
+
+	sess := InstrumentSession(session.Must(session.NewSession(awscore.NewConfig().
+		WithEndpoint(srv.URL).
+		WithRegion("us-east-1").
+		WithCredentials(credentials.NewStaticCredentials("fake", "fake", "")),
+	)))
+
+	ctx, parent := trace.StartSpan(context.Background(), "parent", trace.WithSampler(trace.AlwaysSample()))
+	_, err := sts.New(sess).GetCallerIdentityWithContext(ctx, &sts.GetCallerIdentityInput{})
+	parent.End()
+	require.NoError(t, err)
+
+	require.Len(t, rec.spans, 2, "Expected spans for the AWS call and its parent")
+
+	span := rec.spans[0]
+	assert.Equal(t, "STS.GetCallerIdentity", span.Name)
+	assert.Equal(t, parent.SpanContext().SpanID, span.ParentSpanID)
+	assert.Equal(t, trace.SpanKindClient, span.SpanKind)
+	assert.Equal(t, "us-east-1", span.Attributes["aws.region"])
+	assert.Equal(t, "fake-request-id", span.Attributes["aws.request_id"])
+	assert.Equal(t, int64(http.StatusOK), span.Attributes["http.status_code"])
+}
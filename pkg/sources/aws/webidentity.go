@@ -0,0 +1,94 @@
+/*
+Copyright 2023 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aws
+
+import (
+	"fmt"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	coreclientv1 "k8s.io/client-go/kubernetes/typed/core/v1"
+
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/credentials/stscreds"
+	"github.com/aws/aws-sdk-go/aws/session"
+)
+
+// WebIdentityAudience is the audience of ServiceAccount tokens exchanged for
+// AWS credentials. It matches the audience of the tokens projected into Pods
+// by the EKS Pod Identity Webhook, which IAM OIDC providers are configured
+// to accept.
+const WebIdentityAudience = "sts.amazonaws.com"
+
+// webIdentityTokenExpirationSeconds is the validity period of requested
+// tokens. Tokens are only used to obtain credentials, so they don't need to
+// outlive them.
+const webIdentityTokenExpirationSeconds = 3600
+
+// WebIdentityCredentials returns credentials obtained by assuming the given
+// role with a web identity token issued for the named ServiceAccount.
+// Credentials are refreshed automatically before they expire, using a newly
+// requested token.
+//
+// The configuration of the given session, such as a custom endpoint, applies
+// to requests sent to the STS API.
+func WebIdentityCredentials(sess *session.Session, roleARN string,
+	sas coreclientv1.ServiceAccountInterface, saName string) *credentials.Credentials {
+
+	// AssumeRoleWithWebIdentity requests are authenticated by the token,
+	// not signed
+	stsCli := newSTSClient(sess, credentials.AnonymousCredentials)
+
+	tf := &serviceAccountTokenFetcher{
+		cli:  sas,
+		name: saName,
+	}
+
+	return credentials.NewCredentials(stscreds.NewWebIdentityRoleProviderWithOptions(
+		stsCli, roleARN, DefaultRoleSessionName, tf,
+	))
+}
+
+// serviceAccountTokenFetcher is a stscreds.TokenFetcher which obtains tokens
+// for a ServiceAccount from the Kubernetes TokenRequest API. The requested
+// tokens are equivalent to the ones projected into the Pods which run as
+// that ServiceAccount.
+type serviceAccountTokenFetcher struct {
+	cli  coreclientv1.ServiceAccountInterface
+	name string
+}
+
+var _ stscreds.TokenFetcher = (*serviceAccountTokenFetcher)(nil)
+
+// FetchToken implements stscreds.TokenFetcher.
+func (f *serviceAccountTokenFetcher) FetchToken(ctx credentials.Context) ([]byte, error) {
+	expSeconds := int64(webIdentityTokenExpirationSeconds)
+
+	tr := &authenticationv1.TokenRequest{
+		Spec: authenticationv1.TokenRequestSpec{
+			Audiences:         []string{WebIdentityAudience},
+			ExpirationSeconds: &expSeconds,
+		},
+	}
+
+	tr, err := f.cli.CreateToken(ctx, f.name, tr, metav1.CreateOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("requesting token for ServiceAccount %q: %w", f.name, err)
+	}
+
+	return []byte(tr.Status.Token), nil
+}
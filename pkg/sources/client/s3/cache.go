@@ -0,0 +1,203 @@
+/*
+Copyright 2023 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package s3
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws/session"
+
+	"k8s.io/apimachinery/pkg/types"
+
+	commonv1alpha1 "github.com/triggermesh/scoby-hook-triggermesh/pkg/apis/common/v1alpha1"
+	"github.com/triggermesh/scoby-hook-triggermesh/pkg/apis/sources/v1alpha1"
+)
+
+// sessionEntry is a cached AWS session, along with the bucket metadata
+// resolved while creating it.
+type sessionEntry struct {
+	sess      *session.Session
+	region    string
+	accountID string
+
+	// resourceVersions of the Secrets the session's credentials were
+	// read from, indexed by name
+	secrets map[string]string
+}
+
+// isCurrent returns whether the session's credentials were read from the
+// given versions of Secrets.
+func (e *sessionEntry) isCurrent(secretVersions map[string]string) bool {
+	if len(e.secrets) != len(secretVersions) {
+		return false
+	}
+	for name, ver := range secretVersions {
+		if e.secrets[name] != ver {
+			return false
+		}
+	}
+	return true
+}
+
+// sessionKey identifies the attributes of a source which determine the
+// session and bucket metadata returned by the ClientGetter.
+type sessionKey struct {
+	namespace   string
+	bucket      string
+	queue       string
+	credentials string
+	assumeRoles string
+	endpoint    string
+}
+
+// sessionKeyFor returns the sessionKey of the given source.
+func sessionKeyFor(src *v1alpha1.AWSS3Source) sessionKey {
+	k := sessionKey{
+		namespace: src.Namespace,
+		bucket:    src.Spec.ARN.String(),
+	}
+
+	if dest := src.Spec.Destination; dest != nil && dest.SQS != nil {
+		k.queue = dest.SQS.QueueARN.String()
+	}
+
+	if creds := src.Spec.Auth.Credentials; creds != nil {
+		k.credentials = valueFromFieldKey(creds.AccessKeyID) + "," + valueFromFieldKey(creds.SecretAccessKey)
+	} else if role := src.Spec.Auth.EksIAMRole; role != nil {
+		// role credentials are obtained using the identity of the
+		// source's own ServiceAccount
+		k.credentials = "role:" + role.String() + "," + src.ServiceAccountName()
+	}
+
+	if ep := src.Spec.Endpoint; ep != nil && ep.URL != nil {
+		k.endpoint = ep.URL.String()
+	}
+
+	if roles := src.Spec.Auth.AssumeRoles; len(roles) > 0 {
+		// the serialization of maps is sorted by key, so equal role
+		// chains always produce the same key
+		b, _ := json.Marshal(roles)
+		k.assumeRoles = string(b)
+	}
+
+	return k
+}
+
+// valueFromFieldKey returns a string which identifies the given value. Literal
+// values are hashed to avoid keeping copies of credentials in cache keys.
+func valueFromFieldKey(f commonv1alpha1.ValueFromField) string {
+	if vfs := f.ValueFromSecret; vfs != nil {
+		return "secret:" + vfs.Name + "/" + vfs.Key
+	}
+
+	h := sha256.Sum256([]byte(f.Value))
+	return "value:" + hex.EncodeToString(h[:])
+}
+
+// referencedSecrets returns the names of the Secrets referenced by the
+// credentials of the given source.
+func referencedSecrets(src *v1alpha1.AWSS3Source) []string {
+	creds := src.Spec.Auth.Credentials
+	if creds == nil {
+		return nil
+	}
+
+	var secrets []string
+	for _, f := range []commonv1alpha1.ValueFromField{creds.AccessKeyID, creds.SecretAccessKey} {
+		if vfs := f.ValueFromSecret; vfs != nil {
+			secrets = append(secrets, vfs.Name)
+		}
+	}
+
+	return secrets
+}
+
+// sessionCache is a concurrency-safe cache of AWS sessions.
+//
+// Sessions are evicted as soon as no source uses them anymore, either because
+// the sources which used them were deleted, or because their spec changed.
+type sessionCache struct {
+	m       sync.RWMutex
+	entries map[sessionKey]*sessionEntry
+	// key of the session last used by each source
+	users map[types.NamespacedName]sessionKey
+}
+
+func newSessionCache() *sessionCache {
+	return &sessionCache{
+		entries: make(map[sessionKey]*sessionEntry),
+		users:   make(map[types.NamespacedName]sessionKey),
+	}
+}
+
+func (c *sessionCache) get(k sessionKey) (*sessionEntry, bool) {
+	c.m.RLock()
+	defer c.m.RUnlock()
+
+	e, ok := c.entries[k]
+	return e, ok
+}
+
+func (c *sessionCache) set(k sessionKey, e *sessionEntry) {
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	c.entries[k] = e
+}
+
+// use records that the given source uses the session with the given key, and
+// evicts the session it used previously if no other source uses it.
+func (c *sessionCache) use(src types.NamespacedName, k sessionKey) {
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	prev, ok := c.users[src]
+	c.users[src] = k
+
+	if ok && prev != k {
+		c.evictUnused(prev)
+	}
+}
+
+// forget evicts the session used by the given source if no other source uses
+// it.
+func (c *sessionCache) forget(src types.NamespacedName) {
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	k, ok := c.users[src]
+	if !ok {
+		return
+	}
+	delete(c.users, src)
+
+	c.evictUnused(k)
+}
+
+// evictUnused evicts the session with the given key if no source uses it. The
+// caller must hold the write lock.
+func (c *sessionCache) evictUnused(k sessionKey) {
+	for _, uk := range c.users {
+		if uk == k {
+			return
+		}
+	}
+	delete(c.entries, k)
+}
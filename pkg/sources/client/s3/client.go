@@ -17,23 +17,35 @@ limitations under the License.
 package s3
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"net/http"
 
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
 	coreclientv1 "k8s.io/client-go/kubernetes/typed/core/v1"
 
 	awscore "github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/aws/credentials"
-	"github.com/aws/aws-sdk-go/aws/credentials/stscreds"
 	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/eventbridge"
+	"github.com/aws/aws-sdk-go/service/eventbridge/eventbridgeiface"
 	"github.com/aws/aws-sdk-go/service/s3"
 	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+	"github.com/aws/aws-sdk-go/service/sns"
+	"github.com/aws/aws-sdk-go/service/sns/snsiface"
 	"github.com/aws/aws-sdk-go/service/sqs"
 	"github.com/aws/aws-sdk-go/service/sqs/sqsiface"
 	"github.com/aws/aws-sdk-go/service/sts"
+	"go.opencensus.io/trace"
 
+	commonv1alpha1 "github.com/triggermesh/scoby-hook-triggermesh/pkg/apis/common/v1alpha1"
 	"github.com/triggermesh/scoby-hook-triggermesh/pkg/apis/sources/v1alpha1"
+	"github.com/triggermesh/scoby-hook-triggermesh/pkg/sources/auth"
 	"github.com/triggermesh/scoby-hook-triggermesh/pkg/sources/aws"
+	"github.com/triggermesh/scoby-hook-triggermesh/pkg/sources/errclass"
 )
 
 // Per AWS conventions, a bucket which does not explicitly specify its location
@@ -47,52 +59,178 @@ type Client = s3iface.S3API
 // SQSClient is an alias for the SQSAPI interface.
 type SQSClient = sqsiface.SQSAPI
 
-// ClientGetter can obtain S3 and SQS clients.
+// SNSClient is an alias for the SNSAPI interface.
+type SNSClient = snsiface.SNSAPI
+
+// EventBridgeClient is an alias for the EventBridgeAPI interface.
+type EventBridgeClient = eventbridgeiface.EventBridgeAPI
+
+// Clients contains clients for the APIs involved in the delivery of S3 event
+// notifications.
+type Clients struct {
+	S3          Client
+	SQS         SQSClient
+	SNS         SNSClient
+	EventBridge EventBridgeClient
+}
+
+// ClientGetter can obtain clients for the S3 API and the APIs of intermediate
+// destinations of S3 event notifications.
 type ClientGetter interface {
-	Get(*v1alpha1.AWSS3Source) (Client, SQSClient, error)
+	Get(context.Context, *v1alpha1.AWSS3Source) (*Clients, error)
+	// Forget releases what was retained to obtain the clients of a source
+	// which is being deleted.
+	Forget(*v1alpha1.AWSS3Source)
 }
 
-// NewClientGetter returns a ClientGetter for the given secrets getter.
-func NewClientGetter(sg NamespacedSecretsGetter) *ClientGetterWithSecretGetter {
+// NewClientGetter returns a ClientGetter for the given secrets and service
+// accounts getters.
+func NewClientGetter(sg NamespacedSecretsGetter, sag NamespacedServiceAccountsGetter) *ClientGetterWithSecretGetter {
 	return &ClientGetterWithSecretGetter{
-		sg: sg,
+		sg:    sg,
+		sag:   sag,
+		cache: newSessionCache(),
 	}
 }
 
 // NamespacedSecretsGetter returns a SecretInterface for the given namespace.
 type NamespacedSecretsGetter func(namespace string) coreclientv1.SecretInterface
 
+// NamespacedServiceAccountsGetter returns a ServiceAccountInterface for the
+// given namespace.
+type NamespacedServiceAccountsGetter func(namespace string) coreclientv1.ServiceAccountInterface
+
 // ClientGetterWithSecretGetter gets S3 clients using static credentials
-// retrieved using a Secret getter.
+// retrieved using a Secret getter, or credentials of an EKS IAM role obtained
+// using a token of the source's ServiceAccount.
+//
+// AWS sessions and the bucket metadata resolved while creating them are
+// cached, so that sources which share credentials and a bucket don't cause
+// repeated calls to the AWS APIs. A cached session is re-created when the
+// resourceVersion of a Secret its credentials were read from has changed, and
+// evicted once no source uses it.
 type ClientGetterWithSecretGetter struct {
-	sg NamespacedSecretsGetter
+	sg    NamespacedSecretsGetter
+	sag   NamespacedServiceAccountsGetter
+	cache *sessionCache
 }
 
 // ClientGetterWithSecretGetter implements ClientGetter.
 var _ ClientGetter = (*ClientGetterWithSecretGetter)(nil)
 
 // Get implements ClientGetter.
-func (g *ClientGetterWithSecretGetter) Get(src *v1alpha1.AWSS3Source) (Client, SQSClient, error) {
+func (g *ClientGetterWithSecretGetter) Get(ctx context.Context, src *v1alpha1.AWSS3Source) (*Clients, error) {
+	ctx, span := trace.StartSpan(ctx, "s3.ClientGetter.Get")
+	defer span.End()
+
 	if src.Spec.Auth.Credentials == nil && src.Spec.Auth.EksIAMRole == nil {
-		return nil, nil, errors.New("AWS security credentials were not specified")
+		return nil, auth.NewPermanentCredentialsError(errors.New("AWS security credentials were not specified"))
+	}
+
+	key := sessionKeyFor(src)
+
+	// versions are read before the credentials, so that a change to a
+	// Secret made in between causes the session to be re-created once
+	// more instead of being missed
+	secretVersions, err := g.secretVersions(ctx, src)
+	if err != nil {
+		return nil, fmt.Errorf("retrieving AWS security credentials: %w", err)
+	}
+
+	e, cached := g.cache.get(key)
+	cached = cached && e.isCurrent(secretVersions)
+	span.AddAttributes(trace.BoolAttribute("cache_hit", cached))
+
+	if !cached {
+		if e, err = g.newSession(ctx, src, secretVersions); err != nil {
+			// Credentials which were rejected by AWS won't become
+			// valid by retrying with the same session parameters.
+			return nil, errclass.Permanent(err)
+		}
+		g.cache.set(key, e)
+	}
+	g.cache.use(sourceName(src), key)
+
+	if src.Spec.ARN.Region == "" {
+		src.Spec.ARN.Region = e.region
+	}
+	if src.Spec.ARN.AccountID == "" {
+		src.Spec.ARN.AccountID = e.accountID
+	}
+
+	return &Clients{
+		S3:          s3.New(e.sess),
+		SQS:         sqs.New(e.sess),
+		SNS:         sns.New(e.sess),
+		EventBridge: eventbridge.New(e.sess),
+	}, nil
+}
+
+// Forget implements ClientGetter.
+func (g *ClientGetterWithSecretGetter) Forget(src *v1alpha1.AWSS3Source) {
+	g.cache.forget(sourceName(src))
+}
+
+// sourceName returns the namespaced name of the given source.
+func sourceName(src *v1alpha1.AWSS3Source) types.NamespacedName {
+	return types.NamespacedName{Namespace: src.Namespace, Name: src.Name}
+}
+
+// secretVersions returns the resourceVersion of each Secret referenced by the
+// credentials of the given source, indexed by name.
+func (g *ClientGetterWithSecretGetter) secretVersions(ctx context.Context, src *v1alpha1.AWSS3Source) (map[string]string, error) {
+	names := referencedSecrets(src)
+	if len(names) == 0 {
+		return nil, nil
+	}
+
+	versions := make(map[string]string, len(names))
+	for _, name := range names {
+		if _, ok := versions[name]; ok {
+			continue
+		}
+
+		secr, err := g.sg(src.Namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("getting Secret from cluster: %w", err)
+		}
+		versions[name] = secr.ResourceVersion
 	}
 
-	sess := session.Must(session.NewSession(awscore.NewConfig()))
+	return versions, nil
+}
+
+// newSession returns a session for interacting with the S3 bucket of the
+// given source, along with the bucket's region and owner account.
+func (g *ClientGetterWithSecretGetter) newSession(ctx context.Context, src *v1alpha1.AWSS3Source,
+	secretVersions map[string]string) (*sessionEntry, error) {
+	sess := aws.InstrumentSession(session.Must(session.NewSession(newConfig(src.Spec.Endpoint))))
 
-	var creds *credentials.Value
-	var err error
+	var creds *credentials.Credentials
 	if src.Spec.Auth.Credentials != nil {
-		creds, err = aws.Credentials(g.sg(src.Namespace), src.Spec.Auth.Credentials)
+		credsVal, err := aws.Credentials(g.sg(src.Namespace), src.Spec.Auth.Credentials)
 		if err != nil {
-			return nil, nil, fmt.Errorf("retrieving AWS security credentials: %w", err)
+			return nil, fmt.Errorf("retrieving AWS security credentials: %w", err)
 		}
+		creds = credentials.NewStaticCredentialsFromCreds(*credsVal)
 	} else {
-		iamCreds := stscreds.NewCredentials(sess, src.Spec.Auth.EksIAMRole.String())
-		cred, err := iamCreds.Get()
-		if err != nil {
-			return nil, nil, fmt.Errorf("retrieving AWS IAM Role: %w", err)
+		// The role is assumed with the identity of the source's
+		// ServiceAccount, the same way the adapter assumes it using the
+		// token projected into its Pod. Assumed role credentials are
+		// refreshed by the provider before they expire, which allows
+		// caching the session.
+		creds = aws.WebIdentityCredentials(sess, src.Spec.Auth.EksIAMRole.String(),
+			g.sag(src.Namespace), src.ServiceAccountName())
+		if _, err := creds.GetWithContext(ctx); err != nil {
+			return nil, fmt.Errorf("assuming AWS IAM Role with web identity: %w", err)
+		}
+	}
+
+	if roles := src.Spec.Auth.AssumeRoles; len(roles) > 0 {
+		creds = aws.AssumeRoleChain(sess, creds, roles)
+		if _, err := creds.GetWithContext(ctx); err != nil {
+			return nil, fmt.Errorf("assuming AWS IAM Role %q: %w", roles[len(roles)-1].RoleARN.String(), err)
 		}
-		creds = &cred
 	}
 
 	// The ARN of a S3 bucket differs from other ARNs because it doesn't
@@ -105,27 +243,26 @@ func (g *ClientGetterWithSecretGetter) Get(src *v1alpha1.AWSS3Source) (Client, S
 	// places, we bake the very specific logic of retrieving both the
 	// account ID and region into the ClientGetter for the time being.
 
-	region, err := determineS3Region(src, creds)
+	region, err := determineS3Region(ctx, src, creds)
 	if err != nil {
-		return nil, nil, fmt.Errorf("determining suitable S3 region: %w", err)
-	}
-	if src.Spec.ARN.Region == "" {
-		src.Spec.ARN.Region = region
+		return nil, fmt.Errorf("determining suitable S3 region: %w", err)
 	}
 
-	accID, err := determineBucketOwnerAccount(src, creds)
+	accID, err := determineBucketOwnerAccount(ctx, src, region, creds)
 	if err != nil {
-		return nil, nil, fmt.Errorf("determining bucket's owner: %w", err)
-	}
-	if src.Spec.ARN.AccountID == "" {
-		src.Spec.ARN.AccountID = accID
+		return nil, fmt.Errorf("determining bucket's owner: %w", err)
 	}
 
 	sess.Config.
-		WithRegion(src.Spec.ARN.Region).
-		WithCredentials(credentials.NewStaticCredentialsFromCreds(*creds))
-
-	return s3.New(sess), sqs.New(sess), nil
+		WithRegion(region).
+		WithCredentials(creds)
+
+	return &sessionEntry{
+		sess:      sess,
+		region:    region,
+		accountID: accID,
+		secrets:   secretVersions,
+	}, nil
 }
 
 // determineS3Region determines the most suitable region for interacting with
@@ -134,7 +271,7 @@ func (g *ClientGetterWithSecretGetter) Get(src *v1alpha1.AWSS3Source) (Client, S
 // - Value provided in the ARN of the S3 bucket
 // - Value provided in the ARN of the SQS queue
 // - Value retrieved from the S3 API
-func determineS3Region(src *v1alpha1.AWSS3Source, creds *credentials.Value) (string, error) {
+func determineS3Region(ctx context.Context, src *v1alpha1.AWSS3Source, creds *credentials.Credentials) (string, error) {
 	if src.Spec.ARN.Region != "" {
 		return src.Spec.ARN.Region, nil
 	}
@@ -145,7 +282,7 @@ func determineS3Region(src *v1alpha1.AWSS3Source, creds *credentials.Value) (str
 		}
 	}
 
-	region, err := getBucketRegion(src.Spec.ARN.Resource, creds)
+	region, err := getBucketRegion(ctx, src.Spec.ARN.Resource, src.Spec.Endpoint, creds)
 	if err != nil {
 		return "", fmt.Errorf("getting location of bucket %q: %w", src.Spec.ARN.Resource, err)
 	}
@@ -154,13 +291,15 @@ func determineS3Region(src *v1alpha1.AWSS3Source, creds *credentials.Value) (str
 }
 
 // getBucketRegion retrieves the region the provided bucket resides in.
-func getBucketRegion(bucketName string, creds *credentials.Value) (string, error) {
-	sess := session.Must(session.NewSession(awscore.NewConfig().
+func getBucketRegion(ctx context.Context, bucketName string,
+	endpoint *commonv1alpha1.AWSEndpoint, creds *credentials.Credentials) (string, error) {
+
+	sess := aws.InstrumentSession(session.Must(session.NewSession(newConfig(endpoint).
 		WithRegion(defaultS3Region).
-		WithCredentials(credentials.NewStaticCredentialsFromCreds(*creds)),
-	))
+		WithCredentials(creds),
+	)))
 
-	resp, err := s3.New(sess).GetBucketLocation(&s3.GetBucketLocationInput{
+	resp, err := s3.New(sess).GetBucketLocationWithContext(ctx, &s3.GetBucketLocationInput{
 		Bucket: &bucketName,
 	})
 	if err != nil {
@@ -179,7 +318,14 @@ func getBucketRegion(bucketName string, creds *credentials.Value) (string, error
 // - Value provided in the ARN of the S3 bucket
 // - Value provided in the ARN of the SQS queue
 // - Value retrieved from the STS API
-func determineBucketOwnerAccount(src *v1alpha1.AWSS3Source, creds *credentials.Value) (string, error) {
+//
+// The account of the caller, which is retrieved from the STS API, does not
+// necessarily own the bucket, e.g. when the bucket policy grants access to
+// principals of other accounts. The bucket owner is therefore verified in
+// this case, and an error is returned if the caller doesn't own the bucket.
+func determineBucketOwnerAccount(ctx context.Context, src *v1alpha1.AWSS3Source,
+	region string, creds *credentials.Credentials) (string, error) {
+
 	if src.Spec.ARN.AccountID != "" {
 		return src.Spec.ARN.AccountID, nil
 	}
@@ -190,21 +336,62 @@ func determineBucketOwnerAccount(src *v1alpha1.AWSS3Source, creds *credentials.V
 		}
 	}
 
-	accID, err := getCallerAccountID(creds)
+	accID, err := getCallerAccountID(ctx, region, src.Spec.Endpoint, creds)
 	if err != nil {
 		return "", fmt.Errorf("getting ID of caller: %w", err)
 	}
 
+	owns, err := isBucketOwner(ctx, src.Spec.ARN.Resource, accID, region, src.Spec.Endpoint, creds)
+	if err != nil {
+		return "", fmt.Errorf("verifying owner of bucket %q: %w", src.Spec.ARN.Resource, err)
+	}
+	if !owns {
+		return "", fmt.Errorf("bucket %q is not owned by the account of the caller (%s), "+
+			"its owner account must be specified in the bucket ARN", src.Spec.ARN.Resource, accID)
+	}
+
 	return accID, nil
 }
 
+// isBucketOwner returns whether the provided bucket is owned by the given
+// account. S3 rejects requests with an AccessDenied error when the value of
+// their ExpectedBucketOwner parameter doesn't match the bucket owner.
+// https://docs.aws.amazon.com/AmazonS3/latest/userguide/bucket-owner-condition.html
+func isBucketOwner(ctx context.Context, bucketName, accID, region string,
+	endpoint *commonv1alpha1.AWSEndpoint, creds *credentials.Credentials) (bool, error) {
+
+	sess := aws.InstrumentSession(session.Must(session.NewSession(newConfig(endpoint).
+		WithRegion(region).
+		WithCredentials(creds),
+	)))
+
+	_, err := s3.New(sess).GetBucketLocationWithContext(ctx, &s3.GetBucketLocationInput{
+		Bucket:              &bucketName,
+		ExpectedBucketOwner: &accID,
+	})
+
+	var reqErr awserr.RequestFailure
+	switch {
+	case errors.As(err, &reqErr) && reqErr.StatusCode() == http.StatusForbidden:
+		return false, nil
+	case err != nil:
+		return false, err
+	}
+
+	return true, nil
+}
+
 // getCallerAccountID retrieves the account ID of the caller.
-func getCallerAccountID(creds *credentials.Value) (string, error) {
-	sess := session.Must(session.NewSession(awscore.NewConfig().
-		WithCredentials(credentials.NewStaticCredentialsFromCreds(*creds)),
-	))
+// The region is used to sign requests, which is required by custom endpoints.
+func getCallerAccountID(ctx context.Context, region string,
+	endpoint *commonv1alpha1.AWSEndpoint, creds *credentials.Credentials) (string, error) {
+
+	sess := aws.InstrumentSession(session.Must(session.NewSession(newConfig(endpoint).
+		WithRegion(region).
+		WithCredentials(creds),
+	)))
 
-	resp, err := sts.New(sess).GetCallerIdentity(&sts.GetCallerIdentityInput{})
+	resp, err := sts.New(sess).GetCallerIdentityWithContext(ctx, &sts.GetCallerIdentityInput{})
 	if err != nil {
 		return "", err
 	}
@@ -212,13 +399,33 @@ func getCallerAccountID(creds *credentials.Value) (string, error) {
 	return *resp.Account, nil
 }
 
+// newConfig returns the configuration of AWS API clients which send requests
+// to the given endpoint, or to the public AWS cloud if the endpoint is nil.
+//
+// Custom endpoints serve all AWS APIs on a single host, so S3 buckets are
+// addressed using path-style URLs instead of virtual-hosted-style URLs.
+func newConfig(endpoint *commonv1alpha1.AWSEndpoint) *awscore.Config {
+	cfg := awscore.NewConfig()
+
+	if endpoint != nil && endpoint.URL != nil {
+		cfg = cfg.
+			WithEndpoint(endpoint.URL.String()).
+			WithS3ForcePathStyle(true)
+	}
+
+	return cfg
+}
+
 // ClientGetterFunc allows the use of ordinary functions as ClientGetter.
-type ClientGetterFunc func(*v1alpha1.AWSS3Source) (Client, SQSClient, error)
+type ClientGetterFunc func(context.Context, *v1alpha1.AWSS3Source) (*Clients, error)
 
 // ClientGetterFunc implements ClientGetter.
 var _ ClientGetter = (ClientGetterFunc)(nil)
 
 // Get implements ClientGetter.
-func (f ClientGetterFunc) Get(src *v1alpha1.AWSS3Source) (Client, SQSClient, error) {
-	return f(src)
+func (f ClientGetterFunc) Get(ctx context.Context, src *v1alpha1.AWSS3Source) (*Clients, error) {
+	return f(ctx, src)
 }
+
+// Forget implements ClientGetter.
+func (ClientGetterFunc) Forget(*v1alpha1.AWSS3Source) {}
@@ -0,0 +1,412 @@
+/*
+Copyright 2023 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package s3
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	awscore "github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/sqs"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+	pkgapis "knative.dev/pkg/apis"
+
+	"github.com/triggermesh/scoby-hook-triggermesh/pkg/apis"
+	commonv1alpha1 "github.com/triggermesh/scoby-hook-triggermesh/pkg/apis/common/v1alpha1"
+	"github.com/triggermesh/scoby-hook-triggermesh/pkg/apis/sources/v1alpha1"
+	"github.com/triggermesh/scoby-hook-triggermesh/pkg/sources/auth"
+)
+
+// fakeAWSServer is a stand-in for the S3 and STS APIs which records the path
+// of each request it receives, as well as the parameters of AssumeRole and
+// AssumeRoleWithWebIdentity requests.
+type fakeAWSServer struct {
+	*httptest.Server
+
+	// account which owns the bucket
+	bucketOwner string
+	// whether S3 rejects the credentials of requests
+	rejectCredentials bool
+
+	mu            sync.Mutex
+	paths         []string
+	assumeRoles   []url.Values
+	webIdentities []url.Values
+}
+
+func newFakeAWSServer(t *testing.T) *fakeAWSServer {
+	const (
+		bucketLocationResponse = `<LocationConstraint xmlns="http://s3.amazonaws.com/doc/2006-03-01/">eu-west-1</LocationConstraint>`
+
+		callerIdentityResponse = `<GetCallerIdentityResponse xmlns="https://sts.amazonaws.com/doc/2011-06-15/">
+  <GetCallerIdentityResult>
+    <Arn>arn:aws:iam::123456789012:user/fake</Arn>
+    <UserId>AIDAFAKE</UserId>
+    <Account>123456789012</Account>
+  </GetCallerIdentityResult>
+</GetCallerIdentityResponse>`
+
+		assumeRoleResponse = `<AssumeRoleResponse xmlns="https://sts.amazonaws.com/doc/2011-06-15/">
+  <AssumeRoleResult>
+    <Credentials>
+      <AccessKeyId>ASIAFAKE</AccessKeyId>
+      <SecretAccessKey>fake</SecretAccessKey>
+      <SessionToken>fake</SessionToken>
+      <Expiration>2100-01-01T00:00:00Z</Expiration>
+    </Credentials>
+  </AssumeRoleResult>
+</AssumeRoleResponse>`
+
+		assumeRoleWithWebIdentityResponse = `<AssumeRoleWithWebIdentityResponse xmlns="https://sts.amazonaws.com/doc/2011-06-15/">
+  <AssumeRoleWithWebIdentityResult>
+    <Credentials>
+      <AccessKeyId>ASIAFAKE</AccessKeyId>
+      <SecretAccessKey>fake</SecretAccessKey>
+      <SessionToken>fake</SessionToken>
+      <Expiration>2100-01-01T00:00:00Z</Expiration>
+    </Credentials>
+  </AssumeRoleWithWebIdentityResult>
+</AssumeRoleWithWebIdentityResponse>`
+
+		accessDeniedResponse = `<Error><Code>AccessDenied</Code><Message>Access Denied</Message></Error>`
+
+		invalidAccessKeyResponse = `<Error><Code>InvalidAccessKeyId</Code>` +
+			`<Message>The AWS Access Key Id you provided does not exist in our records.</Message></Error>`
+	)
+
+	s := &fakeAWSServer{
+		bucketOwner: "123456789012",
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		s.paths = append(s.paths, r.URL.Path)
+
+		// path-style addressing puts the bucket name in the URL path
+		if r.URL.Path == "/my-bucket" {
+			if s.rejectCredentials {
+				w.WriteHeader(http.StatusForbidden)
+				_, _ = w.Write([]byte(invalidAccessKeyResponse))
+				return
+			}
+			if owner := r.Header.Get("x-amz-expected-bucket-owner"); owner != "" && owner != s.bucketOwner {
+				w.WriteHeader(http.StatusForbidden)
+				_, _ = w.Write([]byte(accessDeniedResponse))
+				return
+			}
+			_, _ = w.Write([]byte(bucketLocationResponse))
+			return
+		}
+
+		_ = r.ParseForm()
+		switch r.PostForm.Get("Action") {
+		case "AssumeRole":
+			s.assumeRoles = append(s.assumeRoles, r.PostForm)
+			_, _ = w.Write([]byte(assumeRoleResponse))
+			return
+		case "AssumeRoleWithWebIdentity":
+			s.webIdentities = append(s.webIdentities, r.PostForm)
+			_, _ = w.Write([]byte(assumeRoleWithWebIdentityResponse))
+			return
+		}
+		_, _ = w.Write([]byte(callerIdentityResponse))
+	}))
+	t.Cleanup(s.Close)
+
+	return s
+}
+
+func (s *fakeAWSServer) requestPaths() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.paths...)
+}
+
+// newTestSource returns a source which targets the given fake AWS server.
+func newTestSource(t *testing.T, srv *fakeAWSServer, creds *commonv1alpha1.AWSSecurityCredentials) *v1alpha1.AWSS3Source {
+	endpointURL, err := pkgapis.ParseURL(srv.URL)
+	require.NoError(t, err)
+
+	src := &v1alpha1.AWSS3Source{}
+	src.Namespace = "test-ns"
+	src.Spec.ARN = apis.ARN{Partition: "aws", Service: "s3", Resource: "my-bucket"}
+	src.Spec.Auth.Credentials = creds
+	src.Spec.Endpoint = &commonv1alpha1.AWSEndpoint{URL: endpointURL}
+
+	return src
+}
+
+func TestGetWithCustomEndpoint(t *testing.T) {
+	srv := newFakeAWSServer(t)
+
+	src := newTestSource(t, srv, &commonv1alpha1.AWSSecurityCredentials{
+		AccessKeyID:     commonv1alpha1.ValueFromField{Value: "fake"},
+		SecretAccessKey: commonv1alpha1.ValueFromField{Value: "fake"},
+	})
+
+	kc := fake.NewSimpleClientset()
+	cg := NewClientGetter(kc.CoreV1().Secrets, kc.CoreV1().ServiceAccounts)
+
+	cli, err := cg.Get(context.Background(), src)
+	require.NoError(t, err)
+
+	assert.Equal(t, "eu-west-1", src.Spec.ARN.Region)
+	assert.Equal(t, "123456789012", src.Spec.ARN.AccountID)
+	assert.Equal(t, []string{"/my-bucket", "/", "/my-bucket"}, srv.requestPaths(),
+		"Expected all requests to be sent to the custom endpoint")
+
+	require.IsType(t, (*s3.S3)(nil), cli.S3)
+	assert.Equal(t, srv.URL, cli.S3.(*s3.S3).Endpoint)
+	assert.True(t, *cli.S3.(*s3.S3).Config.S3ForcePathStyle, "Expected S3 buckets to be addressed using path-style URLs")
+
+	require.IsType(t, (*sqs.SQS)(nil), cli.SQS)
+	assert.Equal(t, srv.URL, cli.SQS.(*sqs.SQS).Endpoint)
+}
+
+func TestGetCachesSessions(t *testing.T) {
+	srv := newFakeAWSServer(t)
+
+	secretRef := func(key string) commonv1alpha1.ValueFromField {
+		return commonv1alpha1.ValueFromField{ValueFromSecret: &corev1.SecretKeySelector{
+			LocalObjectReference: corev1.LocalObjectReference{Name: "aws-creds"},
+			Key:                  key,
+		}}
+	}
+	creds := &commonv1alpha1.AWSSecurityCredentials{
+		AccessKeyID:     secretRef("keyID"),
+		SecretAccessKey: secretRef("secret"),
+	}
+
+	secr := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "test-ns", Name: "aws-creds", ResourceVersion: "1"},
+		Data:       map[string][]byte{"keyID": []byte("fake"), "secret": []byte("fake")},
+	}
+	kc := fake.NewSimpleClientset(secr)
+	cg := NewClientGetter(kc.CoreV1().Secrets, kc.CoreV1().ServiceAccounts)
+
+	ctx := context.Background()
+
+	_, err := cg.Get(ctx, newTestSource(t, srv, creds))
+	require.NoError(t, err)
+	assert.Len(t, srv.requestPaths(), 3)
+	assert.Len(t, kc.Actions(), 2, "Expected the Secret to be read for its version and credentials")
+
+	src := newTestSource(t, srv, creds)
+	_, err = cg.Get(ctx, src)
+	require.NoError(t, err)
+	assert.Len(t, srv.requestPaths(), 3, "Expected cached bucket metadata to be reused")
+	assert.Len(t, kc.Actions(), 3, "Expected only the version of the Secret to be read")
+	assert.Equal(t, "eu-west-1", src.Spec.ARN.Region)
+	assert.Equal(t, "123456789012", src.Spec.ARN.AccountID)
+
+	secr = secr.DeepCopy()
+	secr.ResourceVersion = "2"
+	_, err = kc.CoreV1().Secrets("test-ns").Update(ctx, secr, metav1.UpdateOptions{})
+	require.NoError(t, err)
+	kc.ClearActions()
+
+	_, err = cg.Get(ctx, newTestSource(t, srv, creds))
+	require.NoError(t, err)
+	assert.Len(t, srv.requestPaths(), 6, "Expected bucket metadata to be resolved again")
+	assert.Len(t, kc.Actions(), 2, "Expected credentials to be read again")
+
+	_, err = cg.Get(ctx, newTestSource(t, srv, creds))
+	require.NoError(t, err)
+	assert.Len(t, srv.requestPaths(), 6, "Expected the re-created session to be cached")
+}
+
+func TestCachedSessionsEviction(t *testing.T) {
+	srv := newFakeAWSServer(t)
+
+	creds := &commonv1alpha1.AWSSecurityCredentials{
+		AccessKeyID:     commonv1alpha1.ValueFromField{Value: "fake"},
+		SecretAccessKey: commonv1alpha1.ValueFromField{Value: "fake"},
+	}
+
+	kc := fake.NewSimpleClientset()
+	cg := NewClientGetter(kc.CoreV1().Secrets, kc.CoreV1().ServiceAccounts)
+
+	ctx := context.Background()
+
+	src1 := newTestSource(t, srv, creds)
+	src1.Name = "src1"
+	src2 := newTestSource(t, srv, creds)
+	src2.Name = "src2"
+
+	_, err := cg.Get(ctx, src1)
+	require.NoError(t, err)
+	_, err = cg.Get(ctx, src2)
+	require.NoError(t, err)
+	assert.Len(t, cg.cache.entries, 1, "Expected sources with the same parameters to share a session")
+
+	cg.Forget(src1)
+	assert.Len(t, cg.cache.entries, 1, "Expected the session to be retained while used by another source")
+
+	src2.Spec.ARN.Resource = "other-bucket"
+	_, err = cg.Get(ctx, src2)
+	require.NoError(t, err)
+	assert.Len(t, cg.cache.entries, 1, "Expected the session which isn't used anymore to be evicted")
+	assert.Contains(t, cg.cache.entries, sessionKeyFor(src2))
+
+	cg.Forget(src2)
+	assert.Empty(t, cg.cache.entries, "Expected the session of deleted sources to be evicted")
+}
+
+func TestGetWithAssumeRoleChain(t *testing.T) {
+	srv := newFakeAWSServer(t)
+
+	src := newTestSource(t, srv, &commonv1alpha1.AWSSecurityCredentials{
+		AccessKeyID:     commonv1alpha1.ValueFromField{Value: "fake"},
+		SecretAccessKey: commonv1alpha1.ValueFromField{Value: "fake"},
+	})
+	src.Spec.Auth.AssumeRoles = []commonv1alpha1.AWSAssumeRole{
+		{
+			RoleARN: apis.ARN{Partition: "aws", Service: "iam", AccountID: "111111111111", Resource: "role/hop"},
+		}, {
+			RoleARN:         apis.ARN{Partition: "aws", Service: "iam", AccountID: "123456789012", Resource: "role/target"},
+			ExternalID:      "my-external-id",
+			SessionName:     "my-session",
+			DurationSeconds: awscore.Int64(900),
+			SessionTags:     map[string]string{"team": "events", "app": "s3"},
+		},
+	}
+
+	kc := fake.NewSimpleClientset()
+	cg := NewClientGetter(kc.CoreV1().Secrets, kc.CoreV1().ServiceAccounts)
+
+	_, err := cg.Get(context.Background(), src)
+	require.NoError(t, err)
+
+	require.Len(t, srv.assumeRoles, 2, "Expected each role of the chain to be assumed")
+
+	hop := srv.assumeRoles[0]
+	assert.Equal(t, "arn:aws:iam::111111111111:role/hop", hop.Get("RoleArn"))
+	assert.Equal(t, "triggermesh", hop.Get("RoleSessionName"))
+	assert.Empty(t, hop.Get("ExternalId"))
+
+	target := srv.assumeRoles[1]
+	assert.Equal(t, "arn:aws:iam::123456789012:role/target", target.Get("RoleArn"))
+	assert.Equal(t, "my-session", target.Get("RoleSessionName"))
+	assert.Equal(t, "my-external-id", target.Get("ExternalId"))
+	assert.Equal(t, "900", target.Get("DurationSeconds"))
+	assert.Equal(t, "app", target.Get("Tags.member.1.Key"))
+	assert.Equal(t, "team", target.Get("Tags.member.2.Key"))
+}
+
+func TestGetWithEksIAMRole(t *testing.T) {
+	srv := newFakeAWSServer(t)
+
+	src := newTestSource(t, srv, nil)
+	src.Name = "my-source"
+	src.Spec.Auth.EksIAMRole = &apis.ARN{Partition: "aws", Service: "iam", AccountID: "123456789012", Resource: "role/s3"}
+
+	kc := fake.NewSimpleClientset()
+
+	var tokenReqs []*authenticationv1.TokenRequest
+	kc.PrependReactor("create", "serviceaccounts", func(a k8stesting.Action) (bool, runtime.Object, error) {
+		ca := a.(k8stesting.CreateAction)
+		if ca.GetSubresource() != "token" {
+			return false, nil, nil
+		}
+		assert.Equal(t, "test-ns", ca.GetNamespace())
+		assert.Equal(t, "my-source-awss3source", ca.(k8stesting.CreateActionImpl).Name)
+
+		tr := ca.GetObject().(*authenticationv1.TokenRequest)
+		tokenReqs = append(tokenReqs, tr)
+		tr = tr.DeepCopy()
+		tr.Status.Token = "fake-token"
+		return true, tr, nil
+	})
+
+	cg := NewClientGetter(kc.CoreV1().Secrets, kc.CoreV1().ServiceAccounts)
+
+	_, err := cg.Get(context.Background(), src)
+	require.NoError(t, err)
+
+	require.Len(t, tokenReqs, 1, "Expected a token to be requested for the source's ServiceAccount")
+	assert.Equal(t, []string{"sts.amazonaws.com"}, tokenReqs[0].Spec.Audiences)
+
+	require.Len(t, srv.webIdentities, 1, "Expected the role to be assumed with a web identity")
+	assert.Equal(t, "arn:aws:iam::123456789012:role/s3", srv.webIdentities[0].Get("RoleArn"))
+	assert.Equal(t, "fake-token", srv.webIdentities[0].Get("WebIdentityToken"))
+	assert.Empty(t, srv.assumeRoles)
+}
+
+func TestGetBucketOwnedByOtherAccount(t *testing.T) {
+	srv := newFakeAWSServer(t)
+	srv.bucketOwner = "210987654321"
+
+	src := newTestSource(t, srv, &commonv1alpha1.AWSSecurityCredentials{
+		AccessKeyID:     commonv1alpha1.ValueFromField{Value: "fake"},
+		SecretAccessKey: commonv1alpha1.ValueFromField{Value: "fake"},
+	})
+
+	kc := fake.NewSimpleClientset()
+	cg := NewClientGetter(kc.CoreV1().Secrets, kc.CoreV1().ServiceAccounts)
+
+	_, err := cg.Get(context.Background(), src)
+	assert.ErrorContains(t, err, "not owned by the account of the caller")
+	assert.False(t, isPermanent(err))
+	assert.Empty(t, src.Spec.ARN.AccountID)
+
+	src.Spec.ARN.AccountID = srv.bucketOwner
+	_, err = cg.Get(context.Background(), src)
+	assert.NoError(t, err, "Expected the owner account provided in the bucket ARN to be trusted")
+}
+
+func TestGetWithRejectedCredentials(t *testing.T) {
+	srv := newFakeAWSServer(t)
+	srv.rejectCredentials = true
+
+	kc := fake.NewSimpleClientset()
+	cg := NewClientGetter(kc.CoreV1().Secrets, kc.CoreV1().ServiceAccounts)
+
+	src := newTestSource(t, srv, &commonv1alpha1.AWSSecurityCredentials{
+		AccessKeyID:     commonv1alpha1.ValueFromField{Value: "fake"},
+		SecretAccessKey: commonv1alpha1.ValueFromField{Value: "fake"},
+	})
+
+	_, err := cg.Get(context.Background(), src)
+	assert.ErrorContains(t, err, "InvalidAccessKeyId")
+	assert.True(t, isPermanent(err), "Expected rejected credentials to be reported as permanent")
+
+	src.Spec.Auth.Credentials = nil
+	_, err = cg.Get(context.Background(), src)
+	assert.True(t, isPermanent(err), "Expected missing credentials to be reported as permanent")
+}
+
+// isPermanent returns whether err implements auth.PermanentCredentialsError.
+func isPermanent(err error) bool {
+	permErr := (auth.PermanentCredentialsError)(nil)
+	return errors.As(err, &permErr)
+}
@@ -0,0 +1,318 @@
+/*
+Copyright 2023 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package errclass classifies errors returned by the APIs of cloud providers
+// (AWS, Azure, GCP) and by the Kubernetes API, so that callers can decide
+// whether and when an operation that failed should be retried.
+package errclass
+
+import (
+	"errors"
+	"net/http"
+	"reflect"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/eventbridge"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/sns"
+	"github.com/aws/aws-sdk-go/service/sqs"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/triggermesh/scoby-hook-triggermesh/pkg/sources/auth"
+)
+
+// Class is the class of an error.
+type Class string
+
+// Classes of errors.
+const (
+	// Unknown errors could not be classified.
+	Unknown Class = ""
+	// NotFound errors indicate that some resource was not found.
+	NotFound Class = "NotFound"
+	// Unauthenticated errors indicate that a request could not be
+	// authorized because credentials are either invalid, expired or
+	// missing.
+	Unauthenticated Class = "Unauthenticated"
+	// Denied errors indicate that a request could not be authorized
+	// because valid credentials are missing permissions, which may be
+	// granted without changing the credentials.
+	Denied Class = "Denied"
+	// Throttled errors indicate that a request was rejected due to rate
+	// limiting.
+	Throttled Class = "Throttled"
+	// Transient errors indicate a temporary failure on the side of the
+	// provider or of the network (server errors, timeouts, conflicts).
+	Transient Class = "Transient"
+	// Invalid errors indicate that a request was rejected by the provider
+	// for reasons that are unlikely to change without a user intervention.
+	Invalid Class = "Invalid"
+)
+
+// Classify returns the class of the given error.
+//
+// Errors are inspected from the outermost to the innermost one, and the first
+// error that can be classified determines the class of the whole chain.
+func Classify(err error) Class {
+	permErr := (auth.PermanentCredentialsError)(nil)
+	if errors.As(err, &permErr) {
+		return Unauthenticated
+	}
+
+	for ; err != nil; err = unwrap(err) {
+		if c := classifyOne(err); c != Unknown {
+			return c
+		}
+	}
+
+	return Unknown
+}
+
+// IsNotFound returns whether the given error indicates that some resource was
+// not found.
+func IsNotFound(err error) bool {
+	return Classify(err) == NotFound
+}
+
+// IsDenied returns whether the given error indicates that a request could not
+// be authorized, either because of the credentials or of their permissions.
+func IsDenied(err error) bool {
+	c := Classify(err)
+	return c == Denied || c == Unauthenticated
+}
+
+// IsAWSError returns whether the given error is an AWS error.
+func IsAWSError(err error) bool {
+	awsErr := awserr.Error(nil)
+	return errors.As(err, &awsErr)
+}
+
+// Permanent marks the given error as an auth.PermanentCredentialsError if it
+// indicates that credentials are invalid, expired or missing. Other errors,
+// including missing permissions, are returned unchanged.
+func Permanent(err error) error {
+	if err == nil || Classify(err) != Unauthenticated {
+		return err
+	}
+
+	permErr := (auth.PermanentCredentialsError)(nil)
+	if errors.As(err, &permErr) {
+		return err
+	}
+
+	return auth.NewPermanentCredentialsError(err)
+}
+
+// classifyOne returns the class of the given error, without inspecting the
+// errors it wraps.
+func classifyOne(err error) Class {
+	if k8sErr, ok := err.(apierrors.APIStatus); ok {
+		return classifyKubernetes(k8sErr)
+	}
+	if awsErr, ok := err.(awserr.Error); ok {
+		return classifyAWS(awsErr)
+	}
+	if code, ok := httpStatusCode(err); ok {
+		return classifyHTTPStatus(code)
+	}
+	return Unknown
+}
+
+// classifyKubernetes returns the class of a Kubernetes API error.
+//
+// Authorization errors returned by the Kubernetes API relate to the hook's
+// own permissions rather than to user-provided credentials, they are
+// therefore not classified as Denied.
+func classifyKubernetes(err apierrors.APIStatus) Class {
+	switch err.Status().Reason {
+	case metav1.StatusReasonNotFound:
+		return NotFound
+	case metav1.StatusReasonTooManyRequests:
+		return Throttled
+	case metav1.StatusReasonConflict,
+		metav1.StatusReasonServerTimeout,
+		metav1.StatusReasonTimeout,
+		metav1.StatusReasonServiceUnavailable,
+		metav1.StatusReasonInternalError:
+		return Transient
+	}
+	return Unknown
+}
+
+// awsNotFoundCodes are codes of AWS errors which indicate that some resource
+// was not found, regardless of the HTTP status code of the response.
+var awsNotFoundCodes = map[string]struct{}{
+	sqs.ErrCodeQueueDoesNotExist:                 {},
+	s3.ErrCodeNoSuchBucket:                       {},
+	sns.ErrCodeNotFoundException:                 {},
+	eventbridge.ErrCodeResourceNotFoundException: {},
+}
+
+// awsUnauthenticatedCodes are codes of AWS errors which indicate that
+// credentials are invalid, expired or missing, regardless of the HTTP status
+// code of the response.
+var awsUnauthenticatedCodes = map[string]struct{}{
+	"EmptyStaticCreds":            {}, // credentials.ErrStaticCredentialsEmpty
+	"ExpiredToken":                {},
+	"ExpiredTokenException":       {},
+	"IDPRejectedClaim":            {}, // STS
+	"InvalidAccessKeyId":          {},
+	"InvalidClientTokenId":        {},
+	"InvalidIdentityToken":        {}, // STS
+	"SignatureDoesNotMatch":       {},
+	"UnrecognizedClientException": {},
+}
+
+// awsDeniedCodes are codes of AWS errors which indicate that valid credentials
+// are missing permissions, regardless of the HTTP status code of the response.
+var awsDeniedCodes = map[string]struct{}{
+	"AccessDenied":          {},
+	"AccessDeniedException": {},
+	"AuthorizationError":    {}, // SNS
+}
+
+// awsTransientCodes are codes of AWS errors which indicate a temporary
+// failure, in addition to those considered retryable by the AWS SDK.
+var awsTransientCodes = map[string]struct{}{
+	sqs.ErrCodeQueueDeletedRecently: {},
+	"OperationAborted":              {}, // S3
+}
+
+// classifyAWS returns the class of an AWS error.
+func classifyAWS(err awserr.Error) Class {
+	code := err.Code()
+
+	if _, ok := awsNotFoundCodes[code]; ok {
+		return NotFound
+	}
+	if _, ok := awsUnauthenticatedCodes[code]; ok || err == credentials.ErrStaticCredentialsEmpty {
+		return Unauthenticated
+	}
+	if _, ok := awsDeniedCodes[code]; ok {
+		return Denied
+	}
+	if request.IsErrorThrottle(err) {
+		return Throttled
+	}
+	if _, ok := awsTransientCodes[code]; ok {
+		return Transient
+	}
+
+	if reqFail, ok := err.(awserr.RequestFailure); ok {
+		return classifyHTTPStatus(reqFail.StatusCode())
+	}
+
+	// The error didn't originate from a response of the AWS API, e.g.
+	// it is a network error.
+	if request.IsErrorRetryable(err) {
+		return Transient
+	}
+
+	return Unknown
+}
+
+// classifyHTTPStatus returns the class of an error based on the HTTP status
+// code of the response which caused it.
+func classifyHTTPStatus(code int) Class {
+	switch {
+	case code == http.StatusNotFound,
+		code == http.StatusGone:
+		return NotFound
+	case code == http.StatusUnauthorized:
+		return Unauthenticated
+	case code == http.StatusForbidden:
+		return Denied
+	case code == http.StatusTooManyRequests:
+		return Throttled
+	case code == http.StatusRequestTimeout,
+		code == http.StatusConflict,
+		code >= http.StatusInternalServerError:
+		return Transient
+	case code >= http.StatusBadRequest:
+		return Invalid
+	}
+	return Unknown
+}
+
+// httpStatusCode returns the HTTP status code of the response which caused
+// the given error, if the error carries one.
+//
+// The SDKs of Azure and GCP aren't dependencies of this module, so their
+// errors are recognized by their shape rather than by their type:
+//   - GCP (apierror.APIError): HTTPCode() method
+//   - Azure (azcore.ResponseError, autorest.DetailedError): StatusCode field
+//   - GCP (googleapi.Error): Code field
+func httpStatusCode(err error) (int, bool) {
+	if e, ok := err.(interface{ HTTPCode() int }); ok {
+		if code := e.HTTPCode(); code > 0 {
+			return code, true
+		}
+		return 0, false
+	}
+
+	v := reflect.ValueOf(err)
+	if v.Kind() == reflect.Pointer {
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return 0, false
+	}
+
+	if code, ok := intField(v, "StatusCode"); ok {
+		return code, true
+	}
+	if v.Type().PkgPath() == googleAPIPkgPath {
+		return intField(v, "Code")
+	}
+
+	return 0, false
+}
+
+// googleAPIPkgPath is the import path of the package of the error type
+// returned by GCP's REST clients.
+const googleAPIPkgPath = "google.golang.org/api/googleapi"
+
+// intField returns the value of the struct field with the given name if it
+// holds a positive integer, either directly or inside an interface.
+func intField(v reflect.Value, name string) (int, bool) {
+	f := v.FieldByName(name)
+	if f.Kind() == reflect.Interface {
+		f = f.Elem()
+	}
+
+	switch f.Kind() {
+	case reflect.Int, reflect.Int32, reflect.Int64:
+		if i := int(f.Int()); i > 0 {
+			return i, true
+		}
+	}
+
+	return 0, false
+}
+
+// unwrap returns the error wrapped by the given error, if any. Errors from
+// the AWS SDK expose the error they wrap via the awserr.Error interface
+// instead of errors.Unwrap.
+func unwrap(err error) error {
+	if awsErr, ok := err.(awserr.Error); ok {
+		return awsErr.OrigErr()
+	}
+	return errors.Unwrap(err)
+}
@@ -0,0 +1,180 @@
+/*
+Copyright 2023 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package errclass
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/credentials/stscreds"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/sqs"
+	"github.com/stretchr/testify/assert"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"github.com/triggermesh/scoby-hook-triggermesh/pkg/sources/auth"
+)
+
+func TestClassify(t *testing.T) {
+	testCases := map[string]struct {
+		err    error
+		expect Class
+	}{
+		"Generic error": {
+			err:    assert.AnError,
+			expect: Unknown,
+		},
+		"Permanent credentials error": {
+			err:    auth.NewPermanentCredentialsError(assert.AnError),
+			expect: Unauthenticated,
+		},
+		"Kubernetes not found": {
+			err:    apierrors.NewNotFound(schema.GroupResource{Resource: "secrets"}, "creds"),
+			expect: NotFound,
+		},
+		"Kubernetes forbidden": {
+			err:    apierrors.NewForbidden(schema.GroupResource{Resource: "secrets"}, "creds", assert.AnError),
+			expect: Unknown,
+		},
+		"AWS not found code": {
+			err:    awserr.NewRequestFailure(awserr.New(sqs.ErrCodeQueueDoesNotExist, "", nil), http.StatusBadRequest, ""),
+			expect: NotFound,
+		},
+		"AWS not found status": {
+			err:    awserr.NewRequestFailure(awserr.New("NotFound", "", nil), http.StatusNotFound, ""),
+			expect: NotFound,
+		},
+		"AWS denied status": {
+			err:    awserr.NewRequestFailure(awserr.New("AccessDenied", "", nil), http.StatusForbidden, ""),
+			expect: Denied,
+		},
+		"AWS invalid token": {
+			err:    awserr.NewRequestFailure(awserr.New("UnrecognizedClientException", "", nil), http.StatusBadRequest, ""),
+			expect: Unauthenticated,
+		},
+		"AWS empty static credentials": {
+			err:    credentials.ErrStaticCredentialsEmpty,
+			expect: Unauthenticated,
+		},
+		"AWS web identity rejected": {
+			err: awserr.New(stscreds.ErrCodeWebIdentity, "failed to retrieve credentials",
+				awserr.NewRequestFailure(awserr.New("InvalidIdentityToken", "", nil), http.StatusBadRequest, "")),
+			expect: Unauthenticated,
+		},
+		"AWS expired token": {
+			err:    awserr.NewRequestFailure(awserr.New("ExpiredToken", "", nil), http.StatusForbidden, ""),
+			expect: Unauthenticated,
+		},
+		"AWS throttled": {
+			err:    awserr.NewRequestFailure(awserr.New("Throttling", "", nil), http.StatusBadRequest, ""),
+			expect: Throttled,
+		},
+		"AWS queue deleted recently": {
+			err:    awserr.NewRequestFailure(awserr.New(sqs.ErrCodeQueueDeletedRecently, "", nil), http.StatusBadRequest, ""),
+			expect: Transient,
+		},
+		"AWS server error": {
+			err:    awserr.NewRequestFailure(awserr.New("InternalError", "", nil), http.StatusInternalServerError, ""),
+			expect: Transient,
+		},
+		"AWS network error": {
+			err:    awserr.New(request.ErrCodeRequestError, "send request failed", assert.AnError),
+			expect: Transient,
+		},
+		"AWS invalid request": {
+			err:    awserr.NewRequestFailure(awserr.New("InvalidArgument", "", nil), http.StatusBadRequest, ""),
+			expect: Invalid,
+		},
+		"AWS not found wrapped": {
+			err:    fmt.Errorf("getting bucket: %w", awserr.New(s3.ErrCodeNoSuchBucket, "", nil)),
+			expect: NotFound,
+		},
+		"Azure response error": {
+			err:    &fakeAzureResponseError{ErrorCode: "AuthorizationFailed", StatusCode: http.StatusForbidden},
+			expect: Denied,
+		},
+		"Azure detailed error": {
+			err:    fakeAzureDetailedError{StatusCode: http.StatusNotFound},
+			expect: NotFound,
+		},
+		"Azure detailed error without response": {
+			err:    fakeAzureDetailedError{},
+			expect: Unknown,
+		},
+		"GCP API error": {
+			err:    fmt.Errorf("creating subscription: %w", &fakeGCPAPIError{code: http.StatusTooManyRequests}),
+			expect: Throttled,
+		},
+		"GCP gRPC API error": {
+			err:    &fakeGCPAPIError{code: -1},
+			expect: Unknown,
+		},
+	}
+
+	for name, tc := range testCases {
+		//nolint:scopelint
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tc.expect, Classify(tc.err))
+		})
+	}
+}
+
+func TestPermanent(t *testing.T) {
+	deniedErr := awserr.NewRequestFailure(awserr.New("InvalidClientTokenId", "", nil), http.StatusForbidden, "")
+
+	permErr := (auth.PermanentCredentialsError)(nil)
+
+	assert.True(t, errors.As(Permanent(deniedErr), &permErr))
+	assert.ErrorIs(t, Permanent(deniedErr), deniedErr)
+	assert.Equal(t, permErr, Permanent(permErr), "Permanent error should be returned unchanged")
+
+	accessDeniedErr := awserr.NewRequestFailure(awserr.New("AccessDenied", "", nil), http.StatusForbidden, "")
+	assert.Equal(t, accessDeniedErr, Permanent(accessDeniedErr), "Missing permissions should not be permanent")
+
+	assert.Equal(t, assert.AnError, Permanent(assert.AnError))
+	assert.NoError(t, Permanent(nil))
+}
+
+// fakeAzureResponseError has the shape of azcore.ResponseError.
+type fakeAzureResponseError struct {
+	ErrorCode  string
+	StatusCode int
+}
+
+func (*fakeAzureResponseError) Error() string { return "fake Azure response error" }
+
+// fakeAzureDetailedError has the shape of autorest.DetailedError.
+type fakeAzureDetailedError struct {
+	StatusCode interface{}
+}
+
+func (fakeAzureDetailedError) Error() string { return "fake Azure detailed error" }
+
+// fakeGCPAPIError has the shape of apierror.APIError.
+type fakeGCPAPIError struct {
+	code int
+}
+
+func (*fakeGCPAPIError) Error() string   { return "fake GCP API error" }
+func (e *fakeGCPAPIError) HTTPCode() int { return e.code }
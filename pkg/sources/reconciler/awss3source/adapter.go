@@ -0,0 +1,151 @@
+/*
+Copyright 2023 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package awss3source
+
+import (
+	corev1 "k8s.io/api/core/v1"
+
+	commonv1alpha1 "github.com/triggermesh/scoby-hook-triggermesh/pkg/apis/common/v1alpha1"
+	"github.com/triggermesh/scoby-hook-triggermesh/pkg/apis/sources/v1alpha1"
+)
+
+// Environment variables read by the AWS SQS source adapter.
+const (
+	envARN              = "ARN"
+	envRegion           = "AWS_REGION"
+	envAccessKeyID      = "AWS_ACCESS_KEY_ID"
+	envSecretAccessKey  = "AWS_SECRET_ACCESS_KEY"
+	envAssumeIAMRole    = "AWS_ASSUME_ROLE_ARN"
+	envEndpointURL      = "AWS_ENDPOINT_URL"
+	envMessageProcessor = "SQS_MESSAGE_PROCESSOR"
+	envCESource         = "CE_SOURCE"
+	envCEType           = "CE_TYPE"
+)
+
+// Adapter message processors.
+const (
+	// messageProcessorS3 converts S3 event notifications received from
+	// SQS into CloudEvents.
+	messageProcessorS3 = "s3"
+	// messageProcessorDefault converts SQS messages into CloudEvents
+	// without interpreting their content. S3 events routed by EventBridge
+	// are wrapped in EventBridge events, which the S3 processor can't
+	// interpret.
+	messageProcessorDefault = "default"
+)
+
+// MakeAppEnv returns the environment variables the adapter needs to consume
+// the notifications of the given source from its SQS queue.
+// It expects the queue ARN to be populated in the source's status.
+func MakeAppEnv(src *v1alpha1.AWSS3Source) []corev1.EnvVar {
+	msgProcessor := messageProcessorS3
+	if eventBridgeDestination(src) != nil {
+		msgProcessor = messageProcessorDefault
+	}
+
+	env := []corev1.EnvVar{
+		{
+			Name:  envARN,
+			Value: src.Status.QueueARN.String(),
+		}, {
+			Name:  envRegion,
+			Value: src.Status.QueueARN.Region,
+		}, {
+			Name:  envMessageProcessor,
+			Value: msgProcessor,
+		}, {
+			Name:  envCESource,
+			Value: src.AsEventSource(),
+		}, {
+			Name:  envCEType,
+			Value: v1alpha1.AWSEventType(src.Spec.ARN.Service, v1alpha1.AWSS3GenericEventType),
+		},
+	}
+
+	if creds := src.Spec.Auth.Credentials; creds != nil {
+		env = appendValueFromEnvVar(env, envAccessKeyID, creds.AccessKeyID)
+		env = appendValueFromEnvVar(env, envSecretAccessKey, creds.SecretAccessKey)
+	}
+
+	// The adapter can only assume a single role, so only the target role
+	// of a chain is passed to it.
+	// The EKS IAM role is not passed to the adapter, which doesn't run as
+	// the ServiceAccount that is allowed to assume it (see
+	// EnsureServiceAccount).
+	if roles := src.Spec.Auth.AssumeRoles; len(roles) > 0 {
+		env = append(env, corev1.EnvVar{
+			Name:  envAssumeIAMRole,
+			Value: roles[len(roles)-1].RoleARN.String(),
+		})
+	}
+
+	if ep := src.Spec.Endpoint; ep != nil && ep.URL != nil {
+		env = append(env, corev1.EnvVar{
+			Name:  envEndpointURL,
+			Value: ep.URL.String(),
+		})
+	}
+
+	if ao := src.Spec.AdapterOverrides; ao != nil {
+		env = mergeEnv(env, ao.Env)
+	}
+
+	return env
+}
+
+// mergeEnv merges the given overrides into a list of environment variables.
+// Overrides replace the variables which have the same name, others are
+// appended.
+func mergeEnv(env, overrides []corev1.EnvVar) []corev1.EnvVar {
+	idx := make(map[string]int, len(env))
+	for i, e := range env {
+		idx[e.Name] = i
+	}
+
+	for _, o := range overrides {
+		if i, ok := idx[o.Name]; ok {
+			env[i] = o
+			continue
+		}
+		idx[o.Name] = len(env)
+		env = append(env, o)
+	}
+
+	return env
+}
+
+// appendValueFromEnvVar appends an environment variable with the given name,
+// sourced either from the literal value of the field or from the Secret it
+// references.
+func appendValueFromEnvVar(env []corev1.EnvVar, name string, f commonv1alpha1.ValueFromField) []corev1.EnvVar {
+	switch {
+	case f.ValueFromSecret != nil:
+		return append(env, corev1.EnvVar{
+			Name: name,
+			ValueFrom: &corev1.EnvVarSource{
+				SecretKeyRef: f.ValueFromSecret,
+			},
+		})
+	case f.Value != "":
+		return append(env, corev1.EnvVar{
+			Name:  name,
+			Value: f.Value,
+		})
+	}
+
+	return env
+}
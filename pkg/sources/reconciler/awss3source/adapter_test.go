@@ -0,0 +1,158 @@
+/*
+Copyright 2023 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package awss3source
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	pkgapis "knative.dev/pkg/apis"
+
+	"github.com/triggermesh/scoby-hook-triggermesh/pkg/apis"
+	commonv1alpha1 "github.com/triggermesh/scoby-hook-triggermesh/pkg/apis/common/v1alpha1"
+	"github.com/triggermesh/scoby-hook-triggermesh/pkg/apis/sources/v1alpha1"
+)
+
+func TestMakeAppEnv(t *testing.T) {
+	bucketARN := apis.ARN{Partition: "aws", Service: "s3", Resource: "my-bucket"}
+	queueARN := &apis.ARN{Partition: "aws", Service: "sqs", Region: "eu-west-1", AccountID: "123456789012", Resource: "my-queue"}
+	roleARN := &apis.ARN{Partition: "aws", Service: "iam", AccountID: "123456789012", Resource: "role/my-role"}
+
+	commonEnv := []corev1.EnvVar{
+		{Name: envARN, Value: "arn:aws:sqs:eu-west-1:123456789012:my-queue"},
+		{Name: envRegion, Value: "eu-west-1"},
+		{Name: envMessageProcessor, Value: "s3"},
+		{Name: envCESource, Value: "arn:aws:s3:::my-bucket"},
+		{Name: envCEType, Value: "com.amazon.s3.objectnotification"},
+	}
+
+	secretRef := &corev1.SecretKeySelector{
+		LocalObjectReference: corev1.LocalObjectReference{Name: "aws-creds"},
+		Key:                  "secret",
+	}
+
+	testCases := map[string]struct {
+		auth        commonv1alpha1.AWSAuth
+		endpoint    *commonv1alpha1.AWSEndpoint
+		overrides   *commonv1alpha1.AdapterOverrides
+		destination *v1alpha1.AWSS3SourceDestination
+		expect      []corev1.EnvVar
+	}{
+		"credentials from values and secrets": {
+			auth: commonv1alpha1.AWSAuth{
+				Credentials: &commonv1alpha1.AWSSecurityCredentials{
+					AccessKeyID:     commonv1alpha1.ValueFromField{Value: "key-id"},
+					SecretAccessKey: commonv1alpha1.ValueFromField{ValueFromSecret: secretRef},
+				},
+			},
+			expect: append(commonEnv[:len(commonEnv):len(commonEnv)],
+				corev1.EnvVar{Name: envAccessKeyID, Value: "key-id"},
+				corev1.EnvVar{Name: envSecretAccessKey, ValueFrom: &corev1.EnvVarSource{SecretKeyRef: secretRef}},
+			),
+		},
+		"IAM role and overrides": {
+			auth: commonv1alpha1.AWSAuth{
+				EksIAMRole: roleARN,
+			},
+			overrides: &commonv1alpha1.AdapterOverrides{
+				Env: []corev1.EnvVar{{Name: "LOG_LEVEL", Value: "debug"}},
+			},
+			expect: append(commonEnv[:len(commonEnv):len(commonEnv)],
+				corev1.EnvVar{Name: "LOG_LEVEL", Value: "debug"},
+			),
+		},
+		"assumed role chain": {
+			auth: commonv1alpha1.AWSAuth{
+				EksIAMRole: roleARN,
+				AssumeRoles: []commonv1alpha1.AWSAssumeRole{{
+					RoleARN: apis.ARN{Partition: "aws", Service: "iam", AccountID: "111111111111", Resource: "role/hop"},
+				}, {
+					RoleARN:    apis.ARN{Partition: "aws", Service: "iam", AccountID: "210987654321", Resource: "role/other"},
+					ExternalID: "my-external-id",
+				}},
+			},
+			expect: append(commonEnv[:len(commonEnv):len(commonEnv)],
+				corev1.EnvVar{Name: envAssumeIAMRole, Value: "arn:aws:iam::210987654321:role/other"},
+			),
+		},
+		"overrides of generated variables": {
+			auth: commonv1alpha1.AWSAuth{
+				EksIAMRole: roleARN,
+			},
+			overrides: &commonv1alpha1.AdapterOverrides{
+				Env: []corev1.EnvVar{
+					{Name: envRegion, Value: "us-east-1"},
+					{Name: "LOG_LEVEL", Value: "debug"},
+					{Name: "LOG_LEVEL", Value: "info"},
+				},
+			},
+			expect: []corev1.EnvVar{
+				commonEnv[0],
+				{Name: envRegion, Value: "us-east-1"},
+				commonEnv[2],
+				commonEnv[3],
+				commonEnv[4],
+				{Name: "LOG_LEVEL", Value: "info"},
+			},
+		},
+		"custom endpoint": {
+			auth: commonv1alpha1.AWSAuth{
+				EksIAMRole: roleARN,
+			},
+			endpoint: &commonv1alpha1.AWSEndpoint{URL: pkgapis.HTTP("localstack:4566")},
+			expect: append(commonEnv[:len(commonEnv):len(commonEnv)],
+				corev1.EnvVar{Name: envEndpointURL, Value: "http://localstack:4566"},
+			),
+		},
+		"EventBridge destination": {
+			auth: commonv1alpha1.AWSAuth{
+				EksIAMRole: roleARN,
+			},
+			destination: &v1alpha1.AWSS3SourceDestination{EventBridge: &v1alpha1.AWSS3SourceDestinationEventBridge{}},
+			expect: []corev1.EnvVar{
+				commonEnv[0],
+				commonEnv[1],
+				{Name: envMessageProcessor, Value: "default"},
+				commonEnv[3],
+				commonEnv[4],
+			},
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			src := &v1alpha1.AWSS3Source{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "name"},
+				Spec: v1alpha1.AWSS3SourceSpec{
+					ARN:              bucketARN,
+					Auth:             tc.auth,
+					Endpoint:         tc.endpoint,
+					Destination:      tc.destination,
+					AdapterOverrides: tc.overrides,
+				},
+				Status: v1alpha1.AWSS3SourceStatus{
+					QueueARN: queueARN,
+				},
+			}
+
+			assert.Equal(t, tc.expect, MakeAppEnv(src))
+		})
+	}
+}
@@ -20,32 +20,35 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"net/http"
 	"sort"
-
-	apierrors "k8s.io/apimachinery/pkg/api/errors"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"strings"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/awserr"
-	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/awsutil"
 	"github.com/aws/aws-sdk-go/service/s3"
 	"github.com/aws/aws-sdk-go/service/s3/s3iface"
-	"github.com/aws/aws-sdk-go/service/sqs"
+	"go.opencensus.io/trace"
 
 	"github.com/triggermesh/scoby-hook-triggermesh/pkg/apis/sources/v1alpha1"
+	"github.com/triggermesh/scoby-hook-triggermesh/pkg/reconciler/event"
+	"github.com/triggermesh/scoby-hook-triggermesh/pkg/sources/errclass"
 )
 
 // EnsureNotificationsEnabled ensures that event notifications are enabled in
-// the S3 bucket.
-func EnsureNotificationsEnabled(ctx context.Context, src *v1alpha1.AWSS3Source, cli s3iface.S3API, queueARN string) error {
+// the S3 bucket. The ARN is the one of the destination of notifications, either
+// a SQS queue or a SNS topic. It is ignored for EventBridge destinations.
+func EnsureNotificationsEnabled(ctx context.Context, src *v1alpha1.AWSS3Source, cli s3iface.S3API, destARN string) error {
+	ctx, span := trace.StartSpan(ctx, "awss3source.EnsureNotificationsEnabled")
+	defer span.End()
+
 	bucketARN := src.Spec.ARN
 
 	notifCfg, err := getNotificationsConfig(ctx, cli, bucketARN.Resource)
 	switch {
-	case isNotFound(err):
+	case errclass.IsNotFound(err):
 		return fmt.Errorf("The bucket does not exist: %v", toErrMsg(err))
-	case isAWSError(err):
+	case errclass.IsAWSError(err):
 		// All documented API errors require some user intervention and
 		// are not to be retried.
 		// https://docs.aws.amazon.com/AmazonS3/latest/API/ErrorResponses.html
@@ -54,14 +57,18 @@ func EnsureNotificationsEnabled(ctx context.Context, src *v1alpha1.AWSS3Source,
 		return fmt.Errorf("Cannot obtain current bucket configuration: %v", toErrMsg(err))
 	}
 
-	desiredQueueCfg := makeQueueConfiguration(src, queueARN)
+	currentCfg := awsutil.CopyOf(notifCfg)
 
-	notifCfg, hasUpdates := setQueueConfiguration(notifCfg, desiredQueueCfg)
+	notifCfg, hasUpdates := setDestinationConfiguration(notifCfg, src, destARN)
 
 	if hasUpdates {
+		if reportDrift(ctx, "S3 bucket notification configuration", currentCfg, notifCfg) {
+			return nil
+		}
 		if err := configureNotifications(ctx, cli, bucketARN.Resource, notifCfg); err != nil {
 			return fmt.Errorf("Cannot configure event notifications: %v", toErrMsg(err))
 		}
+		event.Normal(ctx, ReasonSubscribed, "Enabled event notifications on S3 bucket %q", bucketARN.Resource)
 	}
 
 	return nil
@@ -70,25 +77,41 @@ func EnsureNotificationsEnabled(ctx context.Context, src *v1alpha1.AWSS3Source,
 // EnsureNotificationsDisabled ensures that event notifications are disabled in
 // the S3 bucket.
 func EnsureNotificationsDisabled(ctx context.Context, src *v1alpha1.AWSS3Source, cli s3iface.S3API) error {
+	ctx, span := trace.StartSpan(ctx, "awss3source.EnsureNotificationsDisabled")
+	defer span.End()
+
 	bucketARN := src.Spec.ARN
 
 	notifCfg, err := getNotificationsConfig(ctx, cli, bucketARN.Resource)
 	switch {
-	case isNotFound(err):
-		return fmt.Errorf("Bucket not found: %v", toErrMsg(err))
-	case isDenied(err):
-		// it is unlikely that we recover from auth errors in the
-		// finalizer, so we simply record a warning event and return
+	case errclass.IsNotFound(err):
+		// notifications of deleted buckets can't be delivered
+		event.Warn(ctx, ReasonUnsubscribed, "Bucket not found, skipping disabling of event notifications")
+		return nil
+	case errclass.IsDenied(err):
+		// the finalizer can only recover from auth errors if the
+		// permissions are granted, or if the deletion policy is
+		// changed to retain AWS resources
 		return fmt.Errorf("Authorization error getting bucket configuration: %v", toErrMsg(err))
 	case err != nil:
 		return fmt.Errorf("Error reading current event notifications configuration: %v", toErrMsg(err))
 	}
 
+	numQueueCfgs, numTopicCfgs := len(notifCfg.QueueConfigurations), len(notifCfg.TopicConfigurations)
+
+	// The EventBridge configuration is shared by all EventBridge rules
+	// which match events from the bucket, so it is left enabled.
 	notifCfg = removeQueueConfiguration(notifCfg, sourceID(src))
+	notifCfg = removeTopicConfiguration(notifCfg, sourceID(src))
+
+	if len(notifCfg.QueueConfigurations) == numQueueCfgs && len(notifCfg.TopicConfigurations) == numTopicCfgs {
+		return nil
+	}
 
 	if err := configureNotifications(ctx, cli, bucketARN.Resource, notifCfg); err != nil {
 		return fmt.Errorf("Error configuring event notifications: %v", toErrMsg(err))
 	}
+	event.Normal(ctx, ReasonUnsubscribed, "Disabled event notifications on S3 bucket %q", bucketARN.Resource)
 
 	return nil
 }
@@ -119,15 +142,97 @@ func configureNotifications(ctx context.Context, cli s3iface.S3API, bucket strin
 	return nil
 }
 
+// setDestinationConfiguration sets/updates the configuration of the
+// destination of the given source in the given NotificationConfiguration, and
+// removes the configurations of other destinations previously set for the
+// source.
+// The returned boolean value indicates whether some updates need to be applied
+// to the bucket configuration.
+func setDestinationConfiguration(nCfg *s3.NotificationConfiguration,
+	src *v1alpha1.AWSS3Source, destARN string) (*s3.NotificationConfiguration, bool) {
+
+	id := sourceID(src)
+	numQueueCfgs, numTopicCfgs := len(nCfg.QueueConfigurations), len(nCfg.TopicConfigurations)
+
+	var hasUpdates bool
+
+	switch {
+	case eventBridgeDestination(src) != nil:
+		if nCfg.EventBridgeConfiguration == nil {
+			nCfg.EventBridgeConfiguration = &s3.EventBridgeConfiguration{}
+			hasUpdates = true
+		}
+		nCfg = removeQueueConfiguration(nCfg, id)
+		nCfg = removeTopicConfiguration(nCfg, id)
+
+	case snsDestination(src) != nil:
+		nCfg, hasUpdates = setTopicConfiguration(nCfg, makeTopicConfiguration(src, destARN))
+		nCfg = removeQueueConfiguration(nCfg, id)
+
+	default:
+		nCfg, hasUpdates = setQueueConfiguration(nCfg, makeQueueConfiguration(src, destARN))
+		nCfg = removeTopicConfiguration(nCfg, id)
+	}
+
+	hasUpdates = hasUpdates ||
+		len(nCfg.QueueConfigurations) < numQueueCfgs ||
+		len(nCfg.TopicConfigurations) < numTopicCfgs
+
+	return nCfg, hasUpdates
+}
+
 // makeQueueConfiguration returns a QueueConfiguration for the given source.
 func makeQueueConfiguration(src *v1alpha1.AWSS3Source, queueARN string) *s3.QueueConfiguration {
 	return &s3.QueueConfiguration{
 		Id:       aws.String(sourceID(src)),
 		Events:   aws.StringSlice(src.Spec.EventTypes),
+		Filter:   makeNotificationFilter(src.Spec.Filter),
 		QueueArn: &queueARN,
 	}
 }
 
+// makeTopicConfiguration returns a TopicConfiguration for the given source.
+func makeTopicConfiguration(src *v1alpha1.AWSS3Source, topicARN string) *s3.TopicConfiguration {
+	return &s3.TopicConfiguration{
+		Id:       aws.String(sourceID(src)),
+		Events:   aws.StringSlice(src.Spec.EventTypes),
+		Filter:   makeNotificationFilter(src.Spec.Filter),
+		TopicArn: &topicARN,
+	}
+}
+
+// makeNotificationFilter returns a NotificationConfigurationFilter for the
+// given key filter rules, or nil if no rule is set.
+func makeNotificationFilter(f *v1alpha1.AWSS3SourceFilter) *s3.NotificationConfigurationFilter {
+	if f == nil {
+		return nil
+	}
+
+	var rules []*s3.FilterRule
+	if f.Prefix != "" {
+		rules = append(rules, &s3.FilterRule{
+			Name:  aws.String(s3.FilterRuleNamePrefix),
+			Value: aws.String(f.Prefix),
+		})
+	}
+	if f.Suffix != "" {
+		rules = append(rules, &s3.FilterRule{
+			Name:  aws.String(s3.FilterRuleNameSuffix),
+			Value: aws.String(f.Suffix),
+		})
+	}
+
+	if len(rules) == 0 {
+		return nil
+	}
+
+	return &s3.NotificationConfigurationFilter{
+		Key: &s3.KeyFilter{
+			FilterRules: rules,
+		},
+	}
+}
+
 // setQueueConfiguration sets/updates a QueueConfiguration in the given
 // NotificationConfiguration, without touching existing configurations.
 // The returned boolean value indicates whether some updates need to be applied
@@ -140,7 +245,9 @@ func setQueueConfiguration(nCfg *s3.NotificationConfiguration, qCfg *s3.QueueCon
 		if *cfg.Id == *qCfg.Id {
 			isSet = true
 			nCfg.QueueConfigurations[i] = qCfg
-			hasUpdates = !equalEventTypes(qCfg.Events, cfg.Events)
+			hasUpdates = !equalEventTypes(qCfg.Events, cfg.Events) ||
+				!equalFilters(qCfg.Filter, cfg.Filter) ||
+				aws.StringValue(qCfg.QueueArn) != aws.StringValue(cfg.QueueArn)
 			break
 		}
 	}
@@ -152,6 +259,32 @@ func setQueueConfiguration(nCfg *s3.NotificationConfiguration, qCfg *s3.QueueCon
 	return nCfg, hasUpdates
 }
 
+// setTopicConfiguration sets/updates a TopicConfiguration in the given
+// NotificationConfiguration, without touching existing configurations.
+// The returned boolean value indicates whether some updates need to be applied
+// to the bucket configuration.
+func setTopicConfiguration(nCfg *s3.NotificationConfiguration, tCfg *s3.TopicConfiguration) (*s3.NotificationConfiguration, bool) {
+	var isSet bool
+	var hasUpdates bool
+
+	for i, cfg := range nCfg.TopicConfigurations {
+		if *cfg.Id == *tCfg.Id {
+			isSet = true
+			nCfg.TopicConfigurations[i] = tCfg
+			hasUpdates = !equalEventTypes(tCfg.Events, cfg.Events) ||
+				!equalFilters(tCfg.Filter, cfg.Filter) ||
+				aws.StringValue(tCfg.TopicArn) != aws.StringValue(cfg.TopicArn)
+			break
+		}
+	}
+	if !isSet {
+		nCfg.TopicConfigurations = append(nCfg.TopicConfigurations, tCfg)
+		hasUpdates = true
+	}
+
+	return nCfg, hasUpdates
+}
+
 // equalEventTypes returns whether two lists of bucket event types are
 // semantically equal.
 // "b" must be the "current" state, which is expected to always be returned
@@ -174,6 +307,41 @@ func equalEventTypes(a, b []*string) bool {
 	return true
 }
 
+// equalFilters returns whether two notification filters are semantically
+// equal.
+// The S3 API returns the names of filter rules capitalized ("Prefix",
+// "Suffix"), so names are compared case-insensitively.
+func equalFilters(a, b *s3.NotificationConfigurationFilter) bool {
+	rulesA, rulesB := filterRules(a), filterRules(b)
+
+	if len(rulesA) != len(rulesB) {
+		return false
+	}
+
+	for name, val := range rulesA {
+		if v, ok := rulesB[name]; !ok || v != val {
+			return false
+		}
+	}
+
+	return true
+}
+
+// filterRules returns the key filter rules of the given notification filter,
+// indexed by lowercase rule name.
+func filterRules(f *s3.NotificationConfigurationFilter) map[string]string {
+	if f == nil || f.Key == nil {
+		return nil
+	}
+
+	rules := make(map[string]string, len(f.Key.FilterRules))
+	for _, r := range f.Key.FilterRules {
+		rules[strings.ToLower(aws.StringValue(r.Name))] = aws.StringValue(r.Value)
+	}
+
+	return rules
+}
+
 // removeQueueConfiguration removes a QueueConfiguration by ID from the given
 // NotificationConfiguration, without touching other configurations.
 func removeQueueConfiguration(nCfg *s3.NotificationConfiguration, id string) *s3.NotificationConfiguration {
@@ -190,40 +358,20 @@ func removeQueueConfiguration(nCfg *s3.NotificationConfiguration, id string) *s3
 	return nCfg
 }
 
-// isNotFound returns whether the given error indicates that some resource was
-// not found.
-func isNotFound(err error) bool {
-	if k8sErr := apierrors.APIStatus(nil); errors.As(err, &k8sErr) {
-		return k8sErr.Status().Reason == metav1.StatusReasonNotFound
-	}
-	if awsErr := awserr.Error(nil); errors.As(err, &awsErr) {
-		errcode := awsErr.Code()
-		return errcode == sqs.ErrCodeQueueDoesNotExist ||
-			errcode == s3.ErrCodeNoSuchBucket
-	}
-	return false
-}
-
-// isDenied returns whether the given error indicates that a request to the AWS
-// API could not be authorized.
-func isDenied(err error) bool {
-	if awsErr := awserr.Error(nil); errors.As(err, &awsErr) {
-		if awsErr == credentials.ErrStaticCredentialsEmpty {
-			return true
-		}
+// removeTopicConfiguration removes a TopicConfiguration by ID from the given
+// NotificationConfiguration, without touching other configurations.
+func removeTopicConfiguration(nCfg *s3.NotificationConfiguration, id string) *s3.NotificationConfiguration {
+	tCfgs := nCfg.TopicConfigurations[:0]
 
-		if awsReqFail := awserr.RequestFailure(nil); errors.As(err, &awsReqFail) {
-			code := awsReqFail.StatusCode()
-			return code == http.StatusUnauthorized || code == http.StatusForbidden
+	for _, cfg := range nCfg.TopicConfigurations {
+		if *cfg.Id != id {
+			tCfgs = append(tCfgs, cfg)
 		}
 	}
-	return false
-}
 
-// isAWSError returns whether the given error is an AWS API error.
-func isAWSError(err error) bool {
-	awsErr := awserr.Error(nil)
-	return errors.As(err, &awsErr)
+	nCfg.TopicConfigurations = tCfgs
+
+	return nCfg
 }
 
 // toErrMsg attempts to extract the message from the given error if it is an
@@ -0,0 +1,207 @@
+/*
+Copyright 2023 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package awss3source
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+
+	"github.com/triggermesh/scoby-hook-triggermesh/pkg/apis/sources/v1alpha1"
+)
+
+func TestSetQueueConfiguration(t *testing.T) {
+	const (
+		id       = "io.triggermesh.awss3sources.ns.name"
+		queueARN = "arn:aws:sqs:eu-west-1:123456789012:my-queue"
+	)
+
+	// currentCfg returns a queue configuration as returned by the S3 API.
+	currentCfg := func(filter *s3.NotificationConfigurationFilter, events ...string) *s3.QueueConfiguration {
+		return &s3.QueueConfiguration{
+			Id:       aws.String(id),
+			Events:   aws.StringSlice(events),
+			Filter:   filter,
+			QueueArn: aws.String(queueARN),
+		}
+	}
+	keyFilter := func(rules ...string) *s3.NotificationConfigurationFilter {
+		f := &s3.NotificationConfigurationFilter{Key: &s3.KeyFilter{}}
+		for i := 0; i < len(rules); i += 2 {
+			f.Key.FilterRules = append(f.Key.FilterRules, &s3.FilterRule{
+				Name:  aws.String(rules[i]),
+				Value: aws.String(rules[i+1]),
+			})
+		}
+		return f
+	}
+
+	testCases := map[string]struct {
+		current      []*s3.QueueConfiguration
+		eventTypes   []string
+		filter       *v1alpha1.AWSS3SourceFilter
+		expectUpdate bool
+	}{
+		"not configured": {
+			eventTypes:   []string{"s3:ObjectCreated:*"},
+			expectUpdate: true,
+		},
+		"up to date without filter": {
+			current:    []*s3.QueueConfiguration{currentCfg(nil, "s3:ObjectCreated:*", "s3:ObjectRemoved:*")},
+			eventTypes: []string{"s3:ObjectRemoved:*", "s3:ObjectCreated:*"},
+		},
+		"up to date with filter": {
+			current:    []*s3.QueueConfiguration{currentCfg(keyFilter("Suffix", ".jpg", "Prefix", "images/"), "s3:ObjectCreated:*")},
+			eventTypes: []string{"s3:ObjectCreated:*"},
+			filter:     &v1alpha1.AWSS3SourceFilter{Prefix: "images/", Suffix: ".jpg"},
+		},
+		"event types drift": {
+			current:      []*s3.QueueConfiguration{currentCfg(nil, "s3:ObjectCreated:*")},
+			eventTypes:   []string{"s3:ObjectRemoved:*"},
+			expectUpdate: true,
+		},
+		"filter added": {
+			current:      []*s3.QueueConfiguration{currentCfg(nil, "s3:ObjectCreated:*")},
+			eventTypes:   []string{"s3:ObjectCreated:*"},
+			filter:       &v1alpha1.AWSS3SourceFilter{Prefix: "images/"},
+			expectUpdate: true,
+		},
+		"filter changed": {
+			current:      []*s3.QueueConfiguration{currentCfg(keyFilter("Prefix", "images/"), "s3:ObjectCreated:*")},
+			eventTypes:   []string{"s3:ObjectCreated:*"},
+			filter:       &v1alpha1.AWSS3SourceFilter{Prefix: "videos/"},
+			expectUpdate: true,
+		},
+		"filter removed": {
+			current:      []*s3.QueueConfiguration{currentCfg(keyFilter("Prefix", "images/"), "s3:ObjectCreated:*")},
+			eventTypes:   []string{"s3:ObjectCreated:*"},
+			filter:       &v1alpha1.AWSS3SourceFilter{},
+			expectUpdate: true,
+		},
+		"queue changed": {
+			current: []*s3.QueueConfiguration{{
+				Id:       aws.String(id),
+				Events:   aws.StringSlice([]string{"s3:ObjectCreated:*"}),
+				QueueArn: aws.String("arn:aws:sqs:eu-west-1:123456789012:s3-events_my-bucket"),
+			}},
+			eventTypes:   []string{"s3:ObjectCreated:*"},
+			expectUpdate: true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			src := &v1alpha1.AWSS3Source{}
+			src.Namespace, src.Name = "ns", "name"
+			src.Spec.EventTypes = tc.eventTypes
+			src.Spec.Filter = tc.filter
+
+			desired := makeQueueConfiguration(src, queueARN)
+
+			nCfg, hasUpdates := setQueueConfiguration(&s3.NotificationConfiguration{
+				QueueConfigurations: tc.current,
+			}, desired)
+
+			assert.Equal(t, tc.expectUpdate, hasUpdates)
+			assert.Equal(t, []*s3.QueueConfiguration{desired}, nCfg.QueueConfigurations)
+		})
+	}
+}
+
+func TestSetDestinationConfiguration(t *testing.T) {
+	const (
+		id       = "io.triggermesh.awss3sources.ns.name"
+		queueARN = "arn:aws:sqs:eu-west-1:123456789012:my-queue"
+		topicARN = "arn:aws:sns:eu-west-1:123456789012:my-topic"
+	)
+
+	otherQueueCfg := &s3.QueueConfiguration{Id: aws.String("other"), QueueArn: aws.String(queueARN)}
+	ownQueueCfg := &s3.QueueConfiguration{Id: aws.String(id), QueueArn: aws.String(queueARN)}
+	ownTopicCfg := &s3.TopicConfiguration{Id: aws.String(id), TopicArn: aws.String(topicARN)}
+
+	testCases := map[string]struct {
+		current      *s3.NotificationConfiguration
+		destination  *v1alpha1.AWSS3SourceDestination
+		destARN      string
+		expectUpdate bool
+		expect       func(*testing.T, *s3.NotificationConfiguration)
+	}{
+		"switch from SQS to SNS": {
+			current: &s3.NotificationConfiguration{
+				QueueConfigurations: []*s3.QueueConfiguration{otherQueueCfg, ownQueueCfg},
+			},
+			destination:  &v1alpha1.AWSS3SourceDestination{SNS: &v1alpha1.AWSS3SourceDestinationSNS{}},
+			destARN:      topicARN,
+			expectUpdate: true,
+			expect: func(t *testing.T, nCfg *s3.NotificationConfiguration) {
+				assert.Equal(t, []*s3.QueueConfiguration{otherQueueCfg}, nCfg.QueueConfigurations)
+				if assert.Len(t, nCfg.TopicConfigurations, 1) {
+					assert.Equal(t, topicARN, *nCfg.TopicConfigurations[0].TopicArn)
+				}
+			},
+		},
+		"switch from SNS to EventBridge": {
+			current: &s3.NotificationConfiguration{
+				TopicConfigurations: []*s3.TopicConfiguration{ownTopicCfg},
+			},
+			destination:  &v1alpha1.AWSS3SourceDestination{EventBridge: &v1alpha1.AWSS3SourceDestinationEventBridge{}},
+			expectUpdate: true,
+			expect: func(t *testing.T, nCfg *s3.NotificationConfiguration) {
+				assert.Empty(t, nCfg.TopicConfigurations)
+				assert.NotNil(t, nCfg.EventBridgeConfiguration)
+			},
+		},
+		"EventBridge already enabled": {
+			current: &s3.NotificationConfiguration{
+				QueueConfigurations:      []*s3.QueueConfiguration{otherQueueCfg},
+				EventBridgeConfiguration: &s3.EventBridgeConfiguration{},
+			},
+			destination: &v1alpha1.AWSS3SourceDestination{EventBridge: &v1alpha1.AWSS3SourceDestinationEventBridge{}},
+			expect: func(t *testing.T, nCfg *s3.NotificationConfiguration) {
+				assert.Equal(t, []*s3.QueueConfiguration{otherQueueCfg}, nCfg.QueueConfigurations)
+			},
+		},
+		"switch from EventBridge to SQS": {
+			current: &s3.NotificationConfiguration{
+				EventBridgeConfiguration: &s3.EventBridgeConfiguration{},
+			},
+			destARN:      queueARN,
+			expectUpdate: true,
+			expect: func(t *testing.T, nCfg *s3.NotificationConfiguration) {
+				assert.Len(t, nCfg.QueueConfigurations, 1)
+				assert.NotNil(t, nCfg.EventBridgeConfiguration, "Expected the shared EventBridge configuration to be preserved")
+			},
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			src := &v1alpha1.AWSS3Source{}
+			src.Namespace, src.Name = "ns", "name"
+			src.Spec.EventTypes = []string{"s3:ObjectCreated:*"}
+			src.Spec.Destination = tc.destination
+
+			nCfg, hasUpdates := setDestinationConfiguration(tc.current, src, tc.destARN)
+
+			assert.Equal(t, tc.expectUpdate, hasUpdates)
+			tc.expect(t, nCfg)
+		})
+	}
+}
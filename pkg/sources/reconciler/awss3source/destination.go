@@ -0,0 +1,51 @@
+/*
+Copyright 2023 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package awss3source
+
+import (
+	"github.com/triggermesh/scoby-hook-triggermesh/pkg/apis"
+	"github.com/triggermesh/scoby-hook-triggermesh/pkg/apis/sources/v1alpha1"
+)
+
+// snsDestination returns the SNS destination of the given source, if any.
+func snsDestination(src *v1alpha1.AWSS3Source) *v1alpha1.AWSS3SourceDestinationSNS {
+	if dest := src.Spec.Destination; dest != nil {
+		return dest.SNS
+	}
+	return nil
+}
+
+// eventBridgeDestination returns the EventBridge destination of the given
+// source, if any.
+func eventBridgeDestination(src *v1alpha1.AWSS3Source) *v1alpha1.AWSS3SourceDestinationEventBridge {
+	if dest := src.Spec.Destination; dest != nil {
+		return dest.EventBridge
+	}
+	return nil
+}
+
+// bucketLocalARN returns the ARN of an AWS resource which resides in the same
+// region and account as the S3 bucket of the given source.
+func bucketLocalARN(src *v1alpha1.AWSS3Source, service, resource string) string {
+	return apis.ARN{
+		Partition: src.Spec.ARN.Partition,
+		Service:   service,
+		Region:    src.Spec.ARN.Region,
+		AccountID: src.Spec.ARN.AccountID,
+		Resource:  resource,
+	}.String()
+}
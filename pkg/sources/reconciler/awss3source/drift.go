@@ -0,0 +1,160 @@
+/*
+Copyright 2023 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package awss3source
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/triggermesh/scoby-hook-triggermesh/pkg/apis/sources/v1alpha1"
+)
+
+// observeMode returns whether the AWS resources of the given source are only
+// observed by the hook instead of being managed.
+func observeMode(src *v1alpha1.AWSS3Source, forced bool) bool {
+	return forced || src.Spec.ReconcileMode == v1alpha1.AWSS3SourceReconcileModeObserve
+}
+
+// drift is a difference between the current and desired state of an AWS
+// resource.
+type drift struct {
+	// description of the drifted resource or attribute
+	what string
+	// readable diff between the current and desired state
+	diff string
+}
+
+// driftReport collects the drifts detected while reconciling a source in the
+// Observe mode.
+type driftReport struct {
+	drifts []drift
+}
+
+// Summary returns the list of drifted resources in the report. Unlike diffs,
+// whose format isn't stable, it is suitable for the status of sources.
+func (r *driftReport) Summary() string {
+	whats := make([]string, len(r.drifts))
+	for i, d := range r.drifts {
+		whats[i] = d.what
+	}
+	return "AWS resources differ from their desired state: " + strings.Join(whats, ", ")
+}
+
+// String returns a readable summary of the drifts in the report, including
+// their diffs.
+func (r *driftReport) String() string {
+	var sb strings.Builder
+	for i, d := range r.drifts {
+		if i > 0 {
+			sb.WriteString("\n")
+		}
+		sb.WriteString(d.what)
+		sb.WriteString(" (-current +desired):\n")
+		sb.WriteString(d.diff)
+	}
+	return sb.String()
+}
+
+// driftReportKey is the context key for the drift report.
+type driftReportKey struct{}
+
+// withDriftReport returns a copy of the parent context in which changes to
+// AWS resources are recorded in the returned report instead of being applied.
+func withDriftReport(ctx context.Context) (context.Context, *driftReport) {
+	r := &driftReport{}
+	return context.WithValue(ctx, driftReportKey{}, r), r
+}
+
+// isObserving returns whether changes to AWS resources must be reported
+// instead of being applied.
+func isObserving(ctx context.Context) bool {
+	_, ok := ctx.Value(driftReportKey{}).(*driftReport)
+	return ok
+}
+
+// reportDrift records a difference between the current and desired state of
+// an AWS resource in the drift report of the context, if any. It returns
+// whether the difference was recorded, in which case the caller must not
+// apply the desired state.
+//
+// JSON documents, such as IAM policies, can be passed as strings so that
+// their content gets compared instead of their serialization.
+func reportDrift(ctx context.Context, what string, current, desired interface{}) bool {
+	r, ok := ctx.Value(driftReportKey{}).(*driftReport)
+	if !ok {
+		return false
+	}
+
+	r.drifts = append(r.drifts, drift{
+		what: what,
+		diff: cmp.Diff(comparableValue(current), comparableValue(desired)),
+	})
+
+	return true
+}
+
+// comparableValue returns a generic representation of the given value, as
+// obtained by decoding its JSON serialization. Nil attributes of AWS API
+// types are omitted to keep the diff readable.
+func comparableValue(v interface{}) interface{} {
+	var b []byte
+
+	switch vt := v.(type) {
+	case nil:
+		return nil
+	case string:
+		if !json.Valid([]byte(vt)) {
+			return vt
+		}
+		b = []byte(vt)
+	default:
+		var err error
+		if b, err = json.Marshal(v); err != nil {
+			return fmt.Sprintf("%+v", v)
+		}
+	}
+
+	var generic interface{}
+	if err := json.Unmarshal(b, &generic); err != nil {
+		return string(b)
+	}
+
+	return withoutNulls(generic)
+}
+
+// withoutNulls removes the null attributes of the given generic JSON value.
+func withoutNulls(v interface{}) interface{} {
+	switch vt := v.(type) {
+	case map[string]interface{}:
+		for k, attr := range vt {
+			if attr == nil {
+				delete(vt, k)
+				continue
+			}
+			vt[k] = withoutNulls(attr)
+		}
+	case []interface{}:
+		for i := range vt {
+			vt[i] = withoutNulls(vt[i])
+		}
+	}
+	return v
+}
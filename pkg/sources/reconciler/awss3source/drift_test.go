@@ -0,0 +1,67 @@
+/*
+Copyright 2023 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package awss3source
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReportDrift(t *testing.T) {
+	assert.False(t, reportDrift(context.Background(), "resource", "a", "b"),
+		"Expected drifts to be ignored outside of the Observe mode")
+
+	ctx, drifts := withDriftReport(context.Background())
+	require.True(t, isObserving(ctx))
+
+	currentPol := `{"Version":"2012-10-17","Statement":[{"Sid":"a","Effect":"Allow"}]}`
+	desiredPol := `{
+  "Version": "2012-10-17",
+  "Statement": [{"Sid": "a", "Effect": "Allow"}, {"Sid": "b", "Effect": "Allow"}]
+}`
+
+	currentCfg := &s3.NotificationConfiguration{}
+	desiredCfg := &s3.NotificationConfiguration{
+		QueueConfigurations: []*s3.QueueConfiguration{{
+			Id:       aws.String("my-id"),
+			QueueArn: aws.String("arn:aws:sqs:eu-west-1:123456789012:my-queue"),
+		}},
+	}
+
+	assert.True(t, reportDrift(ctx, "policy", currentPol, desiredPol))
+	assert.True(t, reportDrift(ctx, "config", currentCfg, desiredCfg))
+
+	require.Len(t, drifts.drifts, 2)
+
+	polDiff := drifts.drifts[0].diff
+	assert.Contains(t, polDiff, `"b"`, "Expected the added statement to be reported")
+	assert.NotContains(t, polDiff, "- \t", "Expected unchanged statements not to be reported as removed")
+
+	cfgDiff := drifts.drifts[1].diff
+	assert.Contains(t, cfgDiff, "my-queue")
+	assert.NotContains(t, cfgDiff, "Filter", "Expected nil attributes to be omitted")
+
+	assert.Equal(t, "AWS resources differ from their desired state: policy, config", drifts.Summary())
+
+	assert.Contains(t, drifts.String(), "policy (-current +desired):\n")
+	assert.Contains(t, drifts.String(), "\nconfig (-current +desired):\n")
+}
@@ -0,0 +1,61 @@
+/*
+Copyright 2023 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package awss3source
+
+// Reasons for API Events
+const (
+	// ReasonQueueCreated indicates that a SQS queue was created for receiving S3 event notifications.
+	ReasonQueueCreated = "QueueCreated"
+	// ReasonQueueDeleted indicates that the SQS queue created for receiving S3 event notifications was deleted.
+	ReasonQueueDeleted = "QueueDeleted"
+	// ReasonQueueMigrated indicates that messages were moved from a SQS queue created under a legacy name.
+	ReasonQueueMigrated = "QueueMigrated"
+	// ReasonFailedQueue indicates a failure while synchronizing the SQS queue for receiving S3 event notifications.
+	ReasonFailedQueue = "FailedQueue"
+	// ReasonTopicDeleted indicates that the SNS topic created for receiving S3 event notifications was deleted.
+	ReasonTopicDeleted = "TopicDeleted"
+	// ReasonFailedTopic indicates a failure while synchronizing the SNS topic for receiving S3 event notifications.
+	ReasonFailedTopic = "FailedTopic"
+	// ReasonRuleCreated indicates that an EventBridge rule was created for routing S3 event notifications.
+	ReasonRuleCreated = "RuleCreated"
+	// ReasonRuleDeleted indicates that the EventBridge rule created for routing S3 event notifications was deleted.
+	ReasonRuleDeleted = "RuleDeleted"
+	// ReasonFailedRule indicates a failure while synchronizing the EventBridge rule for routing S3 event notifications.
+	ReasonFailedRule = "FailedRule"
+
+	// ReasonServiceAccountCreated indicates that a ServiceAccount was created for assuming the IAM role of a source.
+	ReasonServiceAccountCreated = "ServiceAccountCreated"
+	// ReasonServiceAccountUpdated indicates that the IAM role of the ServiceAccount of a source was updated.
+	ReasonServiceAccountUpdated = "ServiceAccountUpdated"
+	// ReasonFailedServiceAccount indicates a failure while synchronizing the ServiceAccount of a source.
+	ReasonFailedServiceAccount = "FailedServiceAccount"
+
+	// ReasonObserved indicates that the AWS resources of a source in the Observe reconcile mode were left untouched.
+	ReasonObserved = "Observed"
+
+	// ReasonRetained indicates that AWS resources of a deleted source were retained according to its deletion policy.
+	ReasonRetained = "Retained"
+
+	// ReasonSubscribed indicates that event notifications were enabled on a S3 bucket.
+	ReasonSubscribed = "Subscribed"
+	// ReasonUnsubscribed indicates that event notifications were disabled on a S3 bucket.
+	ReasonUnsubscribed = "Unsubscribed"
+	// ReasonFailedSubscribe indicates a failure while enabling event notifications on a S3 bucket.
+	ReasonFailedSubscribe = "FailedSubscribe"
+	// ReasonFailedUnsubscribe indicates a failure while disabling event notifications on a S3 bucket.
+	ReasonFailedUnsubscribe = "FailedUnsubscribe"
+)
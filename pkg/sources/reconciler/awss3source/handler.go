@@ -8,6 +8,7 @@ import (
 
 	"go.uber.org/zap"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 
 	commonv1alpha1 "github.com/triggermesh/scoby/pkg/apis/common/v1alpha1"
@@ -15,6 +16,7 @@ import (
 
 	"github.com/triggermesh/scoby-hook-triggermesh/pkg/apis/sources/v1alpha1"
 	"github.com/triggermesh/scoby-hook-triggermesh/pkg/handler"
+	"github.com/triggermesh/scoby-hook-triggermesh/pkg/reconciler/event"
 	s3client "github.com/triggermesh/scoby-hook-triggermesh/pkg/sources/client/s3"
 )
 
@@ -22,15 +24,38 @@ type AWSS3Handler struct {
 	gvr  schema.GroupVersionResource
 	kind string
 
-	// Getter than can obtain clients for interacting with the S3 and SQS APIs
+	// Getter than can obtain clients for interacting with the S3 API and the
+	// APIs of intermediate destinations of notifications (SQS, SNS, EventBridge)
 	s3Cg s3client.ClientGetter
-	log  *zap.SugaredLogger
+	// Getter for the ServiceAccounts which carry the EKS IAM role of
+	// sources
+	sag s3client.NamespacedServiceAccountsGetter
+	log *zap.SugaredLogger
+
+	// whether AWS resources are observed instead of managed for every
+	// source, regardless of its reconcile mode
+	observeOnly bool
 }
 
-var _ handler.Handler = (*AWSS3Handler)(nil)
+var (
+	_ handler.Handler            = (*AWSS3Handler)(nil)
+	_ handler.HandlerFinalizable = (*AWSS3Handler)(nil)
+	_ handler.HandlerConditions  = (*AWSS3Handler)(nil)
+)
+
+// Option is a functional option for an AWSS3Handler.
+type Option func(*AWSS3Handler)
+
+// WithObserveOnly sets whether the AWS resources of every source are observed
+// instead of managed, as if all sources were in the Observe reconcile mode.
+func WithObserveOnly(observeOnly bool) Option {
+	return func(h *AWSS3Handler) {
+		h.observeOnly = observeOnly
+	}
+}
 
-func New(s3Cg s3client.ClientGetter, log *zap.SugaredLogger) *AWSS3Handler {
-	return &AWSS3Handler{
+func New(s3Cg s3client.ClientGetter, sag s3client.NamespacedServiceAccountsGetter, log *zap.SugaredLogger, opts ...Option) *AWSS3Handler {
+	h := &AWSS3Handler{
 		gvr: schema.GroupVersionResource{
 			Group:    "sources.triggermesh.io",
 			Version:  "v1alpha1",
@@ -39,8 +64,15 @@ func New(s3Cg s3client.ClientGetter, log *zap.SugaredLogger) *AWSS3Handler {
 		kind: "AWSS3Source",
 
 		s3Cg: s3Cg,
+		sag:  sag,
 		log:  log,
 	}
+
+	for _, opt := range opts {
+		opt(h)
+	}
+
+	return h
 }
 
 func (h *AWSS3Handler) GroupVersionResource() *schema.GroupVersionResource {
@@ -52,13 +84,59 @@ func (h *AWSS3Handler) Kind() string {
 	return h.kind
 }
 
-// func newSubscribedCondition() *commonv1alpha1.Condition {
-// 	return &commonv1alpha1.Condition{
-// 		Type:   "Subscribed",
-// 		Status: metav1.ConditionUnknown,
-// 		Reason: "Unknown",
-// 	}
-// }
+// NewObject returns an empty AWSS3Source.
+func (h *AWSS3Handler) NewObject() runtime.Object {
+	return &v1alpha1.AWSS3Source{}
+}
+
+// ConditionSubscribed reports whether the S3 bucket is configured to send
+// event notifications.
+const ConditionSubscribed = "Subscribed"
+
+// ConditionDrifted reports whether the AWS resources of a source in the
+// Observe reconcile mode differ from their desired state.
+//
+// Scoby derives the Ready condition from all the conditions listed in the
+// registration, which must all be True, so the status of this condition is
+// True when no drift was detected, and False with the DriftDetected reason
+// otherwise. It is always True for sources whose AWS resources are managed by
+// the hook.
+const ConditionDrifted = "Drifted"
+
+// Reasons of the Drifted condition.
+const (
+	ReasonDriftedManaged  = "Managed"
+	ReasonDriftedNoDrift  = "NoDrift"
+	ReasonDriftedDetected = "DriftDetected"
+)
+
+// InitialConditions implements handler.HandlerConditions.
+func (h *AWSS3Handler) InitialConditions(op hookv1.Operation) commonv1alpha1.Conditions {
+	if op == hookv1.OperationFinalize {
+		return commonv1alpha1.Conditions{
+			{
+				Type: ConditionSubscribed,
+				// True being set as the default value means that we are
+				// ok removing the component.
+				Status: metav1.ConditionTrue,
+				Reason: "",
+			},
+		}
+	}
+
+	return commonv1alpha1.Conditions{
+		{
+			Type:   ConditionSubscribed,
+			Status: metav1.ConditionUnknown,
+			Reason: "Unknown",
+		},
+		{
+			Type:   ConditionDrifted,
+			Status: metav1.ConditionUnknown,
+			Reason: "Unknown",
+		},
+	}
+}
 
 func (h *AWSS3Handler) Reconcile(ctx context.Context, obj metav1.Object) *hookv1.HookResponse {
 	src := obj.(*v1alpha1.AWSS3Source)
@@ -66,13 +144,7 @@ func (h *AWSS3Handler) Reconcile(ctx context.Context, obj metav1.Object) *hookv1
 	// intialize response
 	res := &hookv1.HookResponse{
 		Status: &hookv1.HookStatus{
-			Conditions: []commonv1alpha1.Condition{
-				{
-					Type:   "Subscribed",
-					Status: metav1.ConditionUnknown,
-					Reason: "Unknown",
-				},
-			},
+			Conditions: h.InitialConditions(hookv1.OperationReconcile),
 		},
 	}
 
@@ -82,102 +154,242 @@ func (h *AWSS3Handler) Reconcile(ctx context.Context, obj metav1.Object) *hookv1
 }
 
 func (h *AWSS3Handler) reconcile(ctx context.Context, src *v1alpha1.AWSS3Source, res *hookv1.HookResponse) {
-	s3Client, sqsClient, err := h.s3Cg.Get(src)
+	log := handler.LoggerFromContext(ctx, h.log)
+
+	// in the Observe mode, changes to AWS resources are collected in a
+	// report instead of being applied
+	var drifts *driftReport
+	if observeMode(src, h.observeOnly) {
+		ctx, drifts = withDriftReport(ctx)
+	}
+
+	// the ServiceAccount must exist before the client getter can request
+	// tokens for it
+	if _, err := EnsureServiceAccount(ctx, src, h.sag(src.Namespace)); err != nil {
+		markSubscribed(res, metav1.ConditionFalse, "ReconcileServiceAccount", "Failed to reconcile ServiceAccount")
+		handler.ReportError(ctx, err)
+		log.Error("Failed to reconcile ServiceAccount", zap.Error(err))
+		event.Warn(ctx, ReasonFailedServiceAccount, "Failed to reconcile ServiceAccount: %s", err)
+		return
+	}
+
+	clients, err := h.s3Cg.Get(ctx, src)
 	if err != nil {
-		subscribed := res.Status.Conditions.GetByType("Subscribed")
-		if subscribed == nil {
-			// Panic protection, this should not happen
-			h.log.Error("Subscribed condition not found")
-			return
-		}
-		subscribed.Status = metav1.ConditionFalse
-		subscribed.Reason = "NoClient"
-		subscribed.Message = "Cannot obtain AWS API clients"
-		h.log.Error("Error creating AWS API clients", zap.Error(err))
+		markSubscribed(res, metav1.ConditionFalse, "NoClient", "Cannot obtain AWS API clients")
+		handler.ReportError(ctx, err)
+		log.Error("Error creating AWS API clients", zap.Error(err))
+		event.Warn(ctx, ReasonFailedSubscribe, "Cannot obtain AWS API clients: %s", toErrMsg(err))
 		return
 	}
 
-	queueARN, err := EnsureQueue(ctx, src, sqsClient)
+	// the bucket region and owner account are resolved by the client getter,
+	// the queue ARN by EnsureQueue
+	defer setStatusAnnotations(res, src)
+
+	queueARN, err := EnsureQueue(ctx, src, clients.SQS)
 	if err != nil {
-		subscribed := res.Status.Conditions[0]
-		subscribed.Status = metav1.ConditionFalse
-		subscribed.Reason = "ReconcileQueue"
-		subscribed.Message = "Failed to reconcile SQS queue"
-		h.log.Error("Failed to reconcile SQS queue", zap.Error(err))
+		markSubscribed(res, metav1.ConditionFalse, "ReconcileQueue", "Failed to reconcile SQS queue")
+		handler.ReportError(ctx, err)
+		log.Error("Failed to reconcile SQS queue", zap.Error(err))
+		event.Warn(ctx, ReasonFailedQueue, "Failed to reconcile SQS queue: %s", toErrMsg(err))
 		return
 	}
 
-	err = EnsureNotificationsEnabled(ctx, src, s3Client, queueARN)
+	// the adapter can be configured as soon as the queue exists, even if
+	// notifications are not yet enabled on the bucket. In the Observe mode,
+	// the queue may not exist, in which case the other AWS resources are
+	// still observed
+	queueExists := src.Status.QueueARN != nil
+	if queueExists {
+		res.EnvVars = MakeAppEnv(src)
+	}
+
+	destARN := queueARN
+
+	switch {
+	case snsDestination(src) != nil:
+		if destARN, err = EnsureTopic(ctx, src, clients.SNS, queueARN); err != nil {
+			markSubscribed(res, metav1.ConditionFalse, "ReconcileTopic", "Failed to reconcile SNS topic")
+			handler.ReportError(ctx, err)
+			log.Error("Failed to reconcile SNS topic", zap.Error(err))
+			event.Warn(ctx, ReasonFailedTopic, "Failed to reconcile SNS topic: %s", toErrMsg(err))
+			return
+		}
+
+	case eventBridgeDestination(src) != nil:
+		if err = EnsureRule(ctx, src, clients.EventBridge, queueARN); err != nil {
+			markSubscribed(res, metav1.ConditionFalse, "ReconcileRule", "Failed to reconcile EventBridge rule")
+			handler.ReportError(ctx, err)
+			log.Error("Failed to reconcile EventBridge rule", zap.Error(err))
+			event.Warn(ctx, ReasonFailedRule, "Failed to reconcile EventBridge rule: %s", toErrMsg(err))
+			return
+		}
+	}
+
+	err = EnsureNotificationsEnabled(ctx, src, clients.S3, destARN)
 	if err != nil {
-		subscribed := res.Status.Conditions[0]
-		subscribed.Status = metav1.ConditionFalse
-		subscribed.Reason = "ConfigureNotifications"
-		subscribed.Message = "Cannot configure SQS notifications"
-		h.log.Error("Failed to configure SQS queue notifications", zap.Error(err))
+		markSubscribed(res, metav1.ConditionFalse, "ConfigureNotifications", "Cannot configure SQS notifications")
+		handler.ReportError(ctx, err)
+		log.Error("Failed to configure SQS queue notifications", zap.Error(err))
+		event.Warn(ctx, ReasonFailedSubscribe, "Cannot configure SQS notifications: %s", toErrMsg(err))
 		return
 	}
 
-	subscribed := res.Status.Conditions[0]
-	subscribed.Status = metav1.ConditionTrue
-	subscribed.Reason = ""
+	// failing to migrate a legacy queue does not prevent notifications from
+	// being delivered, so it is retried during the next reconciliation
+	// without affecting the Subscribed condition
+	if drifts == nil {
+		if err := MigrateLegacyQueue(ctx, src, clients.SQS); err != nil {
+			log.Error("Failed to migrate legacy SQS queue", zap.Error(err))
+			event.Warn(ctx, ReasonFailedQueue, "Failed to migrate legacy SQS queue: %s", toErrMsg(err))
+		}
+	}
+
+	if queueExists {
+		markSubscribed(res, metav1.ConditionTrue, "", "")
+	} else {
+		markSubscribed(res, metav1.ConditionFalse, "QueueNotCreated",
+			"The SQS queue does not exist and is not created in the Observe reconcile mode")
+	}
 
-	return
+	switch {
+	case drifts == nil:
+		markCondition(res, ConditionDrifted, metav1.ConditionTrue, ReasonDriftedManaged,
+			"AWS resources are managed by the hook")
+	case len(drifts.drifts) == 0:
+		markCondition(res, ConditionDrifted, metav1.ConditionTrue, ReasonDriftedNoDrift,
+			"AWS resources match their desired state")
+	default:
+		log.Infow("AWS resources differ from their desired state", zap.String("drifts", drifts.String()))
+		markCondition(res, ConditionDrifted, metav1.ConditionFalse, ReasonDriftedDetected, drifts.Summary())
+	}
 }
 
 func (h *AWSS3Handler) Finalize(ctx context.Context, obj metav1.Object) *hookv1.HookResponse {
 	src := obj.(*v1alpha1.AWSS3Source)
 	res := &hookv1.HookResponse{
 		Status: &hookv1.HookStatus{
-			Conditions: []commonv1alpha1.Condition{
-				{
-					Type: "Subscribed",
-					// True being set as the default value means that we are
-					// ok removing the component.
-					Status: metav1.ConditionTrue,
-					Reason: "",
-				},
-			},
+			Conditions: h.InitialConditions(hookv1.OperationFinalize),
 		},
 	}
 
 	h.finalize(ctx, src, res)
 
+	// the source is deleted once finalized, after which the clients
+	// obtained for it aren't needed anymore
+	if c := res.Status.Conditions.GetByType(ConditionSubscribed); c != nil && c.Status == metav1.ConditionTrue {
+		h.s3Cg.Forget(src)
+	}
+
 	return res
 }
 
 func (h *AWSS3Handler) finalize(ctx context.Context, src *v1alpha1.AWSS3Source, res *hookv1.HookResponse) {
-	s3Client, sqsClient, err := h.s3Cg.Get(src)
-	switch {
-	case isNotFound(err):
-		// the finalizer is unlikely to recover from a missing Secret,
-		// so we simply record a warning event and return
-		h.log.Error("Secret missing while finalizing event source. Ignoring", zap.Error(err))
+	log := handler.LoggerFromContext(ctx, h.log)
+
+	// AWS resources which are only observed are left untouched
+	if observeMode(src, h.observeOnly) {
+		log.Info("Source in the Observe reconcile mode, AWS resources are left untouched")
+		event.Normal(ctx, ReasonObserved, "AWS resources were left untouched in the Observe reconcile mode")
+		return
+	}
+
+	policy := deletionPolicy(src)
+
+	if policy == v1alpha1.AWSS3SourceDeletionPolicyRetain {
+		log.Info("Retaining AWS resources according to the deletion policy")
+		event.Normal(ctx, ReasonRetained, "Retained bucket notifications and AWS resources according to the deletion policy")
+		return
+	}
+
+	// A missing Secret is reported like any other failure, since AWS
+	// resources can't be deleted without credentials. The finalizer
+	// recovers once the Secret is restored, or if the deletion policy is
+	// changed to retain AWS resources.
+	clients, err := h.s3Cg.Get(ctx, src)
+	if err != nil {
+		log.Error("Error creating AWS API clients", zap.Error(err))
+		event.Warn(ctx, ReasonFailedUnsubscribe, "Cannot obtain AWS API clients: %s", toErrMsg(err))
+		markSubscribed(res, metav1.ConditionFalse, "NoClient", "Cannot obtain AWS API clients")
+		handler.ReportError(ctx, err)
+		return
+	}
+
+	// Failures are reported through the Subscribed condition, which blocks
+	// the deletion of the source object until the finalizer succeeds, to
+	// ensure that we don't leave any dangling event notification
+	// configurations or AWS resources behind us.
+	// Notifications are disabled first, so that their destinations can be
+	// deleted safely. Every step is idempotent, so all steps are retried
+	// after a failure.
+	if err := EnsureNotificationsDisabled(ctx, src, clients.S3); err != nil {
+		markSubscribed(res, metav1.ConditionFalse, "DisableNotifications", "Failed to disable S3 notifications")
+		handler.ReportError(ctx, err)
+		log.Error("Failed to disable S3 notifications", zap.Error(err))
+		event.Warn(ctx, ReasonFailedUnsubscribe, "Failed to disable S3 notifications: %s", toErrMsg(err))
 		return
-	case err != nil:
-		h.log.Error("Error creating AWS API clients", zap.Error(err))
-		subscribed := res.Status.Conditions.GetByType("Subscribed")
-		if subscribed == nil {
-			// Panic protection, this should not happen
-			h.log.Error("Subscribed condition not found", zap.Error(err))
+	}
+
+	switch {
+	case snsDestination(src) != nil:
+		if err := EnsureNoTopic(ctx, src, clients.SNS); err != nil {
+			markSubscribed(res, metav1.ConditionFalse, "FinalizeTopic", "Failed to finalize SNS topic")
+			handler.ReportError(ctx, err)
+			log.Error("Failed to finalize SNS topic", zap.Error(err))
+			event.Warn(ctx, ReasonFailedUnsubscribe, "Failed to finalize SNS topic: %s", toErrMsg(err))
+			return
+		}
+
+	case eventBridgeDestination(src) != nil:
+		if err := EnsureNoRule(ctx, src, clients.EventBridge); err != nil {
+			markSubscribed(res, metav1.ConditionFalse, "FinalizeRule", "Failed to finalize EventBridge rule")
+			handler.ReportError(ctx, err)
+			log.Error("Failed to finalize EventBridge rule", zap.Error(err))
+			event.Warn(ctx, ReasonFailedUnsubscribe, "Failed to finalize EventBridge rule: %s", toErrMsg(err))
 			return
 		}
+	}
+
+	if policy == v1alpha1.AWSS3SourceDeletionPolicyRetainQueue {
+		log.Info("Retaining SQS queues according to the deletion policy")
+		event.Normal(ctx, ReasonRetained, "Retained SQS queues according to the deletion policy")
+		return
+	}
 
-		subscribed.Status = metav1.ConditionFalse
-		subscribed.Reason = "NoClient"
-		subscribed.Message = "Cannot obtain AWS API clients"
+	if err := EnsureNoQueue(ctx, src, clients.SQS); err != nil {
+		markSubscribed(res, metav1.ConditionFalse, "FinalizeQueue", "Failed to finalize SQS queue")
+		handler.ReportError(ctx, err)
+		log.Error("Failed to finalize SQS queue", zap.Error(err))
+		event.Warn(ctx, ReasonFailedUnsubscribe, "Failed to finalize SQS queue: %s", toErrMsg(err))
 		return
 	}
+}
 
-	if err := EnsureNoQueue(ctx, src, sqsClient); err != nil {
-		h.log.Error("Failed to finalize SQS queue", zap.Error(err))
+// deletionPolicy returns the deletion policy of the given source, or the
+// default one if it isn't set.
+func deletionPolicy(src *v1alpha1.AWSS3Source) v1alpha1.AWSS3SourceDeletionPolicy {
+	if p := src.Spec.DeletionPolicy; p != "" {
+		return p
 	}
+	return v1alpha1.AWSS3SourceDeletionPolicyDelete
+}
 
-	// The finalizer blocks the deletion of the source object until
-	// ensureNotificationsDisabled succeeds to ensure that we don't leave
-	// any dangling event notification configurations behind us.
-	if err := EnsureNotificationsDisabled(ctx, src, s3Client); err != nil {
-		h.log.Error("Failed to disable S3 notifications", zap.Error(err))
+// markSubscribed sets the Subscribed condition of the response.
+func markSubscribed(res *hookv1.HookResponse, status metav1.ConditionStatus, reason, msg string) {
+	markCondition(res, ConditionSubscribed, status, reason, msg)
+}
+
+// markCondition sets the condition of the given type in the response.
+func markCondition(res *hookv1.HookResponse, typ string, status metav1.ConditionStatus, reason, msg string) {
+	cond := res.Status.Conditions.GetByType(typ)
+	if cond == nil {
+		res.Status.Conditions = append(res.Status.Conditions, commonv1alpha1.Condition{Type: typ})
+		cond = &res.Status.Conditions[len(res.Status.Conditions)-1]
 	}
+
+	cond.Status = status
+	cond.Reason = reason
+	cond.Message = msg
 }
 
 // sourceID returns an ID that identifies the given source instance in AWS
@@ -0,0 +1,295 @@
+// Copyright 2023 TriggerMesh Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package awss3source
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/awsutil"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"sigs.k8s.io/yaml"
+
+	commonv1alpha1 "github.com/triggermesh/scoby/pkg/apis/common/v1alpha1"
+	hookv1 "github.com/triggermesh/scoby/pkg/hook/v1"
+
+	"github.com/triggermesh/scoby-hook-triggermesh/pkg/apis"
+	"github.com/triggermesh/scoby-hook-triggermesh/pkg/apis/sources/v1alpha1"
+	"github.com/triggermesh/scoby-hook-triggermesh/pkg/handler"
+	s3client "github.com/triggermesh/scoby-hook-triggermesh/pkg/sources/client/s3"
+	"github.com/triggermesh/scoby-hook-triggermesh/pkg/sources/errclass"
+)
+
+func TestFinalizeDeletionPolicy(t *testing.T) {
+	src := &v1alpha1.AWSS3Source{}
+	src.Namespace, src.Name = "ns", "name"
+	src.Spec.ARN = apis.ARN{
+		Partition: "aws",
+		Service:   "s3",
+		Region:    "eu-west-1",
+		AccountID: "123456789012",
+		Resource:  "my-bucket",
+	}
+
+	testCases := map[string]struct {
+		policy         v1alpha1.AWSS3SourceDeletionPolicy
+		noNotifs       bool
+		putNotifsErr   error
+		deleteQueueErr error
+		clientsErr     error
+
+		expectClients       bool
+		expectNotifsRemoved bool
+		expectQueueDeleted  bool
+		expectStatus        metav1.ConditionStatus
+		expectReason        string
+		expectDenied        bool
+	}{
+		"default policy": {
+			policy:              "",
+			expectClients:       true,
+			expectNotifsRemoved: true,
+			expectQueueDeleted:  true,
+			expectStatus:        metav1.ConditionTrue,
+		},
+		"delete": {
+			policy:              v1alpha1.AWSS3SourceDeletionPolicyDelete,
+			expectClients:       true,
+			expectNotifsRemoved: true,
+			expectQueueDeleted:  true,
+			expectStatus:        metav1.ConditionTrue,
+		},
+		"retain": {
+			policy:        v1alpha1.AWSS3SourceDeletionPolicyRetain,
+			expectClients: false,
+			expectStatus:  metav1.ConditionTrue,
+		},
+		"retain queue": {
+			policy:              v1alpha1.AWSS3SourceDeletionPolicyRetainQueue,
+			expectClients:       true,
+			expectNotifsRemoved: true,
+			expectQueueDeleted:  false,
+			expectStatus:        metav1.ConditionTrue,
+		},
+		"notifications already disabled": {
+			policy:              v1alpha1.AWSS3SourceDeletionPolicyDelete,
+			noNotifs:            true,
+			expectClients:       true,
+			expectNotifsRemoved: true,
+			expectQueueDeleted:  true,
+			expectStatus:        metav1.ConditionTrue,
+		},
+		"failure to disable notifications": {
+			policy:        v1alpha1.AWSS3SourceDeletionPolicyDelete,
+			putNotifsErr:  errors.New("fake error"),
+			expectClients: true,
+			expectStatus:  metav1.ConditionFalse,
+			expectReason:  "DisableNotifications",
+		},
+		"authorization error deleting queue": {
+			policy:              v1alpha1.AWSS3SourceDeletionPolicyDelete,
+			deleteQueueErr:      awserr.New("AccessDenied", "fake error", nil),
+			expectClients:       true,
+			expectNotifsRemoved: true,
+			expectQueueDeleted:  false,
+			expectStatus:        metav1.ConditionFalse,
+			expectReason:        "FinalizeQueue",
+			expectDenied:        true,
+		},
+		"missing Secret": {
+			policy: v1alpha1.AWSS3SourceDeletionPolicyDelete,
+			clientsErr: fmt.Errorf("retrieving AWS security credentials: %w",
+				apierrors.NewNotFound(corev1.Resource("secrets"), "aws-creds")),
+			expectClients: true,
+			expectStatus:  metav1.ConditionFalse,
+			expectReason:  "NoClient",
+		},
+		"missing Secret with retain policy": {
+			policy: v1alpha1.AWSS3SourceDeletionPolicyRetain,
+			clientsErr: fmt.Errorf("retrieving AWS security credentials: %w",
+				apierrors.NewNotFound(corev1.Resource("secrets"), "aws-creds")),
+			expectClients: false,
+			expectStatus:  metav1.ConditionTrue,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			s3Cli := &fakeS3{
+				cfg: &s3.NotificationConfiguration{
+					QueueConfigurations: []*s3.QueueConfiguration{{
+						Id:       aws.String(sourceID(src)),
+						QueueArn: aws.String(managedQueueARN(src)),
+					}},
+				},
+				putErr: tc.putNotifsErr,
+			}
+			if tc.noNotifs {
+				s3Cli.cfg = &s3.NotificationConfiguration{}
+			}
+			sqsCli := &fakeSQS{
+				queues: map[string]*fakeQueue{
+					queueName(src): {tags: map[string]string{"owned-by": sourceID(src)}},
+				},
+				deleteErr: tc.deleteQueueErr,
+			}
+
+			var gotClients bool
+			cg := s3client.ClientGetterFunc(func(context.Context, *v1alpha1.AWSS3Source) (*s3client.Clients, error) {
+				gotClients = true
+				if tc.clientsErr != nil {
+					return nil, tc.clientsErr
+				}
+				return &s3client.Clients{S3: s3Cli, SQS: sqsCli}, nil
+			})
+
+			h := New(cg, nil, zap.NewNop().Sugar())
+
+			s := src.DeepCopy()
+			s.Spec.DeletionPolicy = tc.policy
+
+			ctx := handler.WithErrorReporting(context.Background())
+
+			res := h.Finalize(ctx, s)
+
+			assert.Equal(t, tc.expectClients, gotClients)
+			assert.Equal(t, tc.expectNotifsRemoved, len(s3Cli.cfg.QueueConfigurations) == 0)
+			assert.Equal(t, tc.expectNotifsRemoved && !tc.noNotifs, s3Cli.puts > 0)
+			_, queueExists := sqsCli.queues[queueName(src)]
+			assert.Equal(t, tc.expectQueueDeleted, !queueExists)
+
+			require.NotNil(t, res.Status)
+			cond := res.Status.Conditions.GetByType(ConditionSubscribed)
+			require.NotNil(t, cond)
+			assert.Equal(t, tc.expectStatus, cond.Status)
+			assert.Equal(t, tc.expectReason, cond.Reason)
+
+			errs := handler.ReportedErrors(ctx)
+			assert.Equal(t, tc.expectStatus == metav1.ConditionFalse, len(errs) > 0, "Expected the failure to be reported")
+			if tc.expectDenied {
+				require.Len(t, errs, 1)
+				assert.True(t, errclass.IsDenied(errs[0]), "Expected an authorization error")
+			}
+		})
+	}
+}
+
+func TestReconcileObserveModeMissingQueue(t *testing.T) {
+	src := &v1alpha1.AWSS3Source{}
+	src.Namespace, src.Name = "ns", "name"
+	src.Spec.ARN = apis.ARN{
+		Partition: "aws",
+		Service:   "s3",
+		Region:    "eu-west-1",
+		AccountID: "123456789012",
+		Resource:  "my-bucket",
+	}
+	src.Spec.ReconcileMode = v1alpha1.AWSS3SourceReconcileModeObserve
+
+	// the queue may have been reported in the status by an earlier
+	// reconciliation in the Manage mode
+	src.Status.QueueARN, _ = arnStrToARN(managedQueueARN(src))
+
+	s3Cli := &fakeS3{cfg: &s3.NotificationConfiguration{}}
+	sqsCli := &fakeSQS{queues: map[string]*fakeQueue{}}
+
+	cg := s3client.ClientGetterFunc(func(context.Context, *v1alpha1.AWSS3Source) (*s3client.Clients, error) {
+		return &s3client.Clients{S3: s3Cli, SQS: sqsCli}, nil
+	})
+
+	h := New(cg, fake.NewSimpleClientset().CoreV1().ServiceAccounts, zap.NewNop().Sugar())
+
+	res := h.Reconcile(handler.WithErrorReporting(context.Background()), src)
+
+	assert.Empty(t, res.EnvVars, "The adapter should not be configured with a queue that doesn't exist")
+	assert.Empty(t, sqsCli.created)
+	assert.Zero(t, s3Cli.puts)
+
+	require.NotNil(t, res.Status)
+
+	subscribed := res.Status.Conditions.GetByType(ConditionSubscribed)
+	require.NotNil(t, subscribed)
+	assert.Equal(t, metav1.ConditionFalse, subscribed.Status)
+	assert.Equal(t, "QueueNotCreated", subscribed.Reason)
+
+	drifted := res.Status.Conditions.GetByType(ConditionDrifted)
+	require.NotNil(t, drifted)
+	assert.Equal(t, metav1.ConditionFalse, drifted.Status)
+	assert.Equal(t, ReasonDriftedDetected, drifted.Reason)
+	assert.Equal(t, "AWS resources differ from their desired state: "+
+		`SQS queue "`+queueName(src)+`", S3 bucket notification configuration`, drifted.Message)
+}
+
+// Scoby only copies the conditions listed in the registration to the status
+// of objects, and discards the others.
+func TestRegistrationConditionsFromHook(t *testing.T) {
+	const regFile = "../../../../config/300-awss3source-registration.yaml"
+
+	b, err := os.ReadFile(regFile)
+	require.NoError(t, err)
+
+	reg := &struct {
+		Spec struct {
+			Workload commonv1alpha1.Workload `json:"workload"`
+		} `json:"spec"`
+	}{}
+	require.NoError(t, yaml.Unmarshal(b, reg))
+	require.NotNil(t, reg.Spec.Workload.StatusConfiguration)
+
+	var registered []string
+	for _, c := range reg.Spec.Workload.StatusConfiguration.ConditionsFromHook {
+		registered = append(registered, c.Type)
+	}
+
+	h := New(nil, nil, zap.NewNop().Sugar())
+
+	for _, op := range []hookv1.Operation{hookv1.OperationReconcile, hookv1.OperationFinalize} {
+		for _, c := range h.InitialConditions(op) {
+			assert.Contains(t, registered, c.Type, "Condition reported by the hook is not registered")
+		}
+	}
+	assert.Contains(t, registered, ConditionDrifted)
+}
+
+// fakeS3 is a fake S3 client that stores the notification configuration of a
+// single bucket in memory.
+type fakeS3 struct {
+	s3iface.S3API
+	cfg    *s3.NotificationConfiguration
+	putErr error
+	// number of successful writes of the configuration
+	puts int
+}
+
+func (c *fakeS3) GetBucketNotificationConfigurationWithContext(_ aws.Context,
+	_ *s3.GetBucketNotificationConfigurationRequest, _ ...request.Option) (*s3.NotificationConfiguration, error) {
+
+	return awsutil.CopyOf(c.cfg).(*s3.NotificationConfiguration), nil
+}
+
+func (c *fakeS3) PutBucketNotificationConfigurationWithContext(_ aws.Context,
+	in *s3.PutBucketNotificationConfigurationInput, _ ...request.Option) (*s3.PutBucketNotificationConfigurationOutput, error) {
+
+	if c.putErr != nil {
+		return nil, c.putErr
+	}
+	c.cfg = in.NotificationConfiguration
+	c.puts++
+	return &s3.PutBucketNotificationConfigurationOutput{}, nil
+}
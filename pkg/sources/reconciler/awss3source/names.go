@@ -0,0 +1,94 @@
+/*
+Copyright 2023 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package awss3source
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"regexp"
+
+	"github.com/triggermesh/scoby-hook-triggermesh/pkg/apis/sources/v1alpha1"
+)
+
+// Maximum length of the names of AWS resources managed for a source.
+const (
+	maxQueueNameLength = 80
+	maxTopicNameLength = 256
+	maxRuleNameLength  = 64
+)
+
+// resourceNamePrefix is the prefix of the names of AWS resources managed for
+// a source.
+const resourceNamePrefix = "s3-events_"
+
+// resourceNameHashLength is the number of hexadecimal characters of the hash
+// which makes the names of AWS resources unique per source.
+const resourceNameHashLength = 16
+
+// invalidNameChars matches characters that are valid in bucket names but not
+// in the names of SQS queues, SNS topics or EventBridge rules.
+var invalidNameChars = regexp.MustCompile(`[^a-zA-Z0-9_-]`)
+
+// resourceName returns the name of an AWS resource managed for the given
+// source instance, which is at most maxLen characters long.
+//
+// The name contains the name of the source's bucket for readability, and ends
+// with a hash of the source's namespace and name so that sources which observe
+// the same bucket don't collide. The bucket name is truncated if necessary.
+func resourceName(src *v1alpha1.AWSS3Source, maxLen int) string {
+	h := sha256.Sum256([]byte(src.Namespace + "/" + src.Name))
+	suffix := "_" + hex.EncodeToString(h[:])[:resourceNameHashLength]
+
+	name := resourceNamePrefix + invalidNameChars.ReplaceAllString(src.Spec.ARN.Resource, "-")
+	if len(name)+len(suffix) > maxLen {
+		name = name[:maxLen-len(suffix)]
+	}
+
+	return name + suffix
+}
+
+// queueName returns a SQS queue name matching the given source instance.
+func queueName(src *v1alpha1.AWSS3Source) string {
+	return resourceName(src, maxQueueNameLength)
+}
+
+// deadLetterQueueSuffix is the suffix of the name of dead-letter queues.
+const deadLetterQueueSuffix = "-dlq"
+
+// deadLetterQueueName returns the name of the SQS dead-letter queue matching
+// the given source instance.
+func deadLetterQueueName(src *v1alpha1.AWSS3Source) string {
+	return resourceName(src, maxQueueNameLength-len(deadLetterQueueSuffix)) + deadLetterQueueSuffix
+}
+
+// legacyQueueName returns the name of the SQS queue which used to be created
+// for the given source instance. It is derived from the bucket name only, so
+// it is shared by all sources which observe the same bucket.
+func legacyQueueName(src *v1alpha1.AWSS3Source) string {
+	return resourceNamePrefix + src.Spec.ARN.Resource
+}
+
+// topicName returns a SNS topic name matching the given source instance.
+func topicName(src *v1alpha1.AWSS3Source) string {
+	return resourceName(src, maxTopicNameLength)
+}
+
+// ruleName returns an EventBridge rule name matching the given source
+// instance.
+func ruleName(src *v1alpha1.AWSS3Source) string {
+	return resourceName(src, maxRuleNameLength)
+}
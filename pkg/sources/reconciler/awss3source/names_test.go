@@ -0,0 +1,104 @@
+/*
+Copyright 2023 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package awss3source
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/triggermesh/scoby-hook-triggermesh/pkg/apis"
+	"github.com/triggermesh/scoby-hook-triggermesh/pkg/apis/sources/v1alpha1"
+)
+
+func TestResourceName(t *testing.T) {
+	validName := regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
+
+	newSource := func(ns, name, bucket string) *v1alpha1.AWSS3Source {
+		src := &v1alpha1.AWSS3Source{}
+		src.Namespace, src.Name = ns, name
+		src.Spec.ARN = apis.ARN{
+			Partition: "aws",
+			Service:   "s3",
+			Region:    "eu-west-1",
+			AccountID: "123456789012",
+			Resource:  bucket,
+		}
+		return src
+	}
+
+	longBucket := strings.Repeat("a.bucket.", 7)
+
+	testCases := map[string]struct {
+		src    *v1alpha1.AWSS3Source
+		expect string
+	}{
+		"short bucket name": {
+			src:    newSource("ns", "name", "my-bucket"),
+			expect: "s3-events_my-bucket_",
+		},
+		"bucket name with dots": {
+			src:    newSource("ns", "name", "my.bucket"),
+			expect: "s3-events_my-bucket_",
+		},
+		"long bucket name": {
+			src:    newSource("ns", "name", longBucket),
+			expect: "s3-events_a-bucket-",
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			for kind, c := range map[string]struct {
+				name   func(*v1alpha1.AWSS3Source) string
+				maxLen int
+			}{
+				"queue": {queueName, maxQueueNameLength},
+				"topic": {topicName, maxTopicNameLength},
+				"rule":  {ruleName, maxRuleNameLength},
+			} {
+				n := c.name(tc.src)
+
+				assert.LessOrEqual(t, len(n), c.maxLen, kind)
+				assert.Regexp(t, validName, n, kind)
+				assert.True(t, strings.HasPrefix(n, tc.expect), "Unexpected %s name %q", kind, n)
+			}
+		})
+	}
+
+	t.Run("unique per source", func(t *testing.T) {
+		names := map[string]struct{}{
+			queueName(newSource("ns1", "name", "my-bucket")):  {},
+			queueName(newSource("ns2", "name", "my-bucket")):  {},
+			queueName(newSource("ns1", "name2", "my-bucket")): {},
+			// truncated bucket names
+			queueName(newSource("ns1", "name", longBucket)): {},
+			queueName(newSource("ns2", "name", longBucket)): {},
+		}
+		assert.Len(t, names, 5)
+
+		src := newSource("ns", "name", "my-bucket")
+		assert.Equal(t, queueName(src), queueName(src.DeepCopy()), "Expected names to be stable")
+	})
+
+	t.Run("rule ARN", func(t *testing.T) {
+		src := newSource("ns", "name", longBucket)
+		assert.Equal(t, "arn:aws:events:eu-west-1:123456789012:rule/"+ruleName(src), ruleARN(src))
+	})
+}
@@ -18,24 +18,28 @@ package awss3source
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
-	"reflect"
+	"time"
 
 	"github.com/aws/aws-sdk-go/aws/arn"
 	awssqs "github.com/aws/aws-sdk-go/service/sqs"
 	"github.com/aws/aws-sdk-go/service/sqs/sqsiface"
+	"go.opencensus.io/trace"
 
 	"github.com/triggermesh/scoby-hook-triggermesh/pkg/apis"
 	"github.com/triggermesh/scoby-hook-triggermesh/pkg/apis/sources/v1alpha1"
+	"github.com/triggermesh/scoby-hook-triggermesh/pkg/reconciler/event"
 	"github.com/triggermesh/scoby-hook-triggermesh/pkg/sources/aws/iam"
 	"github.com/triggermesh/scoby-hook-triggermesh/pkg/sources/aws/s3"
 	"github.com/triggermesh/scoby-hook-triggermesh/pkg/sources/aws/sqs"
+	"github.com/triggermesh/scoby-hook-triggermesh/pkg/sources/errclass"
 )
 
 // EnsureQueue ensures the existence of a SQS queue for sending S3 event
 // notifications.
 func EnsureQueue(ctx context.Context, src *v1alpha1.AWSS3Source, cli sqsiface.SQSAPI) (string /*arn*/, error) {
+	ctx, span := trace.StartSpan(ctx, "awss3source.EnsureQueue")
+	defer span.End()
 
 	status := &src.Status
 
@@ -46,28 +50,48 @@ func EnsureQueue(ctx context.Context, src *v1alpha1.AWSS3Source, cli sqsiface.SQ
 		}
 	}
 
+	var dlqARN string
+	if q := src.Spec.Queue; q != nil && q.DeadLetterQueue != nil {
+		var err error
+		if dlqARN, err = ensureDeadLetterQueue(ctx, src, cli); err != nil {
+			return "", err
+		}
+	}
+
 	queueName := queueName(src)
+	desiredAttrs := makeQueueAttributes(src, dlqARN)
 
-	queueURL, err := sqs.QueueURL(cli, queueName)
+	queueURL, err := sqs.QueueURL(ctx, cli, queueName)
 	switch {
-	case isNotFound(err):
-		queueURL, err = sqs.CreateQueue(cli, queueName, queueTags(src))
+	case errclass.IsNotFound(err):
+		createAttrs := creationAttributes(desiredAttrs)
+		if reportDrift(ctx, fmt.Sprintf("SQS queue %q", queueName), nil, createAttrs) {
+			// the configuration of other resources is compared against
+			// the ARN the queue would have if it existed, but the
+			// status only reports existing queues
+			status.QueueARN = nil
+			return managedQueueARN(src), nil
+		}
+
+		queueURL, err = sqs.CreateQueue(ctx, cli, queueName, createAttrs, resourceTags(src))
 		if err != nil {
-			return "", fmt.Errorf("error creating SQS queue for event notifications: %w", toErrMsg(err))
+			return "", fmt.Errorf("error creating SQS queue for event notifications: %s", toErrMsg(err))
 		}
+		event.Normal(ctx, ReasonQueueCreated, "Created SQS queue %q", queueURL)
 
-	case isAWSError(err):
+	case errclass.IsAWSError(err):
 		// All documented API errors require some user intervention and
 		// are not to be retried.
 		// https://docs.aws.amazon.com/AmazonS3/latest/API/ErrorResponses.html
-		return "", fmt.Errorf("request to SQS API got rejected: %w", toErrMsg(err))
+		return "", fmt.Errorf("request to SQS API got rejected: %s", toErrMsg(err))
 
 	case err != nil:
-		return "", fmt.Errorf("failed to determine URL of SQS queue: %w", toErrMsg(err))
+		return "", fmt.Errorf("failed to determine URL of SQS queue: %s", toErrMsg(err))
 	}
 
-	getAttrs := []string{awssqs.QueueAttributeNameQueueArn, awssqs.QueueAttributeNamePolicy}
-	queueAttrs, err := sqs.QueueAttributes(cli, queueURL, getAttrs)
+	getAttrs := append([]string{awssqs.QueueAttributeNameQueueArn, awssqs.QueueAttributeNamePolicy},
+		managedQueueAttributes...)
+	queueAttrs, err := sqs.QueueAttributes(ctx, cli, queueURL, getAttrs)
 	if err != nil {
 		return "", fmt.Errorf("getting attributes of SQS queue: %w", err)
 	}
@@ -84,10 +108,14 @@ func EnsureQueue(ctx context.Context, src *v1alpha1.AWSS3Source, cli sqsiface.SQ
 	// adapter properly
 	status.QueueARN = queueARNStruct
 
-	currentPol := unmarshalQueuePolicy(queueAttrs[awssqs.QueueAttributeNamePolicy])
-	desiredPol := makeQueuePolicy(queueARN, src)
+	if err := syncQueueAttributes(ctx, cli, queueName, queueURL, queueAttrs, desiredAttrs); err != nil {
+		return "", fmt.Errorf("error synchronizing attributes of SQS queue: %s", toErrMsg(err))
+	}
+
+	currentPol := queueAttrs[awssqs.QueueAttributeNamePolicy]
+	desiredStmt := makeQueuePolicyStatement(queueARN, src)
 
-	if err := syncQueuePolicy(cli, queueURL, currentPol, desiredPol); err != nil {
+	if err := syncQueuePolicy(ctx, cli, queueName, queueURL, currentPol, desiredStmt); err != nil {
 		return "", fmt.Errorf("error synchronizing policy of SQS queue: %w", err)
 	}
 
@@ -97,6 +125,9 @@ func EnsureQueue(ctx context.Context, src *v1alpha1.AWSS3Source, cli sqsiface.SQ
 // EnsureNoQueue ensures that the SQS queue created for sending S3 event
 // notifications is deleted.
 func EnsureNoQueue(ctx context.Context, src *v1alpha1.AWSS3Source, cli sqsiface.SQSAPI) error {
+	ctx, span := trace.StartSpan(ctx, "awss3source.EnsureNoQueue")
+	defer span.End()
+
 	if dest := src.Spec.Destination; dest != nil {
 		if userProvidedQueue := dest.SQS; userProvidedQueue != nil {
 			// do not delete queues managed by the user
@@ -104,102 +135,303 @@ func EnsureNoQueue(ctx context.Context, src *v1alpha1.AWSS3Source, cli sqsiface.
 		}
 	}
 
-	queueURL, err := sqs.QueueURL(cli, queueName(src))
+	if err := ensureNoQueue(ctx, src, cli, queueName(src), true); err != nil {
+		return err
+	}
+
+	// the dead-letter queue is deleted regardless of the source's spec,
+	// since it may have been disabled after being created
+	if err := ensureNoQueue(ctx, src, cli, deadLetterQueueName(src), false); err != nil {
+		return err
+	}
+
+	// a queue created under the legacy name may not have been migrated
+	// yet, in which case it is deleted as well
+	legacyURL, err := legacyQueueURL(ctx, cli, src)
 	switch {
-	case isNotFound(err):
-		// event.Warn(ctx, ReasonUnsubscribed, "Queue not found, skipping deletion")
+	case errclass.IsDenied(err):
+		return fmt.Errorf("authorization error getting legacy SQS queue: %w", err)
+	case err != nil:
+		return fmt.Errorf("failed to look up legacy SQS queue: %s", toErrMsg(err))
+	case legacyURL == "":
 		return nil
-	case isDenied(err):
-		// it is unlikely that we recover from auth errors in the
-		// finalizer, so we simply record a warning event and return
-		// event.Warn(ctx, ReasonFailedUnsubscribe,
-		// 	"Authorization error getting SQS queue. Ignoring: %s", toErrMsg(err))
+	}
+
+	if err := sqs.DeleteQueue(ctx, cli, legacyURL); err != nil {
+		return fmt.Errorf("error deleting legacy SQS queue: %s", toErrMsg(err))
+	}
+
+	event.Normal(ctx, ReasonQueueDeleted, "Deleted legacy SQS queue %q", legacyURL)
+
+	return nil
+}
+
+// ensureNoQueue deletes the SQS queue with the given name if it is owned by
+// the given source instance. A warning event is recorded if the queue is
+// expected to exist but doesn't.
+func ensureNoQueue(ctx context.Context, src *v1alpha1.AWSS3Source, cli sqsiface.SQSAPI, name string, expectExists bool) error {
+	queueURL, err := sqs.QueueURL(ctx, cli, name)
+	switch {
+	case errclass.IsNotFound(err):
+		if expectExists {
+			event.Warn(ctx, ReasonUnsubscribed, "Queue not found, skipping deletion")
+		}
 		return nil
+	case errclass.IsDenied(err):
+		// the finalizer can only recover from auth errors if the
+		// permissions are granted, or if the deletion policy is
+		// changed to retain AWS resources
+		return fmt.Errorf("authorization error getting SQS queue: %w", err)
 	case err != nil:
-		return fmt.Errorf("failed to determine URL of SQS queue: %w", toErrMsg(err))
+		return fmt.Errorf("failed to determine URL of SQS queue: %s", toErrMsg(err))
 	}
 
-	owns, err := assertOwnership(cli, queueURL, src)
+	owns, err := assertOwnership(ctx, cli, queueURL, src)
 	if err != nil {
-		return fmt.Errorf("failed to verify owner of SQS queue: %w", toErrMsg(err))
-		// return reconciler.NewEvent(corev1.EventTypeWarning, ReasonFailedUnsubscribe,
-		// 	"Failed to verify owner of SQS queue: %s", toErrMsg(err))
+		return fmt.Errorf("failed to verify owner of SQS queue: %s", toErrMsg(err))
 	}
 
 	if !owns {
-		// event.Warn(ctx, ReasonUnsubscribed, "Queue %q is not owned by this source instance, "+
-		// 	"skipping deletion", queueURL)
+		event.Warn(ctx, ReasonUnsubscribed, "Queue %q is not owned by this source instance, "+
+			"skipping deletion", queueURL)
+
+		// the queue may still grant permissions to the source's bucket
+		if err := removeQueuePolicyStatement(ctx, cli, queueURL, src); err != nil {
+			return fmt.Errorf("failed to update policy of SQS queue: %s", toErrMsg(err))
+		}
 		return nil
 	}
 
-	err = sqs.DeleteQueue(cli, queueURL)
+	err = sqs.DeleteQueue(ctx, cli, queueURL)
 	switch {
-	case isDenied(err):
-		// it is unlikely that we recover from auth errors in the
-		// finalizer, so we simply record a warning event and return
-		// event.Warn(ctx, ReasonFailedUnsubscribe,
-		// 	"Authorization error deleting SQS queue. Ignoring: %s", toErrMsg(err))
-		return nil
+	case errclass.IsDenied(err):
+		return fmt.Errorf("authorization error deleting SQS queue: %w", err)
 	case err != nil:
-		return fmt.Errorf("error deleting SQS queue: %w", toErrMsg(err))
-		// return reconciler.NewEvent(corev1.EventTypeWarning, ReasonFailedUnsubscribe,
-		// 	"Error deleting SQS queue: %s", toErrMsg(err))
+		return fmt.Errorf("error deleting SQS queue: %s", toErrMsg(err))
+	}
+
+	event.Normal(ctx, ReasonQueueDeleted, "Deleted SQS queue %q", queueURL)
+
+	return nil
+}
+
+// Limits of the work performed to migrate a legacy SQS queue during a single
+// reconciliation. They keep the migration well under the deadline of handler
+// operations; remaining messages are moved during the next reconciliations.
+const (
+	// maxMigratedBatches is the maximum number of batches of messages
+	// moved from a legacy SQS queue.
+	maxMigratedBatches = 5
+	// migrationTimeBudget is the duration after which no new batch of
+	// messages is moved from a legacy SQS queue.
+	migrationTimeBudget = 5 * time.Second
+)
+
+// MigrateLegacyQueue moves the messages of the SQS queue created for the given
+// source instance under its legacy name to the queue returned by queueName(),
+// and deletes the legacy queue once it is empty.
+//
+// Legacy queues were named after the bucket only, so they could be shared by
+// multiple sources. A legacy queue is only migrated by the source which owns
+// it. Because messages must no longer be sent to the legacy queue once it is
+// deleted, this function should be called after bucket notifications were
+// configured to target the current queue.
+func MigrateLegacyQueue(ctx context.Context, src *v1alpha1.AWSS3Source, cli sqsiface.SQSAPI) error {
+	ctx, span := trace.StartSpan(ctx, "awss3source.MigrateLegacyQueue")
+	defer span.End()
+
+	if dest := src.Spec.Destination; dest != nil && dest.SQS != nil {
+		return nil
+	}
+
+	legacyURL, err := legacyQueueURL(ctx, cli, src)
+	if err != nil {
+		return fmt.Errorf("failed to look up legacy SQS queue: %s", toErrMsg(err))
+	}
+	if legacyURL == "" {
+		return nil
+	}
+
+	queueURL, err := sqs.QueueURL(ctx, cli, queueName(src))
+	if err != nil {
+		return fmt.Errorf("failed to determine URL of SQS queue: %s", toErrMsg(err))
+	}
+
+	var moved int
+	defer func() {
+		if moved > 0 {
+			event.Normal(ctx, ReasonQueueMigrated, "Moved %d message(s) from legacy SQS queue %q to %q",
+				moved, legacyURL, queueURL)
+		}
+	}()
+
+	deadline := time.Now().Add(migrationTimeBudget)
+
+	for batches := 0; ; batches++ {
+		if batches == maxMigratedBatches || time.Now().After(deadline) {
+			// remaining messages are moved during the next
+			// reconciliation
+			return nil
+		}
+
+		msgs, err := sqs.ReceiveMessages(ctx, cli, legacyURL)
+		if err != nil {
+			return fmt.Errorf("error receiving messages from legacy SQS queue: %s", toErrMsg(err))
+		}
+		if len(msgs) == 0 {
+			break
+		}
+
+		if err := sqs.SendMessages(ctx, cli, queueURL, msgs); err != nil {
+			return fmt.Errorf("error sending messages to SQS queue: %s", toErrMsg(err))
+		}
+		// messages which fail to be deleted are moved again during the
+		// next attempt, and may therefore be delivered twice
+		if err := sqs.DeleteMessages(ctx, cli, legacyURL, msgs); err != nil {
+			return fmt.Errorf("error deleting messages from legacy SQS queue: %s", toErrMsg(err))
+		}
+
+		moved += len(msgs)
 	}
 
-	// event.Normal(ctx, ReasonQueueDeleted, "Deleted SQS queue %q", queueURL)
+	// messages may still be in flight, e.g. received by an adapter which
+	// was not yet reconfigured to consume the current queue
+	empty, err := isQueueEmpty(ctx, cli, legacyURL)
+	if err != nil {
+		return fmt.Errorf("error getting attributes of legacy SQS queue: %s", toErrMsg(err))
+	}
+	if !empty {
+		return nil
+	}
+
+	if err := sqs.DeleteQueue(ctx, cli, legacyURL); err != nil {
+		return fmt.Errorf("error deleting legacy SQS queue: %s", toErrMsg(err))
+	}
+
+	event.Normal(ctx, ReasonQueueDeleted, "Deleted legacy SQS queue %q", legacyURL)
 
 	return nil
 }
 
+// legacyQueueURL returns the URL of the SQS queue created for the given source
+// instance under its legacy name, or an empty string if no such queue exists
+// or if it is owned by another source instance.
+func legacyQueueURL(ctx context.Context, cli sqsiface.SQSAPI, src *v1alpha1.AWSS3Source) (string, error) {
+	queueURL, err := sqs.QueueURL(ctx, cli, legacyQueueName(src))
+	switch {
+	case errclass.IsNotFound(err):
+		return "", nil
+	case err != nil:
+		return "", fmt.Errorf("determining URL of SQS queue: %w", err)
+	}
+
+	owns, err := assertOwnership(ctx, cli, queueURL, src)
+	if err != nil {
+		return "", fmt.Errorf("verifying owner of SQS queue: %w", err)
+	}
+	if !owns {
+		return "", nil
+	}
+
+	return queueURL, nil
+}
+
+// isQueueEmpty returns whether the SQS queue with the given URL contains
+// neither visible, in flight nor delayed messages.
+func isQueueEmpty(ctx context.Context, cli sqsiface.SQSAPI, queueURL string) (bool, error) {
+	countAttrs := []string{
+		awssqs.QueueAttributeNameApproximateNumberOfMessages,
+		awssqs.QueueAttributeNameApproximateNumberOfMessagesNotVisible,
+		awssqs.QueueAttributeNameApproximateNumberOfMessagesDelayed,
+	}
+
+	attrs, err := sqs.QueueAttributes(ctx, cli, queueURL, countAttrs)
+	if err != nil {
+		return false, err
+	}
+
+	for _, a := range countAttrs {
+		if attrs[a] != "0" {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
+
 // syncQueuePolicy ensures that a SQS queue has the right permissions to
 // receive messages from the S3 bucket observed by the given source.
-func syncQueuePolicy(cli sqsiface.SQSAPI, queueURL string, current, desired iam.Policy) error {
-	if equalPolicies(desired, current) {
+// Statements of the queue policy other than the desired one are preserved.
+func syncQueuePolicy(ctx context.Context, cli sqsiface.SQSAPI, queueName, queueURL, current string, desired iam.PolicyStatement) error {
+	pol, changed, err := iam.MergeStatement(current, desired)
+	if err != nil {
+		return fmt.Errorf("merging statement into policy of SQS queue: %w", err)
+	}
+	if !changed {
+		return nil
+	}
+
+	if reportDrift(ctx, fmt.Sprintf("Policy of SQS queue %q", queueName), current, pol) {
 		return nil
 	}
 
-	if err := sqs.SetQueuePolicy(cli, queueURL, desired); err != nil {
+	if err := sqs.SetQueuePolicy(ctx, cli, queueURL, pol); err != nil {
 		return fmt.Errorf("setting policy of SQS queue: %w", err)
 	}
 
 	return nil
 }
 
-// equalPolicies returns whether two SQS policies are semantically equal.
-func equalPolicies(a, b iam.Policy) bool {
-	if len(a.Statement) != len(b.Statement) {
-		return false
+// removeQueuePolicyStatement removes the statement created for the given
+// source instance from the policy of a SQS queue, if present.
+func removeQueuePolicyStatement(ctx context.Context, cli sqsiface.SQSAPI, queueURL string, src *v1alpha1.AWSS3Source) error {
+	current, err := sqs.QueuePolicy(ctx, cli, queueURL)
+	if err != nil {
+		return fmt.Errorf("getting policy of SQS queue: %w", err)
 	}
 
-	as, bs := a.Statement[0], b.Statement[0]
-
-	if !reflect.DeepEqual(as.Principal, bs.Principal) {
-		return false
+	pol, changed, err := iam.RemoveStatement(current, policyStatementID(src))
+	if err != nil {
+		return fmt.Errorf("removing statement from policy of SQS queue: %w", err)
 	}
-	if !reflect.DeepEqual(as.Condition, bs.Condition) {
-		return false
+	if !changed {
+		return nil
 	}
-	if !reflect.DeepEqual(as.Action, bs.Action) {
-		return false
+
+	if err := sqs.SetQueuePolicy(ctx, cli, queueURL, pol); err != nil {
+		return fmt.Errorf("setting policy of SQS queue: %w", err)
 	}
-	return reflect.DeepEqual(as.Resource, bs.Resource)
+
+	return nil
 }
 
-// makeQueuePolicy creates an IAM policy for the given SQS queue ARN and source instance.
-func makeQueuePolicy(queueARN string, src *v1alpha1.AWSS3Source) iam.Policy {
+// makeQueuePolicyStatement creates an IAM policy statement for the given SQS
+// queue ARN and source instance.
+// The statement allows the intermediate destination of the source's bucket
+// notifications, if any, or the bucket itself to send messages to the queue.
+func makeQueuePolicyStatement(queueARN string, src *v1alpha1.AWSS3Source) iam.PolicyStatement {
+	sid := policyStatementID(src)
+
+	if snsDestination(src) != nil {
+		return newSNSToSQSPolicyStatement(sid, queueARN, topicARN(src))
+	}
+
+	if eventBridgeDestination(src) != nil {
+		return newEventBridgeToSQSPolicyStatement(sid, queueARN, ruleARN(src))
+	}
+
 	bucketARN := s3.RealBucketARN(src.Spec.ARN)
 	accID := src.Spec.ARN.AccountID
 
-	return iam.NewPolicy(
-		newS3ToSQSPolicyStatement(queueARN, bucketARN, accID),
-	)
+	return newS3ToSQSPolicyStatement(sid, queueARN, bucketARN, accID)
 }
 
 // newS3ToSQSPolicyStatement returns an IAM Policy Statement that allows a S3
 // bucket to publish event notifications to the given SQS queue.
 // Ref. https://docs.aws.amazon.com/AmazonS3/latest/userguide/grant-destinations-permissions-to-s3.html#grant-sns-sqs-permission-for-s3
-func newS3ToSQSPolicyStatement(queueARN, bucketARN, accID string) iam.PolicyStatement {
+func newS3ToSQSPolicyStatement(sid, queueARN, bucketARN, accID string) iam.PolicyStatement {
 	return iam.NewPolicyStatement(iam.EffectAllow,
+		iam.Sid(sid),
 		iam.PrincipalService("s3.amazonaws.com"),
 		iam.ConditionArnEquals("aws:SourceArn", bucketARN),
 		iam.ConditionStringEquals("aws:SourceAccount", accID),
@@ -208,25 +440,48 @@ func newS3ToSQSPolicyStatement(queueARN, bucketARN, accID string) iam.PolicyStat
 	)
 }
 
-// unmarshalQueuePolicy deserializes an IAM policy string.
-func unmarshalQueuePolicy(polStr string) iam.Policy {
-	var pol iam.Policy
-	_ = json.Unmarshal([]byte(polStr), &pol)
+// newSNSToSQSPolicyStatement returns an IAM Policy Statement that allows a SNS
+// topic to deliver messages to the given SQS queue.
+// Ref. https://docs.aws.amazon.com/sns/latest/dg/subscribe-sqs-queue-to-sns-topic.html
+func newSNSToSQSPolicyStatement(sid, queueARN, topicARN string) iam.PolicyStatement {
+	return iam.NewPolicyStatement(iam.EffectAllow,
+		iam.Sid(sid),
+		iam.PrincipalService("sns.amazonaws.com"),
+		iam.ConditionArnEquals("aws:SourceArn", topicARN),
+		iam.Action("sqs:SendMessage"),
+		iam.Resource(queueARN),
+	)
+}
+
+// newEventBridgeToSQSPolicyStatement returns an IAM Policy Statement that
+// allows an EventBridge rule to send events to the given SQS queue.
+// Ref. https://docs.aws.amazon.com/eventbridge/latest/userguide/eb-use-resource-based.html#eb-sqs-permissions
+func newEventBridgeToSQSPolicyStatement(sid, queueARN, ruleARN string) iam.PolicyStatement {
+	return iam.NewPolicyStatement(iam.EffectAllow,
+		iam.Sid(sid),
+		iam.PrincipalService("events.amazonaws.com"),
+		iam.ConditionArnEquals("aws:SourceArn", ruleARN),
+		iam.Action("sqs:SendMessage"),
+		iam.Resource(queueARN),
+	)
+}
 
-	// if an error occured, the policy will be empty syncQueuePolicy() will
-	// simply enforce the desired state
-	return pol
+// managedQueueARN returns the ARN of the SQS queue managed for the given
+// source instance.
+func managedQueueARN(src *v1alpha1.AWSS3Source) string {
+	return bucketLocalARN(src, "sqs", queueName(src))
 }
 
-// queueName returns a SQS queue name matching the given source instance.
-func queueName(src *v1alpha1.AWSS3Source) string {
-	return "s3-events_" + src.Spec.ARN.Resource
+// policyStatementID returns the Sid of the IAM policy statements created for
+// the given source instance.
+func policyStatementID(src *v1alpha1.AWSS3Source) string {
+	return sourceID(src)
 }
 
 // assertOwnership returns whether a SQS queue identified by URL is owned by
 // the given source.
-func assertOwnership(cli sqsiface.SQSAPI, queueURL string, src *v1alpha1.AWSS3Source) (bool, error) {
-	tags, err := sqs.QueueTags(cli, queueURL)
+func assertOwnership(ctx context.Context, cli sqsiface.SQSAPI, queueURL string, src *v1alpha1.AWSS3Source) (bool, error) {
+	tags, err := sqs.QueueTags(ctx, cli, queueURL)
 	if err != nil {
 		return false, fmt.Errorf("listing tags of SQS queue: %w", err)
 	}
@@ -234,9 +489,9 @@ func assertOwnership(cli sqsiface.SQSAPI, queueURL string, src *v1alpha1.AWSS3So
 	return tags["owned-by"] == sourceID(src), nil
 }
 
-// queueTags returns a set of tags containing information from the given source
-// instance to set on a SQS queue.
-func queueTags(src *v1alpha1.AWSS3Source) map[string]string {
+// resourceTags returns a set of tags containing information from the given source
+// instance to set on the AWS resources managed for that source.
+func resourceTags(src *v1alpha1.AWSS3Source) map[string]string {
 	return map[string]string{
 		"bucket-arn":    s3.RealBucketARN(src.Spec.ARN),
 		"bucket-region": src.Spec.ARN.Region,
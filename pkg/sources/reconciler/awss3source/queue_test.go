@@ -0,0 +1,459 @@
+/*
+Copyright 2023 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package awss3source
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	awssqs "github.com/aws/aws-sdk-go/service/sqs"
+	"github.com/aws/aws-sdk-go/service/sqs/sqsiface"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/triggermesh/scoby-hook-triggermesh/pkg/apis"
+	"github.com/triggermesh/scoby-hook-triggermesh/pkg/apis/sources/v1alpha1"
+	"github.com/triggermesh/scoby-hook-triggermesh/pkg/sources/aws/iam"
+)
+
+func TestMigrateLegacyQueue(t *testing.T) {
+	src := &v1alpha1.AWSS3Source{}
+	src.Namespace, src.Name = "ns", "name"
+	src.Spec.ARN = apis.ARN{
+		Partition: "aws",
+		Service:   "s3",
+		Region:    "eu-west-1",
+		AccountID: "123456789012",
+		Resource:  "my-bucket",
+	}
+
+	testCases := map[string]struct {
+		legacyOwner    string
+		legacyMsgs     int
+		legacyInFlight int
+		// number of reconciliations
+		attempts int
+
+		expectLegacyDeleted bool
+		expectMoved         int
+	}{
+		"no legacy queue": {},
+		"legacy queue owned by another source": {
+			legacyOwner: "io.triggermesh.awss3sources.other-ns.name",
+			legacyMsgs:  3,
+		},
+		"legacy queue with messages": {
+			legacyOwner:         sourceID(src),
+			legacyMsgs:          25,
+			expectLegacyDeleted: true,
+			expectMoved:         25,
+		},
+		"legacy queue with more messages than a reconciliation moves": {
+			legacyOwner: sourceID(src),
+			legacyMsgs:  75,
+			attempts:    1,
+			expectMoved: 50,
+		},
+		"legacy queue migrated over multiple reconciliations": {
+			legacyOwner:         sourceID(src),
+			legacyMsgs:          75,
+			attempts:            2,
+			expectLegacyDeleted: true,
+			expectMoved:         75,
+		},
+		"legacy queue with in flight messages": {
+			legacyOwner:    sourceID(src),
+			legacyMsgs:     5,
+			legacyInFlight: 1,
+			expectMoved:    5,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			cli := &fakeSQS{queues: map[string]*fakeQueue{
+				queueName(src): {},
+			}}
+			if tc.legacyOwner != "" {
+				legacy := &fakeQueue{
+					tags:     map[string]string{"owned-by": tc.legacyOwner},
+					inFlight: tc.legacyInFlight,
+				}
+				for i := 0; i < tc.legacyMsgs; i++ {
+					legacy.msgs = append(legacy.msgs, "msg"+strconv.Itoa(i))
+				}
+				cli.queues[legacyQueueName(src)] = legacy
+			}
+
+			attempts := tc.attempts
+			if attempts == 0 {
+				attempts = 1
+			}
+			for i := 0; i < attempts; i++ {
+				err := MigrateLegacyQueue(context.Background(), src, cli)
+				require.NoError(t, err)
+			}
+
+			_, legacyExists := cli.queues[legacyQueueName(src)]
+			assert.Equal(t, tc.legacyOwner != "" && !tc.expectLegacyDeleted, legacyExists)
+			assert.Len(t, cli.queues[queueName(src)].msgs, tc.expectMoved)
+		})
+	}
+}
+
+func TestEnsureQueueObserveMode(t *testing.T) {
+	src := &v1alpha1.AWSS3Source{}
+	src.Namespace, src.Name = "ns", "name"
+	src.Spec.ARN = apis.ARN{
+		Partition: "aws",
+		Service:   "s3",
+		Region:    "eu-west-1",
+		AccountID: "123456789012",
+		Resource:  "my-bucket",
+	}
+
+	queueARN := managedQueueARN(src)
+
+	inSyncAttrs := func() map[string]string {
+		attrs := makeQueueAttributes(src, "")
+		attrs[awssqs.QueueAttributeNameQueueArn] = queueARN
+
+		pol, _, err := iam.MergeStatement("", makeQueuePolicyStatement(queueARN, src))
+		require.NoError(t, err)
+		attrs[awssqs.QueueAttributeNamePolicy] = pol
+
+		return attrs
+	}
+
+	testCases := map[string]struct {
+		queue *fakeQueue
+
+		expectDrifts []string
+	}{
+		"queue does not exist": {
+			queue:        nil,
+			expectDrifts: []string{`SQS queue "` + queueName(src) + `"`},
+		},
+		"queue in sync": {
+			queue:        &fakeQueue{attrs: inSyncAttrs()},
+			expectDrifts: nil,
+		},
+		"policy with legacy statement": {
+			queue: &fakeQueue{attrs: func() map[string]string {
+				attrs := inSyncAttrs()
+
+				// statements created before Sids were derived from the
+				// source had random Sids
+				const stmtsPrefix = `{"Version":"2012-10-17","Statement":[`
+				legacyStmt := `{"Sid":"0d9a0cf5-5ce5-4d3e-9b35-b3e4b1e1d6c2","Effect":"Allow",` +
+					`"Principal":{"Service":"s3.amazonaws.com"},"Action":"sqs:SendMessage","Resource":"` + queueARN + `",` +
+					`"Condition":{"ArnEquals":{"aws:SourceArn":"arn:aws:s3:::my-bucket"},` +
+					`"StringEquals":{"aws:SourceAccount":"123456789012"}}}`
+
+				pol := attrs[awssqs.QueueAttributeNamePolicy]
+				require.True(t, strings.HasPrefix(pol, stmtsPrefix))
+				attrs[awssqs.QueueAttributeNamePolicy] = stmtsPrefix + legacyStmt + "," + pol[len(stmtsPrefix):]
+
+				return attrs
+			}()},
+			expectDrifts: []string{`Policy of SQS queue "` + queueName(src) + `"`},
+		},
+		"queue attributes and policy drifted": {
+			queue: &fakeQueue{attrs: func() map[string]string {
+				attrs := inSyncAttrs()
+				attrs[awssqs.QueueAttributeNameVisibilityTimeout] = "120"
+				delete(attrs, awssqs.QueueAttributeNamePolicy)
+				return attrs
+			}()},
+			expectDrifts: []string{
+				`Attributes of SQS queue "` + queueName(src) + `"`,
+				`Policy of SQS queue "` + queueName(src) + `"`,
+			},
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			cli := &fakeSQS{queues: map[string]*fakeQueue{}}
+			var attrsBefore map[string]string
+			if tc.queue != nil {
+				cli.queues[queueName(src)] = tc.queue
+				attrsBefore = make(map[string]string, len(tc.queue.attrs))
+				for k, v := range tc.queue.attrs {
+					attrsBefore[k] = v
+				}
+			}
+
+			ctx, drifts := withDriftReport(context.Background())
+
+			s := src.DeepCopy()
+			arn, err := EnsureQueue(ctx, s, cli)
+			require.NoError(t, err)
+			assert.Equal(t, queueARN, arn)
+			assert.Equal(t, tc.queue != nil, s.Status.QueueARN != nil, "Only existing queues should be reported in the status")
+
+			var driftedResources []string
+			for _, d := range drifts.drifts {
+				driftedResources = append(driftedResources, d.what)
+				assert.NotEmpty(t, d.diff)
+			}
+			assert.Equal(t, tc.expectDrifts, driftedResources)
+
+			assert.Empty(t, cli.created, "No queue should be created")
+			if tc.queue != nil {
+				assert.Equal(t, attrsBefore, tc.queue.attrs, "Queue attributes should not be modified")
+			}
+		})
+	}
+}
+
+func TestEnsureQueueCreation(t *testing.T) {
+	newSource := func() *v1alpha1.AWSS3Source {
+		src := &v1alpha1.AWSS3Source{}
+		src.Namespace, src.Name = "ns", "name"
+		src.Spec.ARN = apis.ARN{
+			Partition: "aws",
+			Service:   "s3",
+			Region:    "eu-west-1",
+			AccountID: "123456789012",
+			Resource:  "my-bucket",
+		}
+		return src
+	}
+
+	testCases := map[string]struct {
+		queue *v1alpha1.AWSS3SourceQueue
+
+		expectAttrs map[string]string
+	}{
+		"default settings": {
+			queue: nil,
+			expectAttrs: map[string]string{
+				awssqs.QueueAttributeNameSqsManagedSseEnabled:   "true",
+				awssqs.QueueAttributeNameMessageRetentionPeriod: "345600",
+				awssqs.QueueAttributeNameVisibilityTimeout:      "30",
+			},
+		},
+		"KMS encryption and dead-letter queue": {
+			queue: &v1alpha1.AWSS3SourceQueue{
+				Encryption:      &v1alpha1.AWSS3SourceQueueEncryption{KMSKeyID: "alias/my-key"},
+				DeadLetterQueue: &v1alpha1.AWSS3SourceDeadLetterQueue{MaxReceiveCount: 5},
+			},
+			expectAttrs: map[string]string{
+				awssqs.QueueAttributeNameKmsMasterKeyId:         "alias/my-key",
+				awssqs.QueueAttributeNameSqsManagedSseEnabled:   "false",
+				awssqs.QueueAttributeNameMessageRetentionPeriod: "345600",
+				awssqs.QueueAttributeNameVisibilityTimeout:      "30",
+				awssqs.QueueAttributeNameRedrivePolicy: makeRedrivePolicy(
+					"arn:aws:sqs:eu-west-1:123456789012:"+deadLetterQueueName(newSource()), 5),
+			},
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			src := newSource()
+			src.Spec.Queue = tc.queue
+
+			cli := &fakeSQS{
+				queues:    map[string]*fakeQueue{},
+				arnPrefix: "arn:aws:sqs:eu-west-1:123456789012:",
+			}
+
+			_, err := EnsureQueue(context.Background(), src, cli)
+			require.NoError(t, err)
+
+			require.Contains(t, cli.created, queueName(src))
+			assert.Equal(t, tc.expectAttrs, cli.created[queueName(src)])
+
+			for name, attrs := range cli.created {
+				for k, v := range attrs {
+					assert.NotEmptyf(t, v, "Attribute %s of queue %s is empty", k, name)
+				}
+			}
+		})
+	}
+}
+
+// fakeSQS is a fake SQS client that stores queues in memory, indexed by name.
+// The URL of a queue is its name, its ARN is the name prefixed by arnPrefix.
+type fakeSQS struct {
+	sqsiface.SQSAPI
+	queues    map[string]*fakeQueue
+	deleteErr error
+
+	arnPrefix string
+	// attributes of the queues created by the client, indexed by name
+	created map[string]map[string]string
+}
+
+type fakeQueue struct {
+	attrs    map[string]string
+	tags     map[string]string
+	msgs     []string
+	inFlight int
+}
+
+func (c *fakeSQS) queue(url *string) (*fakeQueue, error) {
+	q, ok := c.queues[*url]
+	if !ok {
+		return nil, awserr.New(awssqs.ErrCodeQueueDoesNotExist, "queue not found", nil)
+	}
+	return q, nil
+}
+
+func (c *fakeSQS) CreateQueueWithContext(_ aws.Context, in *awssqs.CreateQueueInput,
+	_ ...request.Option) (*awssqs.CreateQueueOutput, error) {
+
+	attrs := aws.StringValueMap(in.Attributes)
+	if c.created == nil {
+		c.created = make(map[string]map[string]string)
+	}
+	c.created[*in.QueueName] = attrs
+
+	q := &fakeQueue{attrs: make(map[string]string, len(attrs)+1), tags: aws.StringValueMap(in.Tags)}
+	for k, v := range attrs {
+		q.attrs[k] = v
+	}
+	q.attrs[awssqs.QueueAttributeNameQueueArn] = c.arnPrefix + *in.QueueName
+	c.queues[*in.QueueName] = q
+
+	return &awssqs.CreateQueueOutput{QueueUrl: in.QueueName}, nil
+}
+
+func (c *fakeSQS) SetQueueAttributesWithContext(_ aws.Context, in *awssqs.SetQueueAttributesInput,
+	_ ...request.Option) (*awssqs.SetQueueAttributesOutput, error) {
+
+	q, err := c.queue(in.QueueUrl)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range in.Attributes {
+		q.attrs[k] = *v
+	}
+	return &awssqs.SetQueueAttributesOutput{}, nil
+}
+
+func (c *fakeSQS) GetQueueUrlWithContext(_ aws.Context, in *awssqs.GetQueueUrlInput,
+	_ ...request.Option) (*awssqs.GetQueueUrlOutput, error) {
+
+	if _, err := c.queue(in.QueueName); err != nil {
+		return nil, err
+	}
+	return &awssqs.GetQueueUrlOutput{QueueUrl: in.QueueName}, nil
+}
+
+func (c *fakeSQS) ListQueueTagsWithContext(_ aws.Context, in *awssqs.ListQueueTagsInput,
+	_ ...request.Option) (*awssqs.ListQueueTagsOutput, error) {
+
+	q, err := c.queue(in.QueueUrl)
+	if err != nil {
+		return nil, err
+	}
+	return &awssqs.ListQueueTagsOutput{Tags: aws.StringMap(q.tags)}, nil
+}
+
+func (c *fakeSQS) GetQueueAttributesWithContext(_ aws.Context, in *awssqs.GetQueueAttributesInput,
+	_ ...request.Option) (*awssqs.GetQueueAttributesOutput, error) {
+
+	q, err := c.queue(in.QueueUrl)
+	if err != nil {
+		return nil, err
+	}
+	attrs := map[string]string{
+		awssqs.QueueAttributeNameApproximateNumberOfMessages:           strconv.Itoa(len(q.msgs)),
+		awssqs.QueueAttributeNameApproximateNumberOfMessagesNotVisible: strconv.Itoa(q.inFlight),
+		awssqs.QueueAttributeNameApproximateNumberOfMessagesDelayed:    "0",
+	}
+	for k, v := range q.attrs {
+		attrs[k] = v
+	}
+	return &awssqs.GetQueueAttributesOutput{Attributes: aws.StringMap(attrs)}, nil
+}
+
+func (c *fakeSQS) ReceiveMessageWithContext(_ aws.Context, in *awssqs.ReceiveMessageInput,
+	_ ...request.Option) (*awssqs.ReceiveMessageOutput, error) {
+
+	q, err := c.queue(in.QueueUrl)
+	if err != nil {
+		return nil, err
+	}
+
+	out := &awssqs.ReceiveMessageOutput{}
+	for i := 0; i < len(q.msgs) && i < int(*in.MaxNumberOfMessages); i++ {
+		out.Messages = append(out.Messages, &awssqs.Message{
+			Body:          aws.String(q.msgs[i]),
+			ReceiptHandle: aws.String(q.msgs[i]),
+		})
+	}
+	return out, nil
+}
+
+func (c *fakeSQS) SendMessageBatchWithContext(_ aws.Context, in *awssqs.SendMessageBatchInput,
+	_ ...request.Option) (*awssqs.SendMessageBatchOutput, error) {
+
+	q, err := c.queue(in.QueueUrl)
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range in.Entries {
+		q.msgs = append(q.msgs, *e.MessageBody)
+	}
+	return &awssqs.SendMessageBatchOutput{}, nil
+}
+
+func (c *fakeSQS) DeleteMessageBatchWithContext(_ aws.Context, in *awssqs.DeleteMessageBatchInput,
+	_ ...request.Option) (*awssqs.DeleteMessageBatchOutput, error) {
+
+	q, err := c.queue(in.QueueUrl)
+	if err != nil {
+		return nil, err
+	}
+
+	deleted := make(map[string]bool, len(in.Entries))
+	for _, e := range in.Entries {
+		deleted[*e.ReceiptHandle] = true
+	}
+
+	var msgs []string
+	for _, m := range q.msgs {
+		if !deleted[m] {
+			msgs = append(msgs, m)
+		}
+	}
+	q.msgs = msgs
+
+	return &awssqs.DeleteMessageBatchOutput{}, nil
+}
+
+func (c *fakeSQS) DeleteQueueWithContext(_ aws.Context, in *awssqs.DeleteQueueInput,
+	_ ...request.Option) (*awssqs.DeleteQueueOutput, error) {
+
+	if _, err := c.queue(in.QueueUrl); err != nil {
+		return nil, err
+	}
+	if c.deleteErr != nil {
+		return nil, c.deleteErr
+	}
+	delete(c.queues, *in.QueueUrl)
+	return &awssqs.DeleteQueueOutput{}, nil
+}
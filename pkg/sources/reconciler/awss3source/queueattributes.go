@@ -0,0 +1,247 @@
+/*
+Copyright 2023 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package awss3source
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	awssqs "github.com/aws/aws-sdk-go/service/sqs"
+	"github.com/aws/aws-sdk-go/service/sqs/sqsiface"
+
+	"github.com/triggermesh/scoby-hook-triggermesh/pkg/apis/sources/v1alpha1"
+	"github.com/triggermesh/scoby-hook-triggermesh/pkg/reconciler/event"
+	"github.com/triggermesh/scoby-hook-triggermesh/pkg/sources/aws/sqs"
+	"github.com/triggermesh/scoby-hook-triggermesh/pkg/sources/errclass"
+)
+
+// Default values of the SQS queue attributes which are omitted from the
+// source's spec.
+const (
+	defaultMessageRetentionPeriod = 345600 // 4 days
+	defaultVisibilityTimeout      = 30
+)
+
+// deadLetterMessageRetentionPeriod is the message retention period of
+// dead-letter queues. The retention of a message moved to a dead-letter queue
+// is based on the time it was originally enqueued, so dead-letter queues use
+// the highest value allowed by SQS.
+const deadLetterMessageRetentionPeriod = 1209600 // 14 days
+
+// managedQueueAttributes is the list of SQS queue attributes which are
+// converged to the state described in the source's spec.
+var managedQueueAttributes = []string{
+	awssqs.QueueAttributeNameSqsManagedSseEnabled,
+	awssqs.QueueAttributeNameKmsMasterKeyId,
+	awssqs.QueueAttributeNameMessageRetentionPeriod,
+	awssqs.QueueAttributeNameVisibilityTimeout,
+	awssqs.QueueAttributeNameRedrivePolicy,
+}
+
+// makeQueueAttributes returns the desired attributes of the SQS queue managed
+// for the given source instance. A non-empty dlqARN enables the redrive of
+// messages to the dead-letter queue with that ARN.
+func makeQueueAttributes(src *v1alpha1.AWSS3Source, dlqARN string) map[string]string {
+	q := src.Spec.Queue
+	if q == nil {
+		q = &v1alpha1.AWSS3SourceQueue{}
+	}
+
+	attrs := encryptionAttributes(q.Encryption)
+
+	retention := int64(defaultMessageRetentionPeriod)
+	if q.MessageRetentionPeriod != nil {
+		retention = *q.MessageRetentionPeriod
+	}
+	attrs[awssqs.QueueAttributeNameMessageRetentionPeriod] = strconv.FormatInt(retention, 10)
+
+	visibility := int64(defaultVisibilityTimeout)
+	if q.VisibilityTimeout != nil {
+		visibility = *q.VisibilityTimeout
+	}
+	attrs[awssqs.QueueAttributeNameVisibilityTimeout] = strconv.FormatInt(visibility, 10)
+
+	// an empty redrive policy removes the existing one, if any
+	var redrivePolicy string
+	if dlq := q.DeadLetterQueue; dlq != nil && dlqARN != "" {
+		redrivePolicy = makeRedrivePolicy(dlqARN, dlq.MaxReceiveCount)
+	}
+	attrs[awssqs.QueueAttributeNameRedrivePolicy] = redrivePolicy
+
+	return attrs
+}
+
+// creationAttributes returns the given desired queue attributes without the
+// ones with an empty value. Those only reset attributes of existing queues,
+// and are omitted when a queue is created.
+func creationAttributes(desired map[string]string) map[string]string {
+	attrs := make(map[string]string, len(desired))
+	for k, v := range desired {
+		if v != "" {
+			attrs[k] = v
+		}
+	}
+	return attrs
+}
+
+// makeDeadLetterQueueAttributes returns the desired attributes of the SQS
+// dead-letter queue managed for the given source instance.
+func makeDeadLetterQueueAttributes(src *v1alpha1.AWSS3Source) map[string]string {
+	attrs := encryptionAttributes(src.Spec.Queue.Encryption)
+	attrs[awssqs.QueueAttributeNameMessageRetentionPeriod] = strconv.Itoa(deadLetterMessageRetentionPeriod)
+
+	return attrs
+}
+
+// encryptionAttributes returns the SQS queue attributes which enable the
+// given server-side encryption settings. The attributes of the other
+// encryption method are explicitly reset, so that existing queues can switch
+// between both methods.
+func encryptionAttributes(enc *v1alpha1.AWSS3SourceQueueEncryption) map[string]string {
+	if enc != nil {
+		return map[string]string{
+			awssqs.QueueAttributeNameKmsMasterKeyId:       enc.KMSKeyID,
+			awssqs.QueueAttributeNameSqsManagedSseEnabled: "false",
+		}
+	}
+
+	// an empty KMS key ID removes the existing one, if any
+	return map[string]string{
+		awssqs.QueueAttributeNameSqsManagedSseEnabled: "true",
+		awssqs.QueueAttributeNameKmsMasterKeyId:       "",
+	}
+}
+
+// redrivePolicy is the JSON representation of the RedrivePolicy attribute of
+// a SQS queue.
+type redrivePolicy struct {
+	DeadLetterTargetARN string      `json:"deadLetterTargetArn"`
+	MaxReceiveCount     json.Number `json:"maxReceiveCount"`
+}
+
+// makeRedrivePolicy returns a serialized redrive policy which moves messages
+// to the given dead-letter queue after maxReceiveCount receives.
+func makeRedrivePolicy(dlqARN string, maxReceiveCount int64) string {
+	b, _ := json.Marshal(redrivePolicy{
+		DeadLetterTargetARN: dlqARN,
+		MaxReceiveCount:     json.Number(strconv.FormatInt(maxReceiveCount, 10)),
+	})
+	return string(b)
+}
+
+// equalRedrivePolicies returns whether two serialized redrive policies are
+// semantically equal. SQS may return the maximum receive count as either a
+// number or a string.
+func equalRedrivePolicies(a, b string) bool {
+	if a == "" || b == "" {
+		return a == b
+	}
+
+	var ap, bp redrivePolicy
+	if err := json.Unmarshal([]byte(a), &ap); err != nil {
+		return false
+	}
+	if err := json.Unmarshal([]byte(b), &bp); err != nil {
+		return false
+	}
+
+	return ap == bp
+}
+
+// queueAttributesUpdates returns the desired queue attributes which differ
+// from the current ones.
+func queueAttributesUpdates(desired, current map[string]string) map[string]string {
+	updates := make(map[string]string)
+
+	for k, v := range desired {
+		if k == awssqs.QueueAttributeNameRedrivePolicy {
+			if !equalRedrivePolicies(v, current[k]) {
+				updates[k] = v
+			}
+			continue
+		}
+
+		if current[k] != v {
+			updates[k] = v
+		}
+	}
+
+	return updates
+}
+
+// syncQueueAttributes ensures that a SQS queue has the desired attributes.
+func syncQueueAttributes(ctx context.Context, cli sqsiface.SQSAPI, queueName, queueURL string, current, desired map[string]string) error {
+	updates := queueAttributesUpdates(desired, current)
+	if len(updates) == 0 {
+		return nil
+	}
+
+	currentUpdated := make(map[string]string, len(updates))
+	for k := range updates {
+		if v, ok := current[k]; ok {
+			currentUpdated[k] = v
+		}
+	}
+	if reportDrift(ctx, fmt.Sprintf("Attributes of SQS queue %q", queueName), currentUpdated, updates) {
+		return nil
+	}
+
+	if err := sqs.SetQueueAttributes(ctx, cli, queueURL, updates); err != nil {
+		return fmt.Errorf("setting attributes of SQS queue: %w", err)
+	}
+
+	return nil
+}
+
+// ensureDeadLetterQueue ensures the existence of a SQS dead-letter queue for
+// the SQS queue managed for the given source instance, and returns its ARN.
+func ensureDeadLetterQueue(ctx context.Context, src *v1alpha1.AWSS3Source, cli sqsiface.SQSAPI) (string /*arn*/, error) {
+	dlqName := deadLetterQueueName(src)
+	desiredAttrs := makeDeadLetterQueueAttributes(src)
+
+	dlqURL, err := sqs.QueueURL(ctx, cli, dlqName)
+	switch {
+	case errclass.IsNotFound(err):
+		createAttrs := creationAttributes(desiredAttrs)
+		if reportDrift(ctx, fmt.Sprintf("SQS dead-letter queue %q", dlqName), nil, createAttrs) {
+			return bucketLocalARN(src, "sqs", dlqName), nil
+		}
+
+		dlqURL, err = sqs.CreateQueue(ctx, cli, dlqName, createAttrs, resourceTags(src))
+		if err != nil {
+			return "", fmt.Errorf("error creating SQS dead-letter queue: %s", toErrMsg(err))
+		}
+		event.Normal(ctx, ReasonQueueCreated, "Created SQS dead-letter queue %q", dlqURL)
+
+	case err != nil:
+		return "", fmt.Errorf("failed to determine URL of SQS dead-letter queue: %s", toErrMsg(err))
+	}
+
+	getAttrs := append([]string{awssqs.QueueAttributeNameQueueArn}, managedQueueAttributes...)
+	dlqAttrs, err := sqs.QueueAttributes(ctx, cli, dlqURL, getAttrs)
+	if err != nil {
+		return "", fmt.Errorf("getting attributes of SQS dead-letter queue: %s", toErrMsg(err))
+	}
+
+	if err := syncQueueAttributes(ctx, cli, dlqName, dlqURL, dlqAttrs, desiredAttrs); err != nil {
+		return "", fmt.Errorf("error synchronizing attributes of SQS dead-letter queue: %s", toErrMsg(err))
+	}
+
+	return dlqAttrs[awssqs.QueueAttributeNameQueueArn], nil
+}
@@ -0,0 +1,146 @@
+/*
+Copyright 2023 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package awss3source
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/triggermesh/scoby-hook-triggermesh/pkg/apis/sources/v1alpha1"
+)
+
+func TestQueueAttributesUpdates(t *testing.T) {
+	const dlqARN = "arn:aws:sqs:eu-west-1:123456789012:my-dlq"
+
+	// defaultAttrs returns the attributes of a queue created with the
+	// default settings, as returned by the SQS API.
+	defaultAttrs := func(kv ...string) map[string]string {
+		attrs := map[string]string{
+			"SqsManagedSseEnabled":   "true",
+			"MessageRetentionPeriod": "345600",
+			"VisibilityTimeout":      "30",
+		}
+		for i := 0; i < len(kv); i += 2 {
+			attrs[kv[i]] = kv[i+1]
+		}
+		return attrs
+	}
+
+	testCases := map[string]struct {
+		queue   *v1alpha1.AWSS3SourceQueue
+		current map[string]string
+		expect  map[string]string
+	}{
+		"defaults up to date": {
+			current: defaultAttrs(),
+			expect:  map[string]string{},
+		},
+		"defaults with drift": {
+			current: defaultAttrs(
+				"SqsManagedSseEnabled", "false",
+				"KmsMasterKeyId", "alias/my-key",
+				"VisibilityTimeout", "60",
+				"RedrivePolicy", `{"deadLetterTargetArn":"`+dlqARN+`","maxReceiveCount":5}`,
+			),
+			expect: map[string]string{
+				"SqsManagedSseEnabled": "true",
+				"KmsMasterKeyId":       "",
+				"VisibilityTimeout":    "30",
+				"RedrivePolicy":        "",
+			},
+		},
+		"switch from KMS to SSE-SQS": {
+			current: defaultAttrs(
+				"SqsManagedSseEnabled", "false",
+				"KmsMasterKeyId", "alias/my-key",
+			),
+			expect: map[string]string{
+				"SqsManagedSseEnabled": "true",
+				"KmsMasterKeyId":       "",
+			},
+		},
+		"switch from SSE-SQS to KMS": {
+			queue: &v1alpha1.AWSS3SourceQueue{
+				Encryption: &v1alpha1.AWSS3SourceQueueEncryption{KMSKeyID: "alias/my-key"},
+			},
+			current: defaultAttrs(),
+			expect: map[string]string{
+				"SqsManagedSseEnabled": "false",
+				"KmsMasterKeyId":       "alias/my-key",
+			},
+		},
+		"KMS up to date": {
+			queue: &v1alpha1.AWSS3SourceQueue{
+				Encryption: &v1alpha1.AWSS3SourceQueueEncryption{KMSKeyID: "alias/my-key"},
+			},
+			current: defaultAttrs(
+				"SqsManagedSseEnabled", "false",
+				"KmsMasterKeyId", "alias/my-key",
+			),
+			expect: map[string]string{},
+		},
+		"custom attributes": {
+			queue: &v1alpha1.AWSS3SourceQueue{
+				Encryption:             &v1alpha1.AWSS3SourceQueueEncryption{KMSKeyID: "alias/my-key"},
+				MessageRetentionPeriod: aws.Int64(86400),
+				VisibilityTimeout:      aws.Int64(120),
+				DeadLetterQueue:        &v1alpha1.AWSS3SourceDeadLetterQueue{MaxReceiveCount: 5},
+			},
+			current: defaultAttrs(),
+			expect: map[string]string{
+				"SqsManagedSseEnabled":   "false",
+				"KmsMasterKeyId":         "alias/my-key",
+				"MessageRetentionPeriod": "86400",
+				"VisibilityTimeout":      "120",
+				"RedrivePolicy":          `{"deadLetterTargetArn":"` + dlqARN + `","maxReceiveCount":5}`,
+			},
+		},
+		"redrive policy up to date": {
+			queue: &v1alpha1.AWSS3SourceQueue{
+				DeadLetterQueue: &v1alpha1.AWSS3SourceDeadLetterQueue{MaxReceiveCount: 5},
+			},
+			current: defaultAttrs(
+				"RedrivePolicy", `{"maxReceiveCount":"5","deadLetterTargetArn":"`+dlqARN+`"}`,
+			),
+			expect: map[string]string{},
+		},
+		"redrive policy with drift": {
+			queue: &v1alpha1.AWSS3SourceQueue{
+				DeadLetterQueue: &v1alpha1.AWSS3SourceDeadLetterQueue{MaxReceiveCount: 10},
+			},
+			current: defaultAttrs(
+				"RedrivePolicy", `{"deadLetterTargetArn":"`+dlqARN+`","maxReceiveCount":5}`,
+			),
+			expect: map[string]string{
+				"RedrivePolicy": `{"deadLetterTargetArn":"` + dlqARN + `","maxReceiveCount":10}`,
+			},
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			src := &v1alpha1.AWSS3Source{}
+			src.Spec.Queue = tc.queue
+
+			desired := makeQueueAttributes(src, dlqARN)
+
+			assert.Equal(t, tc.expect, queueAttributesUpdates(desired, tc.current))
+		})
+	}
+}
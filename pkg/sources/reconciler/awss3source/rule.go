@@ -0,0 +1,250 @@
+/*
+Copyright 2023 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package awss3source
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/service/eventbridge/eventbridgeiface"
+	"go.opencensus.io/trace"
+
+	"github.com/triggermesh/scoby-hook-triggermesh/pkg/apis/sources/v1alpha1"
+	"github.com/triggermesh/scoby-hook-triggermesh/pkg/reconciler/event"
+	"github.com/triggermesh/scoby-hook-triggermesh/pkg/sources/aws/eventbridge"
+	"github.com/triggermesh/scoby-hook-triggermesh/pkg/sources/errclass"
+)
+
+// ruleTargetID is the ID of the SQS queue in the targets of the EventBridge
+// rule managed for a source.
+const ruleTargetID = "triggermesh-awss3source"
+
+// EnsureRule ensures the existence of an EventBridge rule which routes the S3
+// event notifications of the source's bucket to the given SQS queue.
+func EnsureRule(ctx context.Context, src *v1alpha1.AWSS3Source, cli eventbridgeiface.EventBridgeAPI, queueARN string) error {
+	ctx, span := trace.StartSpan(ctx, "awss3source.EnsureRule")
+	defer span.End()
+
+	desiredPattern, err := makeEventPattern(src)
+	if err != nil {
+		return fmt.Errorf("invalid EventBridge destination: %w", err)
+	}
+
+	ruleName := ruleName(src)
+
+	rule, err := eventbridge.GetRule(ctx, cli, ruleName)
+	switch {
+	case errclass.IsNotFound(err):
+		if reportDrift(ctx, fmt.Sprintf("EventBridge rule %q", ruleName), nil, map[string]interface{}{
+			"EventPattern": desiredPattern,
+			"Tags":         resourceTags(src),
+		}) {
+			return nil
+		}
+
+		if _, err := eventbridge.PutRule(ctx, cli, ruleName, desiredPattern, resourceTags(src)); err != nil {
+			return fmt.Errorf("error creating EventBridge rule for event notifications: %s", toErrMsg(err))
+		}
+		event.Normal(ctx, ReasonRuleCreated, "Created EventBridge rule %q", ruleName)
+
+	case errclass.IsAWSError(err):
+		// All documented API errors require some user intervention and
+		// are not to be retried.
+		return fmt.Errorf("request to EventBridge API got rejected: %s", toErrMsg(err))
+
+	case err != nil:
+		return fmt.Errorf("failed to get EventBridge rule: %s", toErrMsg(err))
+
+	case !equalEventPatterns(desiredPattern, rule.EventPattern):
+		if reportDrift(ctx, fmt.Sprintf("Event pattern of EventBridge rule %q", ruleName), rule.EventPattern, desiredPattern) {
+			break
+		}
+		if _, err := eventbridge.PutRule(ctx, cli, ruleName, desiredPattern, nil); err != nil {
+			return fmt.Errorf("error updating EventBridge rule: %s", toErrMsg(err))
+		}
+	}
+
+	targets, err := eventbridge.RuleTargets(ctx, cli, ruleName)
+	if err != nil {
+		return fmt.Errorf("failed to list targets of EventBridge rule: %s", toErrMsg(err))
+	}
+
+	if targets[ruleTargetID] != queueARN {
+		if reportDrift(ctx, fmt.Sprintf("Target of EventBridge rule %q", ruleName), targets[ruleTargetID], queueARN) {
+			return nil
+		}
+		if err := eventbridge.PutTarget(ctx, cli, ruleName, ruleTargetID, queueARN); err != nil {
+			return fmt.Errorf("error setting SQS queue as target of EventBridge rule: %s", toErrMsg(err))
+		}
+	}
+
+	return nil
+}
+
+// EnsureNoRule ensures that the EventBridge rule created for routing S3 event
+// notifications is deleted.
+func EnsureNoRule(ctx context.Context, src *v1alpha1.AWSS3Source, cli eventbridgeiface.EventBridgeAPI) error {
+	ctx, span := trace.StartSpan(ctx, "awss3source.EnsureNoRule")
+	defer span.End()
+
+	ruleName := ruleName(src)
+
+	rule, err := eventbridge.GetRule(ctx, cli, ruleName)
+	switch {
+	case errclass.IsNotFound(err):
+		event.Warn(ctx, ReasonUnsubscribed, "Rule not found, skipping deletion")
+		return nil
+	case errclass.IsDenied(err):
+		// the finalizer can only recover from auth errors if the
+		// permissions are granted, or if the deletion policy is
+		// changed to retain AWS resources
+		return fmt.Errorf("authorization error getting EventBridge rule: %w", err)
+	case err != nil:
+		return fmt.Errorf("failed to get EventBridge rule: %s", toErrMsg(err))
+	}
+
+	tags, err := eventbridge.RuleTags(ctx, cli, rule.ARN)
+	if err != nil {
+		return fmt.Errorf("failed to verify owner of EventBridge rule: %s", toErrMsg(err))
+	}
+
+	if tags["owned-by"] != sourceID(src) {
+		event.Warn(ctx, ReasonUnsubscribed, "Rule %q is not owned by this source instance, "+
+			"skipping deletion", ruleName)
+		return nil
+	}
+
+	err = eventbridge.DeleteRule(ctx, cli, ruleName, ruleTargetID)
+	switch {
+	case errclass.IsDenied(err):
+		return fmt.Errorf("authorization error deleting EventBridge rule: %w", err)
+	case err != nil:
+		return fmt.Errorf("error deleting EventBridge rule: %s", toErrMsg(err))
+	}
+
+	event.Normal(ctx, ReasonRuleDeleted, "Deleted EventBridge rule %q", ruleName)
+
+	return nil
+}
+
+// eventBridgeDetailTypes maps S3 event notification types to the detail types
+// of the corresponding events sent by S3 to EventBridge.
+// EventBridge events do not distinguish the subtypes of some notification
+// types (e.g. "s3:ObjectCreated:Put"), which therefore match all the events
+// of their parent type.
+// https://docs.aws.amazon.com/AmazonS3/latest/userguide/EventBridge.html
+var eventBridgeDetailTypes = map[string][]string{
+	"s3:ObjectCreated:":          {"Object Created"},
+	"s3:ObjectRemoved:":          {"Object Deleted"},
+	"s3:ObjectRestore:Post":      {"Object Restore Initiated"},
+	"s3:ObjectRestore:Completed": {"Object Restore Completed"},
+	"s3:ObjectRestore:Delete":    {"Object Restore Expired"},
+	"s3:ObjectRestore:*":         {"Object Restore Initiated", "Object Restore Completed", "Object Restore Expired"},
+}
+
+// makeEventPattern returns the pattern of the EventBridge rule which matches
+// the S3 events of the given source.
+// https://docs.aws.amazon.com/eventbridge/latest/userguide/eb-event-patterns.html
+func makeEventPattern(src *v1alpha1.AWSS3Source) (string, error) {
+	detailTypesSet := make(map[string]struct{})
+
+	for _, t := range src.Spec.EventTypes {
+		var detailTypes []string
+		for prefix, dt := range eventBridgeDetailTypes {
+			if strings.HasPrefix(t, prefix) {
+				detailTypes = dt
+				break
+			}
+		}
+		if detailTypes == nil {
+			return "", fmt.Errorf("event type %q is not supported by EventBridge", t)
+		}
+
+		for _, dt := range detailTypes {
+			detailTypesSet[dt] = struct{}{}
+		}
+	}
+
+	detailTypes := make([]string, 0, len(detailTypesSet))
+	for dt := range detailTypesSet {
+		detailTypes = append(detailTypes, dt)
+	}
+	sort.Strings(detailTypes)
+
+	detail := map[string]interface{}{
+		"bucket": map[string][]string{
+			"name": {src.Spec.ARN.Resource},
+		},
+	}
+	if m := keyMatcher(src.Spec.Filter); m != nil {
+		detail["object"] = map[string]interface{}{
+			"key": []interface{}{m},
+		}
+	}
+
+	pattern, err := json.Marshal(map[string]interface{}{
+		"source":      []string{"aws.s3"},
+		"detail-type": detailTypes,
+		"detail":      detail,
+	})
+	if err != nil {
+		return "", fmt.Errorf("serializing event pattern to JSON: %w", err)
+	}
+
+	return string(pattern), nil
+}
+
+// keyMatcher returns an EventBridge content filter which matches object keys
+// according to the given filter rules, or nil if no rule is set.
+func keyMatcher(f *v1alpha1.AWSS3SourceFilter) map[string]string {
+	switch {
+	case f == nil || f.Prefix == "" && f.Suffix == "":
+		return nil
+	case f.Suffix == "":
+		return map[string]string{"prefix": f.Prefix}
+	case f.Prefix == "":
+		return map[string]string{"suffix": f.Suffix}
+	}
+
+	// wildcard characters must be escaped in the literal parts of the
+	// pattern
+	esc := strings.NewReplacer(`\`, `\\`, `*`, `\*`)
+	return map[string]string{"wildcard": esc.Replace(f.Prefix) + "*" + esc.Replace(f.Suffix)}
+}
+
+// equalEventPatterns returns whether two event patterns are semantically
+// equal.
+func equalEventPatterns(a, b string) bool {
+	var pa, pb interface{}
+	if err := json.Unmarshal([]byte(a), &pa); err != nil {
+		return false
+	}
+	if err := json.Unmarshal([]byte(b), &pb); err != nil {
+		return false
+	}
+	return reflect.DeepEqual(pa, pb)
+}
+
+// ruleARN returns the ARN of the EventBridge rule managed for the given
+// source instance.
+func ruleARN(src *v1alpha1.AWSS3Source) string {
+	return bucketLocalARN(src, "events", "rule/"+ruleName(src))
+}
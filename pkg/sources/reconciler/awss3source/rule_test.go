@@ -0,0 +1,93 @@
+/*
+Copyright 2023 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package awss3source
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/triggermesh/scoby-hook-triggermesh/pkg/apis"
+	"github.com/triggermesh/scoby-hook-triggermesh/pkg/apis/sources/v1alpha1"
+)
+
+func TestMakeEventPattern(t *testing.T) {
+	testCases := map[string]struct {
+		eventTypes []string
+		filter     *v1alpha1.AWSS3SourceFilter
+		expect     string
+		expectErr  bool
+	}{
+		"subtypes match their parent type": {
+			eventTypes: []string{"s3:ObjectCreated:Put", "s3:ObjectCreated:Copy", "s3:ObjectRemoved:*"},
+			expect: `{"source":["aws.s3"],"detail-type":["Object Created","Object Deleted"],` +
+				`"detail":{"bucket":{"name":["my-bucket"]}}}`,
+		},
+		"restore types": {
+			eventTypes: []string{"s3:ObjectRestore:*"},
+			expect: `{"source":["aws.s3"],"detail-type":["Object Restore Completed","Object Restore Expired","Object Restore Initiated"],` +
+				`"detail":{"bucket":{"name":["my-bucket"]}}}`,
+		},
+		"restore expiration": {
+			eventTypes: []string{"s3:ObjectRestore:Delete", "s3:ObjectRestore:Completed"},
+			expect: `{"source":["aws.s3"],"detail-type":["Object Restore Completed","Object Restore Expired"],` +
+				`"detail":{"bucket":{"name":["my-bucket"]}}}`,
+		},
+		"prefix filter": {
+			eventTypes: []string{"s3:ObjectCreated:*"},
+			filter:     &v1alpha1.AWSS3SourceFilter{Prefix: "images/"},
+			expect: `{"source":["aws.s3"],"detail-type":["Object Created"],` +
+				`"detail":{"bucket":{"name":["my-bucket"]},"object":{"key":[{"prefix":"images/"}]}}}`,
+		},
+		"suffix filter": {
+			eventTypes: []string{"s3:ObjectCreated:*"},
+			filter:     &v1alpha1.AWSS3SourceFilter{Suffix: ".jpg"},
+			expect: `{"source":["aws.s3"],"detail-type":["Object Created"],` +
+				`"detail":{"bucket":{"name":["my-bucket"]},"object":{"key":[{"suffix":".jpg"}]}}}`,
+		},
+		"prefix and suffix filter": {
+			eventTypes: []string{"s3:ObjectCreated:*"},
+			filter:     &v1alpha1.AWSS3SourceFilter{Prefix: "a*b/", Suffix: ".jpg"},
+			expect: `{"source":["aws.s3"],"detail-type":["Object Created"],` +
+				`"detail":{"bucket":{"name":["my-bucket"]},"object":{"key":[{"wildcard":"a\\*b/*.jpg"}]}}}`,
+		},
+		"unsupported type": {
+			eventTypes: []string{"s3:Replication:*"},
+			expectErr:  true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			src := &v1alpha1.AWSS3Source{}
+			src.Spec.ARN = apis.ARN{Partition: "aws", Service: "s3", Resource: "my-bucket"}
+			src.Spec.EventTypes = tc.eventTypes
+			src.Spec.Filter = tc.filter
+
+			pattern, err := makeEventPattern(src)
+			if tc.expectErr {
+				assert.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.JSONEq(t, tc.expect, pattern)
+			assert.True(t, equalEventPatterns(tc.expect, pattern))
+		})
+	}
+}
@@ -0,0 +1,102 @@
+/*
+Copyright 2023 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package awss3source
+
+import (
+	"context"
+	"fmt"
+
+	"go.opencensus.io/trace"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	coreclientv1 "k8s.io/client-go/kubernetes/typed/core/v1"
+
+	commonv1alpha1 "github.com/triggermesh/scoby-hook-triggermesh/pkg/apis/common/v1alpha1"
+	"github.com/triggermesh/scoby-hook-triggermesh/pkg/apis/sources/v1alpha1"
+	"github.com/triggermesh/scoby-hook-triggermesh/pkg/reconciler/event"
+	"github.com/triggermesh/scoby-hook-triggermesh/pkg/reconciler/resource"
+	"github.com/triggermesh/scoby-hook-triggermesh/pkg/sources/errclass"
+)
+
+// EnsureServiceAccount ensures the existence of a ServiceAccount annotated
+// with the EKS IAM role of the source, for sources which authenticate using
+// IAM Roles for Service Accounts (IRSA). The hook exchanges tokens of this
+// ServiceAccount for the role's credentials.
+//
+// The adapter does not run as this ServiceAccount: Scoby neither allows
+// registrations nor hooks to set the ServiceAccount of workloads, so the
+// adapter obtains AWS credentials from the ServiceAccount of its own Pods.
+// The name of the ServiceAccount is reported in the status of the source.
+//
+// The ServiceAccount is controlled by the source, which means that it is
+// garbage collected along with it. Unlike AWS resources, it is also managed in
+// the Observe mode, since AWS credentials can't be obtained without it. It
+// returns the name of the ServiceAccount, or an empty string if the source
+// doesn't authenticate using an IAM role.
+func EnsureServiceAccount(ctx context.Context, src *v1alpha1.AWSS3Source, cli coreclientv1.ServiceAccountInterface) (string, error) {
+	ctx, span := trace.StartSpan(ctx, "awss3source.EnsureServiceAccount")
+	defer span.End()
+
+	iamRole := src.Spec.Auth.EksIAMRole
+	if iamRole == nil {
+		return "", nil
+	}
+
+	desired := resource.NewServiceAccount(src.Namespace, src.ServiceAccountName(),
+		resource.Controller(src),
+	)
+	commonv1alpha1.AwsIamRoleAnnotation(*iamRole)(desired)
+
+	current, err := cli.Get(ctx, desired.Name, metav1.GetOptions{})
+	switch {
+	case errclass.IsNotFound(err):
+		if _, err := cli.Create(ctx, desired, metav1.CreateOptions{}); err != nil {
+			return "", fmt.Errorf("creating ServiceAccount: %w", err)
+		}
+		event.Normal(ctx, ReasonServiceAccountCreated, "Created ServiceAccount %q", desired.Name)
+		return desired.Name, nil
+
+	case err != nil:
+		return "", fmt.Errorf("getting ServiceAccount: %w", err)
+	}
+
+	// A ServiceAccount that is not controlled by the source may be in use
+	// by other workloads, so its role is never overridden.
+	if !metav1.IsControlledBy(current, src) {
+		return "", fmt.Errorf("ServiceAccount %q already exists and is not controlled by the source", current.Name)
+	}
+
+	if roleAnnotation(current) == roleAnnotation(desired) {
+		return current.Name, nil
+	}
+
+	current = current.DeepCopy()
+	commonv1alpha1.AwsIamRoleAnnotation(*iamRole)(current)
+
+	if _, err := cli.Update(ctx, current, metav1.UpdateOptions{}); err != nil {
+		return "", fmt.Errorf("updating IAM role of ServiceAccount: %w", err)
+	}
+	event.Normal(ctx, ReasonServiceAccountUpdated, "Updated IAM role of ServiceAccount %q", current.Name)
+
+	return current.Name, nil
+}
+
+// roleAnnotation returns the value of the EKS role-arn annotation of the
+// given ServiceAccount.
+func roleAnnotation(sa *corev1.ServiceAccount) string {
+	return sa.Annotations[commonv1alpha1.AnnotationEksIAMRole]
+}
@@ -0,0 +1,258 @@
+/*
+Copyright 2023 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package awss3source
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+
+	pkgapis "knative.dev/pkg/apis"
+	"knative.dev/pkg/kmeta"
+
+	"github.com/triggermesh/scoby-hook-triggermesh/pkg/apis"
+	commonv1alpha1 "github.com/triggermesh/scoby-hook-triggermesh/pkg/apis/common/v1alpha1"
+	"github.com/triggermesh/scoby-hook-triggermesh/pkg/apis/sources/v1alpha1"
+	s3client "github.com/triggermesh/scoby-hook-triggermesh/pkg/sources/client/s3"
+)
+
+func TestEnsureServiceAccount(t *testing.T) {
+	const (
+		tNs   = "test-ns"
+		tName = "my-source"
+		tSA   = "my-source-awss3source"
+
+		tRoleARN = "arn:aws:iam::123456789012:role/s3"
+	)
+
+	iamRole := &apis.ARN{Partition: "aws", Service: "iam", AccountID: "123456789012", Resource: "role/s3"}
+
+	newSource := func(iamRole *apis.ARN) *v1alpha1.AWSS3Source {
+		src := &v1alpha1.AWSS3Source{}
+		src.Namespace = tNs
+		src.Name = tName
+		src.UID = "00000000-0000-0000-0000-000000000000"
+		src.Spec.Auth.EksIAMRole = iamRole
+		return src
+	}
+
+	newServiceAccount := func(role string, owner kmeta.OwnerRefable) *corev1.ServiceAccount {
+		sa := &corev1.ServiceAccount{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace:   tNs,
+				Name:        tSA,
+				Annotations: map[string]string{commonv1alpha1.AnnotationEksIAMRole: role},
+			},
+		}
+		if owner != nil {
+			sa.OwnerReferences = []metav1.OwnerReference{*kmeta.NewControllerRef(owner)}
+		}
+		return sa
+	}
+
+	testCases := map[string]struct {
+		iamRole  *apis.ARN
+		existing []runtime.Object
+		observe  bool
+
+		expectName    string
+		expectErr     bool
+		expectActions []string
+		expectRole    string
+	}{
+		"static credentials": {
+			iamRole:       nil,
+			expectName:    "",
+			expectActions: nil,
+		},
+		"service account does not exist": {
+			iamRole:       iamRole,
+			expectName:    tSA,
+			expectActions: []string{"get", "create"},
+			expectRole:    tRoleARN,
+		},
+		"service account up-to-date": {
+			iamRole:       iamRole,
+			existing:      []runtime.Object{newServiceAccount(tRoleARN, newSource(nil))},
+			expectName:    tSA,
+			expectActions: []string{"get"},
+			expectRole:    tRoleARN,
+		},
+		"service account with other role": {
+			iamRole:       iamRole,
+			existing:      []runtime.Object{newServiceAccount("arn:aws:iam::123456789012:role/other", newSource(nil))},
+			expectName:    tSA,
+			expectActions: []string{"get", "update"},
+			expectRole:    tRoleARN,
+		},
+		"service account does not exist in Observe mode": {
+			iamRole:       iamRole,
+			observe:       true,
+			expectName:    tSA,
+			expectActions: []string{"get", "create"},
+			expectRole:    tRoleARN,
+		},
+		"service account with other role in Observe mode": {
+			iamRole:       iamRole,
+			existing:      []runtime.Object{newServiceAccount("arn:aws:iam::123456789012:role/other", newSource(nil))},
+			observe:       true,
+			expectName:    tSA,
+			expectActions: []string{"get", "update"},
+			expectRole:    tRoleARN,
+		},
+		"service account not controlled by the source": {
+			iamRole:       iamRole,
+			existing:      []runtime.Object{newServiceAccount("arn:aws:iam::123456789012:role/other", nil)},
+			expectErr:     true,
+			expectActions: []string{"get"},
+			expectRole:    "arn:aws:iam::123456789012:role/other",
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			kc := fake.NewSimpleClientset(tc.existing...)
+			src := newSource(tc.iamRole)
+
+			ctx := context.Background()
+			if tc.observe {
+				ctx, _ = withDriftReport(ctx)
+			}
+
+			saName, err := EnsureServiceAccount(ctx, src, kc.CoreV1().ServiceAccounts(tNs))
+			if tc.expectErr {
+				assert.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+			assert.Equal(t, tc.expectName, saName)
+
+			var actions []string
+			for _, a := range kc.Actions() {
+				actions = append(actions, a.GetVerb())
+			}
+			assert.Equal(t, tc.expectActions, actions)
+
+			if tc.expectRole == "" {
+				return
+			}
+
+			sa, err := kc.CoreV1().ServiceAccounts(tNs).Get(context.Background(), tSA, metav1.GetOptions{})
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectRole, sa.Annotations[commonv1alpha1.AnnotationEksIAMRole])
+			if !tc.expectErr {
+				assert.True(t, metav1.IsControlledBy(sa, src), "Expected the ServiceAccount to be controlled by the source")
+			}
+		})
+	}
+}
+
+// AWS credentials of sources which authenticate using an EKS IAM role are
+// obtained with a token of the source's ServiceAccount, which must therefore
+// exist regardless of the reconcile mode.
+func TestEnsureServiceAccountObserveModeClients(t *testing.T) {
+	const (
+		bucketLocationResponse = `<LocationConstraint xmlns="http://s3.amazonaws.com/doc/2006-03-01/">eu-west-1</LocationConstraint>`
+
+		callerIdentityResponse = `<GetCallerIdentityResponse xmlns="https://sts.amazonaws.com/doc/2011-06-15/">
+  <GetCallerIdentityResult>
+    <Arn>arn:aws:sts::123456789012:assumed-role/s3/fake</Arn>
+    <UserId>AROAFAKE:fake</UserId>
+    <Account>123456789012</Account>
+  </GetCallerIdentityResult>
+</GetCallerIdentityResponse>`
+
+		assumeRoleWithWebIdentityResponse = `<AssumeRoleWithWebIdentityResponse xmlns="https://sts.amazonaws.com/doc/2011-06-15/">
+  <AssumeRoleWithWebIdentityResult>
+    <Credentials>
+      <AccessKeyId>ASIAFAKE</AccessKeyId>
+      <SecretAccessKey>fake</SecretAccessKey>
+      <SessionToken>fake</SessionToken>
+      <Expiration>2100-01-01T00:00:00Z</Expiration>
+    </Credentials>
+  </AssumeRoleWithWebIdentityResult>
+</AssumeRoleWithWebIdentityResponse>`
+	)
+
+	// stand-in for the S3 and STS APIs
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/my-bucket" {
+			_, _ = w.Write([]byte(bucketLocationResponse))
+			return
+		}
+		_ = r.ParseForm()
+		if r.PostForm.Get("Action") == "AssumeRoleWithWebIdentity" {
+			_, _ = w.Write([]byte(assumeRoleWithWebIdentityResponse))
+			return
+		}
+		_, _ = w.Write([]byte(callerIdentityResponse))
+	}))
+	t.Cleanup(srv.Close)
+
+	endpointURL, err := pkgapis.ParseURL(srv.URL)
+	require.NoError(t, err)
+
+	src := &v1alpha1.AWSS3Source{}
+	src.Namespace, src.Name = "test-ns", "my-source"
+	src.UID = "00000000-0000-0000-0000-000000000000"
+	src.Spec.ARN = apis.ARN{Partition: "aws", Service: "s3", Resource: "my-bucket"}
+	src.Spec.ReconcileMode = v1alpha1.AWSS3SourceReconcileModeObserve
+	src.Spec.Auth.EksIAMRole = &apis.ARN{Partition: "aws", Service: "iam", AccountID: "123456789012", Resource: "role/s3"}
+	src.Spec.Endpoint = &commonv1alpha1.AWSEndpoint{URL: endpointURL}
+
+	kc := fake.NewSimpleClientset()
+
+	// like the API server, refuse to issue tokens for ServiceAccounts
+	// which don't exist
+	kc.PrependReactor("create", "serviceaccounts", func(a k8stesting.Action) (bool, runtime.Object, error) {
+		ca := a.(k8stesting.CreateAction)
+		if ca.GetSubresource() != "token" {
+			return false, nil, nil
+		}
+		saName := ca.(k8stesting.CreateActionImpl).Name
+		if _, err := kc.Tracker().Get(corev1.SchemeGroupVersion.WithResource("serviceaccounts"), ca.GetNamespace(), saName); err != nil {
+			return true, nil, err
+		}
+
+		tr := ca.GetObject().(*authenticationv1.TokenRequest).DeepCopy()
+		tr.Status.Token = "fake-token"
+		return true, tr, nil
+	})
+
+	ctx, _ := withDriftReport(context.Background())
+
+	saName, err := EnsureServiceAccount(ctx, src, kc.CoreV1().ServiceAccounts(src.Namespace))
+	require.NoError(t, err)
+	assert.Equal(t, src.ServiceAccountName(), saName)
+
+	cg := s3client.NewClientGetter(kc.CoreV1().Secrets, kc.CoreV1().ServiceAccounts)
+
+	clients, err := cg.Get(ctx, src)
+	require.NoError(t, err)
+	assert.NotNil(t, clients)
+}
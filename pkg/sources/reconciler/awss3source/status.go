@@ -0,0 +1,59 @@
+/*
+Copyright 2023 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package awss3source
+
+import (
+	hookv1 "github.com/triggermesh/scoby/pkg/hook/v1"
+
+	"github.com/triggermesh/scoby-hook-triggermesh/pkg/apis/sources/v1alpha1"
+)
+
+// Keys of the status annotations that report the AWS resources a source is
+// bound to. Scoby copies them to the status.annotations field of the object.
+const (
+	StatusAnnotationQueueARN      = "queueARN"
+	StatusAnnotationBucketRegion  = "bucketRegion"
+	StatusAnnotationBucketAccount = "bucketAccountID"
+	// StatusAnnotationServiceAccount is the name of the ServiceAccount
+	// annotated with the source's EKS IAM role, whose identity the hook
+	// assumes the role with.
+	StatusAnnotationServiceAccount = "serviceAccountName"
+)
+
+// setStatusAnnotations reports the AWS resource identifiers resolved during
+// the reconciliation of the source in the status annotations of the response.
+// Identifiers that are not yet known are omitted.
+func setStatusAnnotations(res *hookv1.HookResponse, src *v1alpha1.AWSS3Source) {
+	set := func(k, v string) {
+		if v == "" {
+			return
+		}
+		if res.Status.Annotations == nil {
+			res.Status.Annotations = make(map[string]string)
+		}
+		res.Status.Annotations[k] = v
+	}
+
+	set(StatusAnnotationBucketRegion, src.Spec.ARN.Region)
+	set(StatusAnnotationBucketAccount, src.Spec.ARN.AccountID)
+	if src.Status.QueueARN != nil {
+		set(StatusAnnotationQueueARN, src.Status.QueueARN.String())
+	}
+	if src.Spec.Auth.EksIAMRole != nil {
+		set(StatusAnnotationServiceAccount, src.ServiceAccountName())
+	}
+}
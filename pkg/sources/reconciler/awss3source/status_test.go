@@ -0,0 +1,82 @@
+/*
+Copyright 2023 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package awss3source
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	hookv1 "github.com/triggermesh/scoby/pkg/hook/v1"
+
+	"github.com/triggermesh/scoby-hook-triggermesh/pkg/apis"
+	"github.com/triggermesh/scoby-hook-triggermesh/pkg/apis/sources/v1alpha1"
+)
+
+func TestSetStatusAnnotations(t *testing.T) {
+	queueARN := &apis.ARN{Partition: "aws", Service: "sqs", Region: "eu-west-1", AccountID: "123456789012", Resource: "my-queue"}
+
+	testCases := map[string]struct {
+		bucketARN apis.ARN
+		queueARN  *apis.ARN
+		iamRole   *apis.ARN
+		expect    map[string]string
+	}{
+		"all identifiers resolved": {
+			bucketARN: apis.ARN{Partition: "aws", Service: "s3", Region: "eu-west-1", AccountID: "123456789012", Resource: "my-bucket"},
+			queueARN:  queueARN,
+			expect: map[string]string{
+				StatusAnnotationQueueARN:      "arn:aws:sqs:eu-west-1:123456789012:my-queue",
+				StatusAnnotationBucketRegion:  "eu-west-1",
+				StatusAnnotationBucketAccount: "123456789012",
+			},
+		},
+		"queue not yet reconciled": {
+			bucketARN: apis.ARN{Partition: "aws", Service: "s3", Region: "eu-west-1", AccountID: "123456789012", Resource: "my-bucket"},
+			expect: map[string]string{
+				StatusAnnotationBucketRegion:  "eu-west-1",
+				StatusAnnotationBucketAccount: "123456789012",
+			},
+		},
+		"authenticated with an EKS IAM role": {
+			bucketARN: apis.ARN{Partition: "aws", Service: "s3", Resource: "my-bucket"},
+			iamRole:   &apis.ARN{Partition: "aws", Service: "iam", AccountID: "123456789012", Resource: "role/s3"},
+			expect: map[string]string{
+				StatusAnnotationServiceAccount: "my-source-awss3source",
+			},
+		},
+		"nothing resolved": {
+			bucketARN: apis.ARN{Partition: "aws", Service: "s3", Resource: "my-bucket"},
+			expect:    nil,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			src := &v1alpha1.AWSS3Source{}
+			src.Name = "my-source"
+			src.Spec.ARN = tc.bucketARN
+			src.Spec.Auth.EksIAMRole = tc.iamRole
+			src.Status.QueueARN = tc.queueARN
+
+			res := &hookv1.HookResponse{Status: &hookv1.HookStatus{}}
+			setStatusAnnotations(res, src)
+
+			assert.Equal(t, tc.expect, res.Status.Annotations)
+		})
+	}
+}
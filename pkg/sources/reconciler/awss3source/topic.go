@@ -0,0 +1,224 @@
+/*
+Copyright 2023 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package awss3source
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/service/sns/snsiface"
+	"go.opencensus.io/trace"
+
+	"github.com/triggermesh/scoby-hook-triggermesh/pkg/apis/sources/v1alpha1"
+	"github.com/triggermesh/scoby-hook-triggermesh/pkg/reconciler/event"
+	"github.com/triggermesh/scoby-hook-triggermesh/pkg/sources/aws/iam"
+	"github.com/triggermesh/scoby-hook-triggermesh/pkg/sources/aws/s3"
+	"github.com/triggermesh/scoby-hook-triggermesh/pkg/sources/aws/sns"
+	"github.com/triggermesh/scoby-hook-triggermesh/pkg/sources/errclass"
+)
+
+// EnsureTopic ensures the existence of a SNS topic for sending S3 event
+// notifications, and the subscription of the given SQS queue to that topic.
+func EnsureTopic(ctx context.Context, src *v1alpha1.AWSS3Source, cli snsiface.SNSAPI, queueARN string) (string /*arn*/, error) {
+	ctx, span := trace.StartSpan(ctx, "awss3source.EnsureTopic")
+	defer span.End()
+
+	topicARN := topicARN(src)
+	managedTopicARN := topicARN
+
+	// the access policy of user-provided topics is managed by the user
+	if snsDestination(src).TopicARN == nil {
+		var err error
+		topicARN, err = ensureTopicExists(ctx, src, cli)
+		switch {
+		case errclass.IsAWSError(err):
+			// All documented API errors require some user intervention and
+			// are not to be retried.
+			return "", fmt.Errorf("request to SNS API got rejected: %s", toErrMsg(err))
+		case err != nil:
+			return "", fmt.Errorf("error creating SNS topic for event notifications: %s", toErrMsg(err))
+		case topicARN == "":
+			// the topic doesn't exist and was reported as drifted
+			return managedTopicARN, nil
+		}
+
+		if err := syncTopicPolicy(ctx, cli, topicARN, src); err != nil {
+			return "", fmt.Errorf("error synchronizing policy of SNS topic: %w", err)
+		}
+	}
+
+	if isObserving(ctx) {
+		subs, err := sns.QueueSubscriptions(ctx, cli, topicARN, queueARN)
+		if err != nil {
+			return "", fmt.Errorf("error listing subscriptions of SNS topic: %s", toErrMsg(err))
+		}
+		if len(subs) == 0 {
+			reportDrift(ctx, fmt.Sprintf("Subscription of SNS topic %q", topicARN), nil,
+				map[string]string{"Protocol": "sqs", "Endpoint": queueARN})
+		}
+		return topicARN, nil
+	}
+
+	if _, err := sns.SubscribeQueue(ctx, cli, topicARN, queueARN); err != nil {
+		return "", fmt.Errorf("error subscribing SQS queue to SNS topic: %s", toErrMsg(err))
+	}
+
+	return topicARN, nil
+}
+
+// ensureTopicExists ensures the existence of the SNS topic managed for the
+// given source instance, and returns its ARN. In the Observe mode, a missing
+// topic is reported instead of being created, and an empty ARN is returned.
+func ensureTopicExists(ctx context.Context, src *v1alpha1.AWSS3Source, cli snsiface.SNSAPI) (string /*arn*/, error) {
+	if !isObserving(ctx) {
+		// the creation of topics is idempotent
+		return sns.CreateTopic(ctx, cli, topicName(src), resourceTags(src))
+	}
+
+	arn := topicARN(src)
+
+	_, err := sns.TopicPolicy(ctx, cli, arn)
+	switch {
+	case errclass.IsNotFound(err):
+		reportDrift(ctx, fmt.Sprintf("SNS topic %q", topicName(src)), nil, map[string]interface{}{
+			"Name": topicName(src),
+			"Tags": resourceTags(src),
+		})
+		return "", nil
+	case err != nil:
+		return "", err
+	}
+
+	return arn, nil
+}
+
+// EnsureNoTopic ensures that the SNS topic created for sending S3 event
+// notifications is deleted, or that the SQS queue of the source is
+// unsubscribed from a user-provided topic.
+func EnsureNoTopic(ctx context.Context, src *v1alpha1.AWSS3Source, cli snsiface.SNSAPI) error {
+	ctx, span := trace.StartSpan(ctx, "awss3source.EnsureNoTopic")
+	defer span.End()
+
+	topicARN := topicARN(src)
+
+	if snsDestination(src).TopicARN != nil {
+		// do not delete topics managed by the user
+		err := sns.UnsubscribeQueue(ctx, cli, topicARN, managedQueueARN(src))
+		switch {
+		case errclass.IsNotFound(err):
+			return nil
+		case errclass.IsDenied(err):
+			// the finalizer can only recover from auth errors if the
+			// permissions are granted, or if the deletion policy is
+			// changed to retain AWS resources
+			return fmt.Errorf("authorization error unsubscribing SQS queue from SNS topic: %w", err)
+		case err != nil:
+			return fmt.Errorf("error unsubscribing SQS queue from SNS topic: %s", toErrMsg(err))
+		}
+		return nil
+	}
+
+	tags, err := sns.TopicTags(ctx, cli, topicARN)
+	switch {
+	case errclass.IsNotFound(err):
+		event.Warn(ctx, ReasonUnsubscribed, "Topic not found, skipping deletion")
+		return nil
+	case errclass.IsDenied(err):
+		return fmt.Errorf("authorization error getting SNS topic: %w", err)
+	case err != nil:
+		return fmt.Errorf("failed to verify owner of SNS topic: %s", toErrMsg(err))
+	}
+
+	if tags["owned-by"] != sourceID(src) {
+		event.Warn(ctx, ReasonUnsubscribed, "Topic %q is not owned by this source instance, "+
+			"skipping deletion", topicARN)
+		return nil
+	}
+
+	err = sns.DeleteTopic(ctx, cli, topicARN)
+	switch {
+	case errclass.IsDenied(err):
+		return fmt.Errorf("authorization error deleting SNS topic: %w", err)
+	case err != nil:
+		return fmt.Errorf("error deleting SNS topic: %s", toErrMsg(err))
+	}
+
+	event.Normal(ctx, ReasonTopicDeleted, "Deleted SNS topic %q", topicARN)
+
+	return nil
+}
+
+// syncTopicPolicy ensures that a SNS topic has the right permissions to
+// receive notifications from the S3 bucket observed by the given source.
+// Statements of the topic policy other than the desired one, such as the
+// default statement set by SNS upon creation, are preserved.
+func syncTopicPolicy(ctx context.Context, cli snsiface.SNSAPI, topicARN string, src *v1alpha1.AWSS3Source) error {
+	current, err := sns.TopicPolicy(ctx, cli, topicARN)
+	if err != nil {
+		return fmt.Errorf("getting policy of SNS topic: %w", err)
+	}
+
+	pol, changed, err := iam.MergeStatement(current, makeTopicPolicyStatement(topicARN, src))
+	if err != nil {
+		return fmt.Errorf("merging statement into policy of SNS topic: %w", err)
+	}
+	if !changed {
+		return nil
+	}
+
+	if reportDrift(ctx, fmt.Sprintf("Policy of SNS topic %q", topicARN), current, pol) {
+		return nil
+	}
+
+	if err := sns.SetTopicPolicy(ctx, cli, topicARN, pol); err != nil {
+		return fmt.Errorf("setting policy of SNS topic: %w", err)
+	}
+
+	return nil
+}
+
+// makeTopicPolicyStatement creates an IAM policy statement for the given SNS
+// topic ARN and source instance.
+func makeTopicPolicyStatement(topicARN string, src *v1alpha1.AWSS3Source) iam.PolicyStatement {
+	bucketARN := s3.RealBucketARN(src.Spec.ARN)
+	accID := src.Spec.ARN.AccountID
+
+	return newS3ToSNSPolicyStatement(policyStatementID(src), topicARN, bucketARN, accID)
+}
+
+// newS3ToSNSPolicyStatement returns an IAM Policy Statement that allows a S3
+// bucket to publish event notifications to the given SNS topic.
+// Ref. https://docs.aws.amazon.com/AmazonS3/latest/userguide/grant-destinations-permissions-to-s3.html#grant-sns-sqs-permission-for-s3
+func newS3ToSNSPolicyStatement(sid, topicARN, bucketARN, accID string) iam.PolicyStatement {
+	return iam.NewPolicyStatement(iam.EffectAllow,
+		iam.Sid(sid),
+		iam.PrincipalService("s3.amazonaws.com"),
+		iam.ConditionArnEquals("aws:SourceArn", bucketARN),
+		iam.ConditionStringEquals("aws:SourceAccount", accID),
+		iam.Action("sns:Publish"),
+		iam.Resource(topicARN),
+	)
+}
+
+// topicARN returns the ARN of the SNS topic which receives the notifications
+// of the given source instance.
+func topicARN(src *v1alpha1.AWSS3Source) string {
+	if dest := snsDestination(src); dest != nil && dest.TopicARN != nil {
+		return dest.TopicARN.String()
+	}
+	return bucketLocalARN(src, "sns", topicName(src))
+}
@@ -0,0 +1,156 @@
+// Copyright 2023 TriggerMesh Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package tracing configures the export of trace spans produced while
+// serving hook requests.
+package tracing
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+
+	"contrib.go.opencensus.io/exporter/ocagent"
+	"go.opencensus.io/plugin/ochttp"
+	"go.opencensus.io/plugin/ochttp/propagation/b3"
+	"go.opencensus.io/trace"
+)
+
+// DefaultSampleRate is the fraction of traces sampled when no sampling rate
+// is configured.
+const DefaultSampleRate = 0.1
+
+// Setup registers an exporter that sends spans to the OpenCensus agent (or
+// an OpenTelemetry collector with an OpenCensus receiver) at the provided
+// host:port address.
+//
+// The returned function flushes pending spans and must be called before the
+// process exits.
+func Setup(serviceName, address string) (func(), error) {
+	if address == "" {
+		return nil, errors.New("tracing collector address is empty")
+	}
+
+	addr, err := agentAddress(address)
+	if err != nil {
+		return nil, err
+	}
+
+	exp, err := ocagent.NewExporter(
+		ocagent.WithInsecure(),
+		ocagent.WithAddress(addr),
+		ocagent.WithServiceName(serviceName),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("creating OpenCensus agent exporter: %w", err)
+	}
+
+	trace.RegisterExporter(exp)
+
+	return func() {
+		trace.UnregisterExporter(exp)
+		exp.Flush()
+		_ = exp.Stop()
+	}, nil
+}
+
+// SetSampleRate samples the given fraction of traces, which must be in the
+// range [0, 1].
+func SetSampleRate(rate float64) error {
+	if rate < 0 || rate > 1 {
+		return fmt.Errorf("tracing sample rate %v is not in the range [0, 1]", rate)
+	}
+
+	trace.ApplyConfig(trace.Config{DefaultSampler: trace.ProbabilitySampler(rate)})
+	return nil
+}
+
+// agentAddress returns the host:port address of the OpenCensus agent for the
+// provided address. The agent is served over gRPC, so a URL scheme is
+// accepted but ignored.
+func agentAddress(address string) (string, error) {
+	if i := strings.Index(address, "://"); i >= 0 {
+		address = strings.TrimSuffix(address[i+3:], "/")
+	}
+
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return "", fmt.Errorf("parsing tracing collector address: %w", err)
+	}
+	if host == "" || port == "" {
+		return "", fmt.Errorf("tracing collector address %q must be in the host:port format", address)
+	}
+
+	return address, nil
+}
+
+// Handler wraps an HTTP handler so that every request starts a server span.
+// Trace context received from callers using B3 headers is used as the parent
+// of the span.
+func Handler(h http.Handler) http.Handler {
+	return &ochttp.Handler{
+		Handler:     h,
+		Propagation: &b3.HTTPFormat{},
+	}
+}
+
+// Exporter exports spans to a collector whose address can be changed at
+// runtime.
+type Exporter struct {
+	serviceName string
+
+	m       sync.Mutex
+	address string
+	flush   func()
+}
+
+// NewExporter creates an Exporter that does not export spans until an
+// address is set.
+func NewExporter(serviceName string) *Exporter {
+	return &Exporter{
+		serviceName: serviceName,
+	}
+}
+
+// SetAddress exports spans to the collector at the provided address, flushing
+// spans pending for the previous collector if any. Setting an empty address
+// stops exporting spans.
+func (e *Exporter) SetAddress(address string) error {
+	e.m.Lock()
+	defer e.m.Unlock()
+
+	if address == e.address {
+		return nil
+	}
+
+	e.stop()
+	if address == "" {
+		return nil
+	}
+
+	flush, err := Setup(e.serviceName, address)
+	if err != nil {
+		return err
+	}
+	e.address, e.flush = address, flush
+
+	return nil
+}
+
+// Flush sends pending spans and stops exporting.
+func (e *Exporter) Flush() {
+	e.m.Lock()
+	defer e.m.Unlock()
+
+	e.stop()
+}
+
+func (e *Exporter) stop() {
+	if e.flush != nil {
+		e.flush()
+	}
+	e.address, e.flush = "", nil
+}
@@ -0,0 +1,57 @@
+// Copyright 2023 TriggerMesh Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package tracing
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAgentAddress(t *testing.T) {
+	testCases := map[string]struct {
+		address     string
+		expected    string
+		expectedErr bool
+	}{
+		"host and port": {
+			address:  "otel-collector.observability:55678",
+			expected: "otel-collector.observability:55678",
+		},
+		"URL": {
+			address:  "http://otel-collector.observability:55678/",
+			expected: "otel-collector.observability:55678",
+		},
+		"missing port": {
+			address:     "otel-collector.observability",
+			expectedErr: true,
+		},
+		"missing host": {
+			address:     ":55678",
+			expectedErr: true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			addr, err := agentAddress(tc.address)
+			if tc.expectedErr {
+				assert.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, addr)
+		})
+	}
+}
+
+func TestSetSampleRate(t *testing.T) {
+	assert.NoError(t, SetSampleRate(0))
+	assert.NoError(t, SetSampleRate(DefaultSampleRate))
+	assert.NoError(t, SetSampleRate(1))
+	assert.Error(t, SetSampleRate(1.5))
+	assert.Error(t, SetSampleRate(-0.1))
+}